@@ -6,6 +6,7 @@ import (
 	"github.com/TicketsBot/export/internal/artifactstore"
 	"github.com/TicketsBot/export/internal/config"
 	"github.com/TicketsBot/export/internal/metrics"
+	"github.com/TicketsBot/export/internal/migrate"
 	"github.com/TicketsBot/export/internal/repository"
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -57,6 +58,11 @@ func main() {
 
 	cancel()
 
+	if err := migrateDatabase(logger, cfg.Database, repository); err != nil {
+		logger.Error("Failed to migrate database", "error", err)
+		os.Exit(1)
+	}
+
 	publicKey, err := utils.LoadPublicKeyFromDisk(cfg.PublicKeyPath)
 	if err != nil {
 		logger.Error("Failed to load key", "error", err)
@@ -97,3 +103,28 @@ func main() {
 
 	<-closed
 }
+
+// migrateDatabase applies pending migrations if enabled, and otherwise refuses to start against an outdated schema,
+// as the embedded queries depend on it.
+func migrateDatabase(logger *slog.Logger, cfg config.DatabaseConfig, repository *repository.Repository) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		return err
+	}
+
+	if cfg.AutoMigrate {
+		applied, err := migrate.Apply(ctx, repository.Pool(), migrations)
+		for _, migration := range applied {
+			logger.Info("Applied migration", "name", migration.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return migrate.Check(ctx, repository.Pool(), migrations)
+}
@@ -0,0 +1,146 @@
+// export-diff compares two guild data exports, and lists the configuration changes between them, such as panels,
+// support teams and forms that were added, removed or modified. Both archives are verified before being compared.
+// Full guild exports can be compared too, as can exports in any archive format.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/TicketsBot/export/pkg/guilddiff"
+	"github.com/TicketsBot/export/pkg/validator"
+	"os"
+	"strings"
+)
+
+// Exit codes, following diff(1)
+const (
+	exitSame      = 0
+	exitDifferent = 1
+	exitError     = 2
+)
+
+var (
+	keyPath     = flag.String("key", "", "Path to the PEM encoded public key the exports were signed with")
+	asJson      = flag.Bool("json", false, "Print the diff as JSON")
+	maxSize     = flag.Int64("max-size", 1024, "Maximum total uncompressed size to read from each archive, in MiB")
+	maxFileSize = flag.Int64("max-file-size", 100, "Maximum uncompressed size of any single file, in MiB")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: export-diff -key path [options] <old archive> <new archive>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "An export that was split into parts is given as a comma separated list of every part.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Exit codes:")
+		fmt.Fprintln(os.Stderr, "  0  the exports have the same configuration")
+		fmt.Fprintln(os.Stderr, "  1  the exports differ")
+		fmt.Fprintln(os.Stderr, "  2  invalid arguments, or an archive could not be read or verified")
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 2 || *keyPath == "" {
+		flag.Usage()
+		os.Exit(exitError)
+	}
+
+	if err := run(flag.Arg(0), flag.Arg(1)); err != nil {
+		if errors.Is(err, errDifferent) {
+			os.Exit(exitDifferent)
+		}
+
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+
+	os.Exit(exitSame)
+}
+
+var errDifferent = errors.New("exports differ")
+
+func run(oldPath, newPath string) error {
+	key, err := utils.LoadPublicKeyFromDisk(*keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load public key: %w", err)
+	}
+
+	v := validator.NewValidator(key,
+		validator.WithMaxUncompressedSize(*maxSize*1024*1024),
+		validator.WithMaxIndividualFileSize(*maxFileSize*1024*1024))
+
+	old, oldMetadata, err := load(v, oldPath)
+	if err != nil {
+		return err
+	}
+
+	new, newMetadata, err := load(v, newPath)
+	if err != nil {
+		return err
+	}
+
+	// Sections that weren't selected in either export are left out, rather than listed as removed or added
+	sections := guilddiff.CommonSections(sectionsOf(oldMetadata), sectionsOf(newMetadata))
+
+	diff, err := guilddiff.Compare(old, new, sections)
+	if err != nil {
+		return fmt.Errorf("failed to compare exports: %w", err)
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	} else {
+		err = diff.WriteText(os.Stdout)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !diff.Empty() {
+		return errDifferent
+	}
+
+	return nil
+}
+
+// load reads an export, which is given as a comma separated list of its parts if it was split into several.
+func load(v *validator.Validator, path string) (*dto.GuildData, *dto.ExportMetadata, error) {
+	archive, err := v.OpenFiles(strings.Split(path, ",")...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	defer archive.Close()
+
+	data, metadata, err := v.ReadGuildData(archive, archive.Size)
+	if errors.Is(err, validator.ErrSqliteGuildData) {
+		return nil, nil, fmt.Errorf("%s is a SQLite export, which can't be compared, export the server's data in the JSON format instead", path)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+
+	// Each export is pseudonymised with its own salt, so every user ID would show as removed and added again
+	if metadata != nil && metadata.Redaction.Pseudonymised {
+		return nil, nil, fmt.Errorf("%s is pseudonymised, so can't be compared", path)
+	}
+
+	return data, metadata, nil
+}
+
+// sectionsOf returns the sections listed in an export's metadata, which is nil if the export has no metadata.json.
+func sectionsOf(metadata *dto.ExportMetadata) []string {
+	if metadata == nil {
+		return nil
+	}
+
+	return metadata.Sections
+}
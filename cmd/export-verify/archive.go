@@ -0,0 +1,185 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/validator"
+	"io"
+	"strconv"
+)
+
+type ArchiveType string
+
+const (
+	ArchiveTypeAuto             ArchiveType = "auto"
+	ArchiveTypeGuildTranscripts ArchiveType = "guild_transcripts"
+	ArchiveTypeGuildData        ArchiveType = "guild_data"
+	ArchiveTypeGuildFull        ArchiveType = "guild_full"
+	ArchiveTypeUserData         ArchiveType = "user_data"
+	ArchiveTypeErasureReceipt   ArchiveType = "erasure_receipt"
+	// ArchiveTypePart is one part of an export that was split into several archives, which can be of any type
+	ArchiveTypePart ArchiveType = "part"
+)
+
+// detectType works out the archive type from the files it contains. Guild and user data exports both contain a
+// data.json, so they are told apart by whether it has a user_id.
+func detectType(input io.ReaderAt, size int64) (ArchiveType, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return "", fmt.Errorf("archive is not a valid zip file: %w", err)
+	}
+
+	names := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		names[file.Name] = file
+	}
+
+	// Checked first, as any part may hold files that would otherwise identify the archive's type
+	if _, ok := names["parts.json"]; ok {
+		return ArchiveTypePart, nil
+	}
+
+	if _, ok := names["index.json"]; ok {
+		return ArchiveTypeGuildFull, nil
+	}
+
+	if _, ok := names["guild_id.txt"]; ok {
+		return ArchiveTypeGuildTranscripts, nil
+	}
+
+	if _, ok := names["receipt.json"]; ok {
+		return ArchiveTypeErasureReceipt, nil
+	}
+
+	if _, ok := names["data.sqlite"]; ok {
+		return ArchiveTypeGuildData, nil
+	}
+
+	if file, ok := names["data.json"]; ok {
+		f, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+
+		defer f.Close()
+
+		var data struct {
+			UserId *json.RawMessage `json:"user_id"`
+		}
+
+		if err := json.NewDecoder(f).Decode(&data); err != nil {
+			return "", fmt.Errorf("data.json is not valid JSON: %w", err)
+		}
+
+		if data.UserId != nil {
+			return ArchiveTypeUserData, nil
+		}
+
+		return ArchiveTypeGuildData, nil
+	}
+
+	return "", errors.New("could not detect the archive type, pass -type")
+}
+
+func validate(v *validator.Validator, typ ArchiveType, reader io.ReaderAt, size int64, report *Report) error {
+	if typ == ArchiveTypeGuildTranscripts || typ == ArchiveTypeGuildData {
+		metadata, err := v.ValidateMetadata(reader, size)
+		if err != nil {
+			return err
+		}
+
+		if metadata != nil {
+			report.Redaction = metadata.Redaction.Profile
+			report.Sections = metadata.Sections
+		}
+	}
+
+	switch typ {
+	case ArchiveTypeGuildTranscripts:
+		// Transcript archives can be very large, so are streamed rather than loaded into memory
+		guildId, err := v.StreamGuildTranscripts(reader, size, func(int, []byte) error {
+			report.Transcripts++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		report.GuildId = strconv.FormatUint(guildId, 10)
+	case ArchiveTypeGuildData:
+		if isSqlite(reader, size) {
+			_, err := v.ValidateGuildDataSqlite(reader, size)
+			return err
+		}
+
+		output, err := v.ValidateGuildData(reader, size)
+		if err != nil {
+			return err
+		}
+
+		report.GuildId = strconv.FormatUint(output.GuildId, 10)
+		report.Format = int(output.FormatVersion)
+	case ArchiveTypeGuildFull:
+		output, err := v.StreamGuildFull(reader, size, func(int, []byte) error {
+			report.Transcripts++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		report.GuildId = strconv.FormatUint(output.GuildData.GuildId, 10)
+		report.Format = int(output.GuildData.FormatVersion)
+		report.Redaction = output.Metadata.Redaction.Profile
+		report.Sections = output.Metadata.Sections
+	case ArchiveTypePart:
+		index, err := v.ValidateArchivePart(reader, size)
+		if err != nil {
+			return err
+		}
+
+		report.RequestId = index.RequestId
+		report.Part = index.Part
+		report.PartCount = len(index.Parts)
+	case ArchiveTypeUserData:
+		output, err := v.ValidateUserData(reader, size)
+		if err != nil {
+			return err
+		}
+
+		report.UserId = strconv.FormatUint(output.UserId, 10)
+		report.Format = int(output.FormatVersion)
+	case ArchiveTypeErasureReceipt:
+		output, err := v.ValidateErasureReceipt(reader, size)
+		if err != nil {
+			return err
+		}
+
+		report.UserId = strconv.FormatUint(output.UserId, 10)
+		if output.GuildId != nil {
+			report.GuildId = strconv.FormatUint(*output.GuildId, 10)
+		}
+	default:
+		return fmt.Errorf("unknown archive type %s", typ)
+	}
+
+	return nil
+}
+
+// isSqlite returns whether a guild data archive is in the SQLite format rather than JSON.
+func isSqlite(reader io.ReaderAt, size int64) bool {
+	zipReader, err := zip.NewReader(reader, size)
+	if err != nil {
+		return false
+	}
+
+	for _, file := range zipReader.File {
+		if file.Name == "data.sqlite" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/TicketsBot/export/internal/utils"
+	"io"
+	"net/http"
+	"time"
+)
+
+type Signer struct {
+	Source      string `json:"source"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// newSigner identifies the key by the SHA-256 hash of its raw bytes, so that it can be compared against the key
+// published by the service.
+func newSigner(key ed25519.PublicKey, source string) *Signer {
+	hash := sha256.Sum256(key)
+
+	return &Signer{
+		Source:      source,
+		Fingerprint: "SHA256:" + hex.EncodeToString(hash[:]),
+	}
+}
+
+func loadKey(path, url string) (ed25519.PublicKey, string, error) {
+	if path != "" {
+		key, err := utils.LoadPublicKeyFromDisk(path)
+		return key, path, err
+	}
+
+	key, err := fetchKey(url)
+	return key, url, err
+}
+
+func fetchKey(url string) (ed25519.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code fetching key %d", res.StatusCode)
+	}
+
+	// A PEM encoded Ed25519 key is just over 100 bytes, so anything much larger is not a key
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 16*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.ParsePublicKey(raw)
+}
@@ -0,0 +1,157 @@
+// export-verify checks that an export archive was produced by the export service, and has not been modified since,
+// by verifying the signature of every file in it against the service's public key.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/validator"
+	"os"
+	"strings"
+)
+
+// Exit codes, so that scripts can tell an invalid archive apart from a problem running the check
+const (
+	exitValid        = 0
+	exitInvalid      = 1
+	exitUsage        = 2
+	exitError        = 3
+	exitSizeExceeded = 4
+)
+
+var (
+	keyPath     = flag.String("key", "", "Path to the PEM encoded public key")
+	keyUrl      = flag.String("key-url", "", "URL to fetch the PEM encoded public key from, i.e. the API's /keys/signing endpoint")
+	archiveType = flag.String("type", "auto", "Archive type: auto, guild_transcripts, guild_data, guild_full, user_data, erasure_receipt or part")
+	asJson      = flag.Bool("json", false, "Print the report as JSON")
+	maxSize     = flag.Int64("max-size", 1024, "Maximum total uncompressed size to read, in MiB. Transcript archives are streamed, so are not subject to this limit")
+	maxFileSize = flag.Int64("max-file-size", 100, "Maximum uncompressed size of any single file, in MiB")
+	listFiles   = flag.Bool("list", false, "List every verified file in the human readable report")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: export-verify (-key path | -key-url url) [options] <archive.zip|archive.tar.gz|archive.tar.zst>...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "A single part of an export that was split into several can be checked on its own. If every part is given,")
+		fmt.Fprintln(os.Stderr, "they are checked to be a complete set, and the export is checked as a whole.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Exit codes:")
+		fmt.Fprintln(os.Stderr, "  0  the archive is valid")
+		fmt.Fprintln(os.Stderr, "  1  the archive is invalid, e.g. a file has been modified or a signature is missing")
+		fmt.Fprintln(os.Stderr, "  2  invalid arguments")
+		fmt.Fprintln(os.Stderr, "  3  the key or archive could not be read")
+		fmt.Fprintln(os.Stderr, "  4  the archive exceeds -max-size or -max-file-size")
+	}
+
+	flag.Parse()
+
+	if flag.NArg() == 0 || (*keyPath == "") == (*keyUrl == "") {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	report := Report{
+		Archive:  strings.Join(flag.Args(), ", "),
+		Files:    make([]string, 0),
+		Failures: make([]string, 0),
+	}
+
+	os.Exit(run(&report, flag.Args()))
+}
+
+func run(report *Report, paths []string) int {
+	key, source, err := loadKey(*keyPath, *keyUrl)
+	if err != nil {
+		return report.fail(exitError, fmt.Errorf("failed to load public key: %w", err))
+	}
+
+	report.Signer = newSigner(key, source)
+
+	verified := make(map[string]bool)
+	v := validator.NewValidator(key,
+		validator.WithMaxUncompressedSize(*maxSize*1024*1024),
+		validator.WithMaxIndividualFileSize(*maxFileSize*1024*1024),
+		validator.WithOnVerified(func(fileName string) {
+			// metadata.json is verified both on its own and as part of the archive
+			if !verified[fileName] {
+				verified[fileName] = true
+				report.Files = append(report.Files, fileName)
+			}
+		}))
+
+	// The archive is read from disk as needed, rather than loaded into memory, as transcript archives can be large.
+	// Tar archives can't be read at random, so are converted to a zip first, and parts are joined into a single zip.
+	var archive *validator.Archive
+	if len(paths) == 1 {
+		archive, err = v.OpenFile(paths[0])
+	} else {
+		archive, err = v.OpenFiles(paths...)
+	}
+
+	if err != nil {
+		if errors.Is(err, validator.ErrValidationFailed) || errors.Is(err, validator.ErrMaximumSizeExceeded) {
+			return report.fail(exitCode(err), fmt.Errorf("failed to read archive: %w", err))
+		}
+
+		return report.fail(exitError, fmt.Errorf("failed to read archive: %w", err))
+	}
+
+	defer archive.Close()
+
+	report.ArchiveFormat = archive.Format
+	if archive.Parts != nil {
+		report.RequestId = archive.Parts.RequestId
+		report.PartCount = len(archive.Parts.Parts)
+	}
+
+	typ := ArchiveType(*archiveType)
+	if typ == ArchiveTypeAuto {
+		if typ, err = detectType(archive, archive.Size); err != nil {
+			return report.fail(exitError, err)
+		}
+	}
+
+	report.Type = typ
+
+	if err := validate(v, typ, archive, archive.Size, report); err != nil {
+		return report.fail(exitCode(err), err)
+	}
+
+	report.Valid = true
+	return report.print(exitValid)
+}
+
+// exitCode returns the exit code for a validation error. Any error other than a size limit means the archive is
+// missing a file or signature, has been modified, or is malformed, so it cannot be trusted.
+func exitCode(err error) int {
+	if errors.Is(err, validator.ErrMaximumSizeExceeded) {
+		return exitSizeExceeded
+	}
+
+	return exitInvalid
+}
+
+func (r *Report) fail(code int, err error) int {
+	r.Failures = append(r.Failures, err.Error())
+	return r.print(code)
+}
+
+func (r *Report) print(code int) int {
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	} else {
+		r.printHuman(os.Stdout, *listFiles)
+	}
+
+	return code
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/TicketsBot/export/pkg/validator"
+	"io"
+	"strings"
+)
+
+type Report struct {
+	Archive       string                  `json:"archive"`
+	Valid         bool                    `json:"valid"`
+	Type          ArchiveType             `json:"type,omitempty"`
+	ArchiveFormat validator.ArchiveFormat `json:"archive_format,omitempty"` // zip, tar.gz or tar.zst
+	RequestId     string                  `json:"request_id,omitempty"`     // from parts.json, if the archive is one or every part of an export
+	Part          int                     `json:"part,omitempty"`           // unset if every part was given
+	PartCount     int                     `json:"part_count,omitempty"`
+	GuildId       string                  `json:"guild_id,omitempty"`
+	UserId        string                  `json:"user_id,omitempty"`
+	Format        int                     `json:"format_version,omitempty"`
+	Redaction     string                  `json:"redaction,omitempty"` // from metadata.json, if the archive has one
+	Sections      []string                `json:"sections,omitempty"`  // from metadata.json, if the export has selected sections
+	Signer        *Signer                 `json:"signer,omitempty"`
+	Transcripts   int                     `json:"transcripts,omitempty"`
+	Files         []string                `json:"files"` // files whose signature was verified
+	Failures      []string                `json:"failures"`
+}
+
+func (r *Report) printHuman(w io.Writer, listFiles bool) {
+	fmt.Fprintf(w, "Archive:        %s\n", r.Archive)
+
+	if r.Type != "" {
+		fmt.Fprintf(w, "Type:           %s\n", r.Type)
+	}
+
+	if r.ArchiveFormat != "" {
+		fmt.Fprintf(w, "Archive format: %s\n", r.ArchiveFormat)
+	}
+
+	if r.Part != 0 {
+		fmt.Fprintf(w, "Request ID:     %s\n", r.RequestId)
+		fmt.Fprintf(w, "Part:           %d of %d\n", r.Part, r.PartCount)
+	} else if r.PartCount != 0 {
+		fmt.Fprintf(w, "Request ID:     %s\n", r.RequestId)
+		fmt.Fprintf(w, "Parts:          %d, joined\n", r.PartCount)
+	}
+
+	if r.GuildId != "" {
+		fmt.Fprintf(w, "Guild ID:       %s\n", r.GuildId)
+	}
+
+	if r.UserId != "" {
+		fmt.Fprintf(w, "User ID:        %s\n", r.UserId)
+	}
+
+	if r.Format != 0 {
+		fmt.Fprintf(w, "Format version: %d\n", r.Format)
+	}
+
+	if r.Redaction != "" {
+		fmt.Fprintf(w, "Redaction:      %s\n", r.Redaction)
+	}
+
+	if len(r.Sections) > 0 {
+		fmt.Fprintf(w, "Sections:       %s\n", strings.Join(r.Sections, ", "))
+	}
+
+	if r.Signer != nil {
+		fmt.Fprintf(w, "Signer:         %s (%s)\n", r.Signer.Fingerprint, r.Signer.Source)
+	}
+
+	if r.Type == ArchiveTypeGuildTranscripts || r.Type == ArchiveTypeGuildFull {
+		fmt.Fprintf(w, "Transcripts:    %d\n", r.Transcripts)
+	}
+
+	fmt.Fprintf(w, "Files checked:  %d\n", len(r.Files))
+
+	if listFiles {
+		for _, file := range r.Files {
+			fmt.Fprintf(w, "  %s\n", file)
+		}
+	}
+
+	for _, failure := range r.Failures {
+		fmt.Fprintf(w, "Failure:        %s\n", failure)
+	}
+
+	if r.Valid {
+		fmt.Fprintln(w, "Result:         VALID")
+	} else {
+		fmt.Fprintln(w, "Result:         INVALID")
+	}
+}
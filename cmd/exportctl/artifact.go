@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/artifactstore"
+	"github.com/TicketsBot/export/internal/config"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func runArtifact(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "fetch" {
+		return usageError("artifact")
+	}
+
+	flags := flag.NewFlagSet("artifact fetch", flag.ExitOnError)
+	output := flags.String("o", "", "Path to write the artifact to (defaults to <request id> and the artifact's extension, e.g. .zip)")
+	part := flags.Int("part", 0, "Part of the artifact to fetch (defaults to every part, written to <path>.part-<n>.<extension>)")
+	flags.Parse(args[1:])
+
+	if flags.NArg() != 1 {
+		return usageError("artifact")
+	}
+
+	requestId, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid request ID: %w", err)
+	}
+
+	cfg, err := config.New[config.SharedConfig]()
+	if err != nil {
+		return err
+	}
+
+	repo, err := repository.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	var request *model.RequestWithArtifact
+	if err := repo.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		request, err = tx.Requests().GetById(ctx, requestId)
+		return
+	}); err != nil {
+		return err
+	}
+
+	if request == nil {
+		return fmt.Errorf("request %s not found", requestId)
+	}
+
+	if !request.HasArtifact() {
+		return fmt.Errorf("request %s has no artifact", requestId)
+	}
+
+	artifacts := request.Artifacts
+	if *part != 0 {
+		artifact := request.ArtifactPart(*part)
+		if artifact == nil {
+			return fmt.Errorf("request %s has no part %d, it has %d parts", requestId, *part, len(request.Artifacts))
+		}
+
+		artifacts = []model.Artifact{*artifact}
+	}
+
+	if *output == "" {
+		*output = requestId.String() + artifacts[0].Format.Extension()
+	}
+
+	// The object may still exist until the bucket's lifecycle rules remove it
+	if artifacts[0].ExpiresAt.Before(time.Now()) {
+		logger.Warn("Artifact has expired, it may no longer exist", "expired_at", artifacts[0].ExpiresAt)
+	}
+
+	s3Client, err := newS3Client(ctx, cfg.S3)
+	if err != nil {
+		return err
+	}
+
+	store := artifactstore.NewS3ArtifactStore(
+		logger.With(slog.String("module", "artifact_store")),
+		s3Client, cfg.ArtifactStore.Bucket, []byte(cfg.ArtifactStore.EncryptionKey),
+	)
+
+	for _, artifact := range artifacts {
+		path := *output
+		if len(request.Artifacts) > 1 {
+			path = partPath(path, artifact.Part, artifact.Format.Extension())
+		}
+
+		size, err := writeArtifact(ctx, store, artifact, path)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Wrote artifact", "path", path, "part", artifact.Part, "size", size)
+	}
+
+	return nil
+}
+
+// writeArtifact decrypts an artifact into the file at path as it is downloaded, removing the file if the download
+// fails.
+func writeArtifact(ctx context.Context, store artifactstore.ArtifactStore, artifact model.Artifact, path string) (int64, error) {
+	reader, err := store.Fetch(ctx, artifact.RequestId, artifact.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	defer reader.Close()
+
+	// Artifacts contain user data, so are only readable by the current user
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := io.Copy(f, reader)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// partPath inserts the part number before the extension of path, e.g. export.tar.gz becomes export.part-2.tar.gz. If
+// path doesn't have the artifact's extension, its last extension is used instead.
+func partPath(path string, part int, ext string) string {
+	if !strings.HasSuffix(path, ext) {
+		ext = filepath.Ext(path)
+	}
+
+	return fmt.Sprintf("%s.part-%d%s", strings.TrimSuffix(path, ext), part, ext)
+}
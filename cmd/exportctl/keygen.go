@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/utils"
+	"io/fs"
+	"os"
+)
+
+func runKeygen(_ context.Context, args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := flags.String("out", "key.pem", "Path to write the private key to. The public key is written to the same path with .pub appended")
+	force := flags.Bool("force", false, "Overwrite existing key files")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		return usageError("keygen")
+	}
+
+	publicPath := *out + ".pub"
+
+	if !*force {
+		for _, path := range []string{*out, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, pass -force to overwrite it", path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privatePem, err := utils.EncodePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	publicPem, err := utils.EncodePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(*out, privatePem, 0600); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(publicPath, publicPem, 0644); err != nil {
+		return err
+	}
+
+	logger.Info("Generated keypair", "private_key", *out, "public_key", publicPath)
+	return nil
+}
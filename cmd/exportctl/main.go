@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/TicketsBot/export/internal/config"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3Config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type command struct {
+	Usage       string
+	Description string
+	Run         func(ctx context.Context, args []string) error
+}
+
+// commands is populated in init, as commands refer back to it to print their usage
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"migrate": {
+			Usage:       "migrate [-dir migrations] [-status] [-baseline version]",
+			Description: "Apply pending database migrations",
+			Run:         runMigrate,
+		},
+		"requests": {
+			Usage:       "requests list [-user id] [-guild id] [-type type] [-status status] [-limit n] [-json] | requests requeue [-skip-grace-period] <request id>",
+			Description: "List requests, or queue a failed request to run again",
+			Run:         runRequests,
+		},
+		"run": {
+			Usage:       "run [-force] [-timeout 10m] <request id>",
+			Description: "Run a request in this process, for debugging, and record the result",
+			Run:         runRun,
+		},
+		"artifact": {
+			Usage:       "artifact fetch [-o path] [-part n] <request id>",
+			Description: "Fetch and decrypt the artifact of a request, or one part of it",
+			Run:         runArtifact,
+		},
+		"keygen": {
+			Usage:       "keygen [-out key.pem] [-force]",
+			Description: "Generate an Ed25519 signing keypair",
+			Run:         runKeygen,
+		},
+		"queue": {
+			Usage:       "queue stats [-period 24h] [-json]",
+			Description: "Print task queue statistics",
+			Run:         runQueue,
+		},
+	}
+}
+
+// Logs go to stderr so that output can be piped
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+	Level: utils.ParseLogLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo),
+}))
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.Run(context.Background(), os.Args[2:]); err != nil {
+		logger.Error("Command failed", "command", os.Args[1], "error", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: exportctl <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	for _, name := range []string{"migrate", "requests", "run", "artifact", "keygen", "queue"} {
+		cmd := commands[name]
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, cmd.Description)
+		fmt.Fprintf(os.Stderr, "  %-10s exportctl %s\n", "", cmd.Usage)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Configuration is read from the same environment variables as the API and worker.")
+}
+
+// usageError is returned when a command is given invalid arguments.
+func usageError(name string) error {
+	return fmt.Errorf("usage: exportctl %s", strings.TrimSpace(commands[name].Usage))
+}
+
+// databaseConfig is the configuration needed by commands that only use the export database.
+type databaseConfig struct {
+	Database config.DatabaseConfig `envPrefix:"DATABASE_"`
+}
+
+func connectDatabase(ctx context.Context) (*pgxpool.Pool, *repository.Repository, error) {
+	cfg, err := config.New[databaseConfig]()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.Database.Uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pool, repository.NewRepository(pool), nil
+}
+
+func newS3Client(ctx context.Context, cfg config.S3Config) (*s3.Client, error) {
+	s3Cfg, err := s3Config.LoadDefaultConfig(ctx, s3Config.WithCredentialsProvider(aws.NewCredentialsCache(
+		credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""))),
+		s3Config.WithBaseEndpoint(cfg.Endpoint),
+		s3Config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(s3Cfg), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/migrate"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := flags.String("dir", "", "Directory containing the migration files, instead of those built into exportctl")
+	status := flags.Bool("status", false, "Print which migrations have been applied, without applying any")
+	baseline := flags.Int("baseline", 0, "Mark migrations up to and including this version as applied without running them, for databases created before migrations were tracked")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		return usageError("migrate")
+	}
+
+	var migrations []migrate.Migration
+	var err error
+	if *dir == "" {
+		migrations, err = migrate.Embedded()
+	} else {
+		migrations, err = migrate.Load(os.DirFS(*dir))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	pool, _, err := connectDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer pool.Close()
+
+	if *status {
+		applied, err := migrate.Applied(ctx, pool)
+		if err != nil {
+			return err
+		}
+
+		appliedAt := make(map[int]time.Time, len(applied))
+		for _, migration := range applied {
+			appliedAt[migration.Version] = migration.AppliedAt
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, migration := range migrations {
+			applied := "pending"
+			if t, ok := appliedAt[migration.Version]; ok {
+				applied = t.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, applied)
+		}
+
+		return w.Flush()
+	}
+
+	if *baseline > 0 {
+		marked, err := migrate.Baseline(ctx, pool, migrations, *baseline)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range marked {
+			logger.Info("Marked migration as applied", "name", migration.Name)
+		}
+	}
+
+	applied, err := migrate.Apply(ctx, pool, migrations)
+	for _, migration := range applied {
+		logger.Info("Applied migration", "name", migration.Name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Database is up to date", "applied", len(applied))
+	return nil
+}
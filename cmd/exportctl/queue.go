@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runQueue(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "stats" {
+		return usageError("queue")
+	}
+
+	flags := flag.NewFlagSet("queue stats", flag.ExitOnError)
+	period := flags.Duration("period", time.Hour*24, "Count requests created within this period")
+	asJson := flags.Bool("json", false, "Print statistics as JSON")
+	flags.Parse(args[1:])
+
+	pool, repo, err := connectDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer pool.Close()
+
+	var stats model.QueueStats
+	var counts []model.RequestCount
+	if err := repo.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		if stats, err = tx.Tasks().Stats(ctx); err != nil {
+			return err
+		}
+
+		counts, err = tx.Requests().CountByStatus(ctx, *period)
+		return
+	}); err != nil {
+		return err
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Queue    model.QueueStats     `json:"queue"`
+			Requests []model.RequestCount `json:"requests"`
+		}{
+			Queue:    stats,
+			Requests: counts,
+		})
+	}
+
+	fmt.Printf("Tasks due:      %d\n", stats.Due)
+	fmt.Printf("Tasks waiting:  %d\n", stats.Waiting)
+	if stats.OldestDue != nil {
+		fmt.Printf("Oldest due:     %s (%s ago)\n", stats.OldestDue.Format(time.RFC3339),
+			time.Since(*stats.OldestDue).Round(time.Second))
+	}
+
+	fmt.Printf("\nRequests created in the last %s:\n", *period)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tSTATUS\tCOUNT")
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", count.Type, count.Status, count.Count)
+	}
+
+	return w.Flush()
+}
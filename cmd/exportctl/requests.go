@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/google/uuid"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+func runRequests(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usageError("requests")
+	}
+
+	switch args[0] {
+	case "list":
+		return listRequests(ctx, args[1:])
+	case "requeue":
+		return requeueRequest(ctx, args[1:])
+	default:
+		return usageError("requests")
+	}
+}
+
+func listRequests(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("requests list", flag.ExitOnError)
+	userId := flags.Uint64("user", 0, "Only list requests made by this user")
+	guildId := flags.Uint64("guild", 0, "Only list requests for this guild")
+	requestType := flags.String("type", "", "Only list requests of this type")
+	status := flags.String("status", "", "Only list requests with this status")
+	limit := flags.Int("limit", 50, "Maximum number of requests to list")
+	asJson := flags.Bool("json", false, "Print requests as JSON")
+	flags.Parse(args)
+
+	filter := model.RequestFilter{
+		Limit: *limit,
+	}
+
+	if *userId != 0 {
+		filter.UserId = userId
+	}
+
+	if *guildId != 0 {
+		filter.GuildId = guildId
+	}
+
+	if *requestType != "" {
+		filter.Type = (*model.RequestType)(requestType)
+		if !filter.Type.Valid() {
+			return fmt.Errorf("invalid request type %s", *requestType)
+		}
+	}
+
+	if *status != "" {
+		filter.Status = (*model.RequestStatus)(status)
+		if !filter.Status.Valid() {
+			return fmt.Errorf("invalid status %s", *status)
+		}
+	}
+
+	pool, repo, err := connectDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer pool.Close()
+
+	var requests []model.RequestDetails
+	if err := repo.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		requests, err = tx.Requests().ListFiltered(ctx, filter)
+		return
+	}); err != nil {
+		return err
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(requests)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tUSER\tGUILD\tCREATED AT\tARTIFACT EXPIRES\tPARTS\tFAILURE REASON")
+	for _, request := range requests {
+		guild := "-"
+		if request.Request.GuildId != nil {
+			guild = strconv.FormatUint(*request.Request.GuildId, 10)
+		}
+
+		artifactExpires, parts := "-", "-"
+		if len(request.Artifacts) > 0 {
+			artifactExpires = request.Artifacts[0].ExpiresAt.Format(time.RFC3339)
+			parts = strconv.Itoa(len(request.Artifacts))
+		}
+
+		failureReason := "-"
+		if request.FailureReason != nil {
+			failureReason = *request.FailureReason
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			request.Request.Id, request.Request.Type, request.Request.Status, request.Request.UserId, guild,
+			request.Request.CreatedAt.Format(time.RFC3339), artifactExpires, parts, failureReason)
+	}
+
+	return w.Flush()
+}
+
+func requeueRequest(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("requests requeue", flag.ExitOnError)
+	skipGracePeriod := flags.Bool("skip-grace-period", false, "Allow requeueing a queued erasure, skipping its grace period")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return usageError("requests")
+	}
+
+	requestId, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid request ID: %w", err)
+	}
+
+	pool, repo, err := connectDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer pool.Close()
+
+	return repo.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+		request, err := tx.Requests().GetById(ctx, requestId)
+		if err != nil {
+			return err
+		}
+
+		if request == nil {
+			return fmt.Errorf("request %s not found", requestId)
+		}
+
+		if !request.Request.Status.Requeueable() {
+			return fmt.Errorf("only failed or queued requests can be requeued, request %s is %s", requestId, request.Request.Status)
+		}
+
+		// The user can still cancel an erasure during its grace period
+		if request.Request.Type.IsErasure() && request.Request.Status == model.RequestStatusQueued && !*skipGracePeriod {
+			return fmt.Errorf("request %s is a queued erasure, pass -skip-grace-period to run it without waiting for its grace period", requestId)
+		}
+
+		if err := repository.Requeue(ctx, tx, requestId); err != nil {
+			return err
+		}
+
+		logger.Info("Request requeued", "request_id", requestId)
+		return nil
+	})
+}
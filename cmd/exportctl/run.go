@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/TicketsBot/export/internal/artifactstore"
+	"github.com/TicketsBot/export/internal/config"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/internal/worker"
+	"github.com/TicketsBot/export/internal/worker/transcriptstore"
+	"github.com/google/uuid"
+	pgxv4pool "github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
+	"time"
+)
+
+func runRun(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	force := flags.Bool("force", false, "Run the request even if it is an erasure, or is not queued or failed")
+	timeout := flags.Duration("timeout", time.Minute*10, "Maximum time to run the request for")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return usageError("run")
+	}
+
+	requestId, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid request ID: %w", err)
+	}
+
+	cfg, err := config.New[config.WorkerConfig]()
+	if err != nil {
+		return err
+	}
+
+	repo, err := repository.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	var request *model.RequestWithArtifact
+	if err := repo.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		request, err = tx.Requests().GetById(ctx, requestId)
+		return
+	}); err != nil {
+		return err
+	}
+
+	if request == nil {
+		return fmt.Errorf("request %s not found", requestId)
+	}
+
+	if request.HasArtifact() {
+		return fmt.Errorf("request %s already has an artifact", requestId)
+	}
+
+	// Erasures delete data, and requests awaiting confirmation have not been confirmed by the user
+	if !*force && (request.Request.Type.IsErasure() || !request.Request.Status.Requeueable()) {
+		return fmt.Errorf("request %s is a %s request with status %s, pass -force to run it anyway",
+			requestId, request.Request.Type, request.Request.Status)
+	}
+
+	daemon, err := newDaemon(ctx, cfg, repo)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	logger.Info("Running request", "request_id", requestId, "type", request.Request.Type)
+
+	if err := daemon.RunRequest(runCtx, request.Request); err != nil {
+		return err
+	}
+
+	logger.Info("Request completed", "request_id", requestId)
+	return nil
+}
+
+func newDaemon(ctx context.Context, cfg config.WorkerConfig, repo *repository.Repository) (*worker.Daemon, error) {
+	s3Client, err := newS3Client(ctx, cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxv4pool.Connect(ctx, cfg.TicketsDatabaseUri)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := utils.LoadKeyFromDisk(cfg.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	transcriptClient := transcriptstore.NewS3Client(
+		logger.With(slog.String("module", "transcript_client")),
+		cfg, s3Client,
+	)
+
+	artifactClient := artifactstore.NewS3ArtifactStore(
+		logger.With(slog.String("module", "artifact_store")),
+		s3Client, cfg.ArtifactStore.Bucket, []byte(cfg.ArtifactStore.EncryptionKey),
+	)
+
+	return worker.NewDaemon(
+		logger.With(slog.String("module", "daemon")),
+		cfg, key, repo, transcriptClient, artifactClient, pool), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"github.com/TicketsBot/database"
+	"github.com/TicketsBot/export/internal/importer"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/validator"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var (
+	keyPath     = flag.String("key", "", "Path to the public key file")
+	zipPath     = flag.String("zip", "", "Path to the guild data or full guild export, as a zip, tar.gz or tar.zst file. An export split into parts is given as a comma separated list of every part")
+	guildId     = flag.Uint64("guild", 0, "ID of the guild to import into (defaults to the guild the export was taken from)")
+	dryRun      = flag.Bool("dry-run", false, "Report conflicts and what would be created, without writing anything")
+	databaseUri = flag.String("database", os.Getenv("TICKETS_DATABASE_URI"), "Tickets database URI")
+	logLevel    = flag.String("log-level", "INFO", "Log level")
+)
+
+func main() {
+	flag.Parse()
+
+	if *keyPath == "" || *zipPath == "" || *databaseUri == "" {
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	// Logs go to stderr so that the report can be piped
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: utils.ParseLogLevel(*logLevel, slog.LevelInfo),
+	}))
+
+	key, err := utils.LoadPublicKeyFromDisk(*keyPath)
+	if err != nil {
+		logger.Error("Failed to load key", "error", err)
+		os.Exit(1)
+	}
+
+	v := validator.NewValidator(key,
+		validator.WithMaxUncompressedSize(1024*1024*1024),
+		validator.WithMaxIndividualFileSize(1024*1024*1024))
+
+	archive, err := v.OpenFiles(strings.Split(*zipPath, ",")...)
+	if err != nil {
+		logger.Error("Failed to read export", "error", err)
+		os.Exit(1)
+	}
+
+	// Closed straight away, rather than deferred, so that a converted or joined archive is removed before any exit
+	data, metadata, err := v.ReadGuildData(archive, archive.Size)
+	archive.Close()
+
+	if errors.Is(err, validator.ErrSqliteGuildData) {
+		logger.Error("SQLite exports can't be imported, export the server's data in the JSON format instead")
+		os.Exit(1)
+	} else if err != nil {
+		logger.Error("Failed to validate export", "error", err)
+		os.Exit(1)
+	}
+
+	// Checked before connecting to the database, although the importer refuses pseudonymised exports too
+	if metadata != nil && metadata.Redaction.Pseudonymised {
+		logger.Error("Export can't be imported", "error", importer.ErrPseudonymised, "redaction", metadata.Redaction.Profile)
+		os.Exit(1)
+	}
+
+	targetGuildId := *guildId
+	if targetGuildId == 0 {
+		targetGuildId = data.GuildId
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.Connect(ctx, *databaseUri)
+	if err != nil {
+		logger.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+
+	defer pool.Close()
+
+	imp := importer.NewImporter(logger.With(slog.String("module", "importer")), database.NewDatabase(pool),
+		importer.Options{DryRun: *dryRun})
+
+	logger.Info("Starting import", "source_guild_id", data.GuildId, "format_version", data.FormatVersion, "target_guild_id", targetGuildId, "dry_run", *dryRun)
+
+	// The import runs in a single transaction, so nothing has been written if it failed
+	report, err := imp.Import(ctx, data, metadata, targetGuildId)
+	if err != nil {
+		logger.Error("Import failed", "error", err)
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logger.Error("Failed to encode report", "error", err)
+	}
+
+	logger.Info("Import completed", "conflicts", len(report.Conflicts))
+}
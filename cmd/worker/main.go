@@ -2,10 +2,10 @@ package main
 
 import (
 	"context"
-	"github.com/TicketsBot/database"
 	"github.com/TicketsBot/export/internal/artifactstore"
 	"github.com/TicketsBot/export/internal/config"
 	"github.com/TicketsBot/export/internal/metrics"
+	"github.com/TicketsBot/export/internal/migrate"
 	"github.com/TicketsBot/export/internal/repository"
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/TicketsBot/export/internal/worker"
@@ -58,6 +58,11 @@ func main() {
 
 	cancel()
 
+	if err := migrateDatabase(logger, cfg.Database, repository); err != nil {
+		logger.Error("Failed to migrate database", "error", err)
+		os.Exit(1)
+	}
+
 	s3Client := s3.NewFromConfig(s3Cfg)
 	transcriptClient := transcriptstore.NewS3Client(
 		logger.With(slog.String("module", "transcript_client")),
@@ -88,11 +93,31 @@ func main() {
 	daemon.Shutdown()
 }
 
-func connectDatabase(ctx context.Context, cfg config.WorkerConfig) (*database.Database, error) {
-	pool, err := pgxpool.Connect(ctx, cfg.TicketsDatabaseUri)
+func connectDatabase(ctx context.Context, cfg config.WorkerConfig) (*pgxpool.Pool, error) {
+	return pgxpool.Connect(ctx, cfg.TicketsDatabaseUri)
+}
+
+// migrateDatabase applies pending migrations if enabled, and otherwise refuses to start against an outdated schema,
+// as the embedded queries depend on it.
+func migrateDatabase(logger *slog.Logger, cfg config.DatabaseConfig, repository *repository.Repository) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	migrations, err := migrate.Embedded()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if cfg.AutoMigrate {
+		applied, err := migrate.Apply(ctx, repository.Pool(), migrations)
+		for _, migration := range applied {
+			logger.Info("Applied migration", "name", migration.Name)
+		}
+
+		if err != nil {
+			return err
+		}
 	}
 
-	return database.NewDatabase(pool), nil
+	return migrate.Check(ctx, repository.Pool(), migrations)
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
 	"github.com/TicketsBot/export/example/utils"
@@ -27,23 +26,29 @@ func main() {
 		panic(err)
 	}
 
-	b, err := os.ReadFile(*zipPath)
+	f, err := os.Open(*zipPath)
 	if err != nil {
 		panic(err)
 	}
 
-	v := validator.NewValidator(key,
-		validator.WithMaxUncompressedSize(250*1024*1024),
-		validator.WithMaxIndividualFileSize(1*1024*1024))
+	defer f.Close()
 
-	reader := bytes.NewReader(b)
-	output, err := v.ValidateGuildTranscripts(reader, reader.Size())
+	info, err := f.Stat()
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("Guild ID: %d\n", output.GuildId)
-	for ticketId, transcript := range output.Transcripts {
+	v := validator.NewValidator(key,
+		validator.WithMaxIndividualFileSize(1*1024*1024))
+
+	// Transcripts are verified and printed one at a time, so archives of any size can be read
+	guildId, err := v.StreamGuildTranscripts(f, info.Size(), func(ticketId int, transcript []byte) error {
 		fmt.Println(ticketId, string(transcript))
+		return nil
+	})
+	if err != nil {
+		panic(err)
 	}
+
+	fmt.Printf("Guild ID: %d\n", guildId)
 }
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"net/http"
+	"time"
+)
+
+// ListDownloadTotals returns the number of downloads and bytes downloaded by each user over the period query
+// parameter (a Go duration, defaulting to 24h), largest first. Pass user_id to see a single user's total.
+func (a *API) ListDownloadTotals(w http.ResponseWriter, r *http.Request) {
+	period := time.Hour * 24
+	if raw := r.URL.Query().Get("period"); raw != "" {
+		var err error
+		if period, err = time.ParseDuration(raw); err != nil || period <= 0 {
+			a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid period"})
+			return
+		}
+	}
+
+	userId, ok := parseOptionalSnowflake(r, "user_id")
+	if !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid user ID"})
+		return
+	}
+
+	limit, ok := parseLimit(r)
+	if !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": fmt.Sprintf("Limit must be between 1 and %d", maxLimit)})
+		return
+	}
+
+	var totals []model.DownloadTotal
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		totals, err = tx.Downloads().ListUserTotals(ctx, period, userId, limit)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch download totals"))
+		return
+	}
+
+	a.RespondJson(w, http.StatusOK, totals)
+}
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"net/http"
+	"time"
+)
+
+type TaskDto struct {
+	model.Task
+	Request model.Request `json:"request"`
+	// Due is whether the task is due to run, rather than waiting, for example for an erasure grace period to pass
+	Due bool `json:"due"`
+}
+
+// ListTasks lists every task in the queue, in the order they will run.
+func (a *API) ListTasks(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(r)
+	if !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": fmt.Sprintf("Limit must be between 1 and %d", maxLimit)})
+		return
+	}
+
+	var tasks []model.Union[model.Task, model.Request]
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		tasks, err = tx.Tasks().List(ctx, limit)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch tasks"))
+		return
+	}
+
+	now := time.Now()
+
+	dto := make([]TaskDto, len(tasks))
+	for i, task := range tasks {
+		dto[i] = TaskDto{
+			Task:    task.First,
+			Request: task.Second,
+			Due:     !task.First.RunAfter.After(now),
+		}
+	}
+
+	a.RespondJson(w, http.StatusOK, dto)
+}
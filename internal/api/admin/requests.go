@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// ListRequests lists requests from all users, most recent first. Results can be filtered by the user_id, guild_id,
+// type and status query parameters, and paginated by passing the created_at of the last result as before.
+func (a *API) ListRequests(w http.ResponseWriter, r *http.Request) {
+	filter, ok := a.parseRequestFilter(w, r)
+	if !ok {
+		return
+	}
+
+	a.listRequests(w, r, filter)
+}
+
+// ListFailedRequests lists requests whose task failed, with the reason it failed.
+func (a *API) ListFailedRequests(w http.ResponseWriter, r *http.Request) {
+	filter, ok := a.parseRequestFilter(w, r)
+	if !ok {
+		return
+	}
+
+	filter.Status = utils.Ptr(model.RequestStatusFailed)
+	a.listRequests(w, r, filter)
+}
+
+func (a *API) listRequests(w http.ResponseWriter, r *http.Request, filter model.RequestFilter) {
+	var requests []model.RequestDetails
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		requests, err = tx.Requests().ListFiltered(ctx, filter)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch requests"))
+		return
+	}
+
+	a.RespondJson(w, http.StatusOK, requests)
+}
+
+func (a *API) parseRequestFilter(w http.ResponseWriter, r *http.Request) (model.RequestFilter, bool) {
+	var filter model.RequestFilter
+	var ok bool
+
+	if filter.UserId, ok = parseOptionalSnowflake(r, "user_id"); !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid user ID"})
+		return model.RequestFilter{}, false
+	}
+
+	if filter.GuildId, ok = parseOptionalSnowflake(r, "guild_id"); !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid guild ID"})
+		return model.RequestFilter{}, false
+	}
+
+	if raw := r.URL.Query().Get("type"); raw != "" {
+		requestType := model.RequestType(raw)
+		if !requestType.Valid() {
+			a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid request type"})
+			return model.RequestFilter{}, false
+		}
+
+		filter.Type = &requestType
+	}
+
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		status := model.RequestStatus(raw)
+		if !status.Valid() {
+			a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid status"})
+			return model.RequestFilter{}, false
+		}
+
+		filter.Status = &status
+	}
+
+	if filter.Before, ok = parseOptionalTime(r, "before"); !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": "Invalid before timestamp, expected RFC 3339"})
+		return model.RequestFilter{}, false
+	}
+
+	if filter.Limit, ok = parseLimit(r); !ok {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{"error": fmt.Sprintf("Limit must be between 1 and %d", maxLimit)})
+		return model.RequestFilter{}, false
+	}
+
+	return filter, true
+}
+
+// RequeueRequest queues a failed request to run again immediately, or moves a queued request to the front of its
+// wait. Skipping the grace period of a queued erasure, during which the user can still cancel it, requires the
+// skip_grace_period query parameter to be set to true.
+func (a *API) RequeueRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.getRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !request.Request.Status.Requeueable() {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": fmt.Sprintf("Only failed or queued requests can be requeued, this request is %s", request.Request.Status),
+		})
+		return
+	}
+
+	skipGracePeriod := r.URL.Query().Get("skip_grace_period") == "true"
+	if request.Request.Type.IsErasure() && request.Request.Status == model.RequestStatusQueued && !skipGracePeriod {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "Requeueing a queued erasure skips its grace period, set skip_grace_period=true to do so",
+		})
+		return
+	}
+
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) error {
+		return repository.Requeue(ctx, tx, request.Request.Id)
+	}); err != nil {
+		if errors.Is(err, repository.ErrTaskRunning) {
+			a.RespondJson(w, http.StatusConflict, utils.Map{
+				"error": "This request is being run by a worker, try again once it has finished",
+			})
+			return
+		}
+
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to requeue request"))
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "Request requeued by admin",
+		"request_id", request.Request.Id, "admin_id", a.userId(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelRequest cancels a request that has not yet run, removing its tasks from the queue.
+func (a *API) CancelRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.getRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if request.Request.Status != model.RequestStatusQueued && request.Request.Status != model.RequestStatusAwaitingConfirmation {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": fmt.Sprintf("Only queued requests can be cancelled, this request is %s", request.Request.Status),
+		})
+		return
+	}
+
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) error {
+		// The worker would overwrite the cancellation with its result
+		if running, err := tx.Tasks().HasRunningForRequest(ctx, request.Request.Id); err != nil {
+			return err
+		} else if running {
+			return repository.ErrTaskRunning
+		}
+
+		if _, err := tx.Tasks().DeleteForRequest(ctx, request.Request.Id); err != nil {
+			return err
+		}
+
+		return tx.Requests().SetStatus(ctx, request.Request.Id, model.RequestStatusCancelled)
+	}); err != nil {
+		if errors.Is(err, repository.ErrTaskRunning) {
+			a.RespondJson(w, http.StatusConflict, utils.Map{
+				"error": "This request is being run by a worker and can no longer be cancelled",
+			})
+			return
+		}
+
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to cancel request"))
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "Request cancelled by admin",
+		"request_id", request.Request.Id, "admin_id", a.userId(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExpireArtifact expires every part of a request's artifact immediately, and deletes them from the artifact store, so
+// that it can no longer be downloaded.
+func (a *API) ExpireArtifact(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.getRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !request.HasArtifact() {
+		a.RespondJson(w, http.StatusNotFound, utils.Map{
+			"error": "This request has no artifact",
+		})
+		return
+	}
+
+	var expired bool
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		expired, err = tx.Artifacts().Expire(ctx, request.Request.Id)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to expire artifact"))
+		return
+	}
+
+	if !expired {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "Artifact has already expired",
+		})
+		return
+	}
+
+	// The artifact row is kept so that downloads still count towards limits, only the data is deleted
+	for _, artifact := range request.Artifacts {
+		if err := a.Artifacts.Delete(r.Context(), artifact.RequestId, artifact.Key); err != nil {
+			a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Artifact expired, but failed to delete it from storage"))
+			return
+		}
+	}
+
+	a.Logger.InfoContext(r.Context(), "Artifact expired by admin",
+		"request_id", request.Request.Id, "admin_id", a.userId(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) getRequest(w http.ResponseWriter, r *http.Request) (*model.RequestWithArtifact, bool) {
+	requestId, err := uuid.Parse(chi.URLParam(r, "requestId"))
+	if err != nil {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Invalid request ID",
+		})
+		return nil, false
+	}
+
+	var request *model.RequestWithArtifact
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		request, err = tx.Requests().GetById(ctx, requestId)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch request"))
+		return nil, false
+	}
+
+	if request == nil {
+		a.RespondJson(w, http.StatusNotFound, utils.Map{
+			"error": "Request not found",
+		})
+		return nil, false
+	}
+
+	return request, true
+}
@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type RevokeBody struct {
+	Reason *string `json:"reason" validate:"omitempty,max=255"`
+}
+
+// ListRevokedSessions returns the revocation list: every revoked session that has not yet expired.
+func (a *API) ListRevokedSessions(w http.ResponseWriter, r *http.Request) {
+	var sessions []model.Session
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		sessions, err = tx.Sessions().ListRevoked(ctx)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch sessions"))
+		return
+	}
+
+	a.RespondJson(w, http.StatusOK, sessions)
+}
+
+func (a *API) ListUserSessions(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.ParseUint(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	var sessions []model.Session
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		sessions, err = tx.Sessions().ListForUser(ctx, userId)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch sessions"))
+		return
+	}
+
+	a.RespondJson(w, http.StatusOK, sessions)
+}
+
+// RevokeSession revokes a single session. API keys are not tied to a session, so are left valid; use
+// RevokeUserSessions to revoke them too.
+func (a *API) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := uuid.Parse(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Invalid session ID",
+		})
+		return
+	}
+
+	body, ok := a.decodeRevokeBody(w, r)
+	if !ok {
+		return
+	}
+
+	var revoked bool
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		revoked, err = tx.Sessions().Revoke(ctx, sessionId, a.userId(ctx), body.Reason)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to revoke session"))
+		return
+	}
+
+	if !revoked {
+		a.RespondJson(w, http.StatusNotFound, utils.Map{
+			"error": "Session not found or already revoked",
+		})
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "Session revoked by admin", "session_id", sessionId, "admin_id", a.userId(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessions revokes every session and API key belonging to a user, and deletes their stored Discord token,
+// forcing them to log in again.
+func (a *API) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userId, err := strconv.ParseUint(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Invalid user ID",
+		})
+		return
+	}
+
+	body, ok := a.decodeRevokeBody(w, r)
+	if !ok {
+		return
+	}
+
+	var revoked, revokedKeys int64
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		revoked, err = tx.Sessions().RevokeAllForUser(ctx, userId, a.userId(ctx), body.Reason)
+		if err != nil {
+			return err
+		}
+
+		revokedKeys, err = tx.ApiKeys().RevokeAllForUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		return tx.OAuthTokens().Delete(ctx, userId)
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to revoke sessions"))
+		return
+	}
+
+	a.GuildAccess.Invalidate(userId)
+
+	a.Logger.InfoContext(r.Context(), "User sessions revoked by admin",
+		"user_id", userId, "admin_id", a.userId(r.Context()), "count", revoked, "api_keys", revokedKeys)
+
+	a.RespondJson(w, http.StatusOK, utils.Map{
+		"revoked":          revoked,
+		"revoked_api_keys": revokedKeys,
+	})
+}
+
+// decodeRevokeBody decodes the optional revocation reason. An empty body is allowed.
+func (a *API) decodeRevokeBody(w http.ResponseWriter, r *http.Request) (RevokeBody, bool) {
+	var body RevokeBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusBadRequest, "Invalid body"))
+		return RevokeBody{}, false
+	}
+
+	if err := a.Validator.Struct(body); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusBadRequest, "Invalid body"))
+		return RevokeBody{}, false
+	}
+
+	return body, true
+}
@@ -0,0 +1,47 @@
+package apikeys
+
+import (
+	"context"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"strconv"
+)
+
+type API struct {
+	*api.Core
+}
+
+func NewAPI(core *api.Core) *API {
+	return &API{
+		Core: core,
+	}
+}
+
+func (a *API) userId(ctx context.Context) uint64 {
+	return ctx.Value("userId").(uint64)
+}
+
+func (a *API) guildAccess(ctx context.Context) map[uint64]model.AccessLevel {
+	return ctx.Value("guildAccess").(map[uint64]model.AccessLevel)
+}
+
+type ApiKeyDto struct {
+	model.ApiKey
+	// GuildIds are strings, as snowflakes do not fit in a JavaScript number
+	GuildIds []string `json:"guild_ids"`
+}
+
+func NewApiKeyDto(key model.ApiKey) ApiKeyDto {
+	var guildIds []string
+	if key.GuildIds != nil {
+		guildIds = make([]string, len(key.GuildIds))
+		for i, guildId := range key.GuildIds {
+			guildIds[i] = strconv.FormatUint(guildId, 10)
+		}
+	}
+
+	return ApiKeyDto{
+		ApiKey:   key,
+		GuildIds: guildIds,
+	}
+}
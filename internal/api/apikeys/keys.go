@@ -0,0 +1,180 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type CreateApiKeyBody struct {
+	Name   string              `json:"name" validate:"required,max=100"`
+	Scopes []model.ApiKeyScope `json:"scopes" validate:"required,min=1"`
+	// GuildIds limits the key to these guilds. If omitted, the key can be used for any guild the user has access to.
+	GuildIds      []string `json:"guild_ids" validate:"omitempty,max=100"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+func (a *API) ListKeys(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+
+	var keys []model.ApiKey
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		keys, err = tx.ApiKeys().ListForUser(ctx, userId)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch API keys"))
+		return
+	}
+
+	dto := make([]ApiKeyDto, len(keys))
+	for i, key := range keys {
+		dto[i] = NewApiKeyDto(key)
+	}
+
+	a.RespondJson(w, http.StatusOK, dto)
+}
+
+// CreateKey creates a new API key. The key itself is only ever returned in this response.
+func (a *API) CreateKey(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+
+	var body CreateApiKeyBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusBadRequest, "Invalid body"))
+		return
+	}
+
+	if err := a.Validator.Struct(body); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusBadRequest, "Invalid body"))
+		return
+	}
+
+	scopes := make([]model.ApiKeyScope, 0, len(body.Scopes))
+	for _, scope := range body.Scopes {
+		if !scope.Valid() {
+			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusBadRequest, fmt.Sprintf("Invalid scope: %s", scope)))
+			return
+		}
+
+		if !utils.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	// A key limited to no guilds could not be used for anything
+	if body.GuildIds != nil && len(body.GuildIds) == 0 {
+		a.HandleError(r.Context(), w, api.NewError(nil, http.StatusBadRequest,
+			"Guild IDs must not be empty, omit them to allow every guild"))
+		return
+	}
+
+	var guildIds []uint64
+	if body.GuildIds != nil {
+		guildIds = make([]uint64, 0, len(body.GuildIds))
+		for _, raw := range body.GuildIds {
+			guildId, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				a.HandleError(r.Context(), w, api.NewError(err, http.StatusBadRequest, "Invalid guild ID"))
+				return
+			}
+
+			// Access is re-checked whenever the key is used, this only catches mistakes early
+			if level := a.guildAccess(r.Context())[guildId]; level == model.AccessLevelNone {
+				a.HandleError(r.Context(), w, api.NewError(nil, http.StatusForbidden,
+					fmt.Sprintf("You do not have access to guild %d", guildId)))
+				return
+			}
+
+			guildIds = append(guildIds, guildId)
+		}
+	}
+
+	var expiresAt *time.Time
+	if body.ExpiresInDays != nil {
+		expiresAt = utils.Ptr(time.Now().Add(time.Hour * 24 * time.Duration(*body.ExpiresInDays)))
+	}
+
+	rawKey, err := utils.GenerateApiKey()
+	if err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to generate API key"))
+		return
+	}
+
+	var key model.ApiKey
+	var limitReached bool
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) error {
+		count, err := tx.ApiKeys().CountActiveForUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		if count >= a.Config.ApiKeys.MaxPerUser {
+			limitReached = true
+			return tx.Rollback(ctx)
+		}
+
+		key, err = tx.ApiKeys().Create(ctx, userId, body.Name, utils.HashApiKey(rawKey), scopes, guildIds, expiresAt)
+		return err
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to create API key"))
+		return
+	}
+
+	if limitReached {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": fmt.Sprintf("You can have at most %d API keys", a.Config.ApiKeys.MaxPerUser),
+		})
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "API key created", "user_id", userId, "key_id", key.Id)
+
+	a.RespondJson(w, http.StatusCreated, struct {
+		ApiKeyDto
+		Key string `json:"key"`
+	}{
+		ApiKeyDto: NewApiKeyDto(key),
+		Key:       rawKey,
+	})
+}
+
+func (a *API) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+
+	keyId, err := uuid.Parse(chi.URLParam(r, "keyId"))
+	if err != nil {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Invalid API key ID",
+		})
+		return
+	}
+
+	var revoked bool
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		revoked, err = tx.ApiKeys().Revoke(ctx, keyId, userId)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to revoke API key"))
+		return
+	}
+
+	if !revoked {
+		a.RespondJson(w, http.StatusNotFound, utils.Map{
+			"error": "API key not found",
+		})
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "API key revoked", "user_id", userId, "key_id", keyId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
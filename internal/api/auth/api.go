@@ -1,21 +1,25 @@
 package auth
 
 import (
+	"context"
 	"github.com/TicketsBot/export/internal/api"
-	"net/http"
-	"time"
+	"github.com/google/uuid"
 )
 
 type API struct {
 	*api.Core
-	client *http.Client
 }
 
 func NewAPI(core *api.Core) *API {
 	return &API{
 		Core: core,
-		client: &http.Client{
-			Timeout: time.Second * 15,
-		},
 	}
 }
+
+func (a *API) userId(ctx context.Context) uint64 {
+	return ctx.Value("userId").(uint64)
+}
+
+func (a *API) sessionId(ctx context.Context) uuid.UUID {
+	return ctx.Value("sessionId").(uuid.UUID)
+}
@@ -1,20 +1,19 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/TicketsBot/export/internal/api"
 	"github.com/TicketsBot/export/internal/api/constants"
 	"github.com/TicketsBot/export/internal/metrics"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwe"
 	"github.com/lestrrat-go/jwx/v3/jwt"
-	"io"
 	"net/http"
-	"net/url"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -22,8 +21,6 @@ type ExchangeBody struct {
 	Code string `json:"code" validate:"required"`
 }
 
-const DiscordApiVersion = 10
-
 func (a *API) Exchange(w http.ResponseWriter, r *http.Request) {
 	var body ExchangeBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -36,46 +33,70 @@ func (a *API) Exchange(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bearerToken, err := a.exchangeToken(body.Code)
+	token, err := a.Discord.ExchangeCode(r.Context(), body.Code)
 	if err != nil {
-		a.HandleError(r.Context(), w, err)
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to exchange code for token"))
 		return
 	}
 
 	// Get user ID
-	userId, err := a.fetchUserId(bearerToken)
+	userId, err := a.Discord.FetchUserId(r.Context(), token.AccessToken)
 	if err != nil {
-		a.HandleError(r.Context(), w, err)
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch user ID"))
 		return
 	}
 
-	guilds, err := a.retrieveGuilds(r.Context(), bearerToken)
+	guilds, err := a.Discord.FetchGuilds(r.Context(), token.AccessToken)
 	if err != nil {
-		a.HandleError(r.Context(), w, err)
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch guilds"))
+		return
+	}
+
+	// The token is kept so that guild access can be re-checked on sensitive operations, rather than trusting the
+	// claims below for the lifetime of the JWT
+	if err := a.GuildAccess.StoreToken(r.Context(), userId, token); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to store token"))
 		return
 	}
 
-	var ownedGuilds []string
+	// Only guilds the user has some control over are included, to keep the token small
+	guildAccess := make(map[string]model.AccessLevel)
+	currentAccess := make(map[uint64]model.AccessLevel)
 	for _, g := range guilds {
-		if g.Owner {
-			ownedGuilds = append(ownedGuilds, strconv.FormatUint(g.Id, 10))
+		if g.AccessLevel > model.AccessLevelNone {
+			guildAccess[strconv.FormatUint(g.Id, 10)] = g.AccessLevel
+			currentAccess[g.Id] = g.AccessLevel
 		}
 	}
 
-	token, tokenErr := jwt.NewBuilder().
+	a.GuildAccess.Set(userId, currentAccess)
+
+	expiresAt := time.Now().Add(a.Config.Jwt.Expiry)
+
+	var session model.Session
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		session, err = tx.Sessions().Create(ctx, userId, expiresAt)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to create session"))
+		return
+	}
+
+	jwtToken, tokenErr := jwt.NewBuilder().
+		JwtID(session.Id.String()).
 		Issuer("https://export.ticketsbot.net").
 		IssuedAt(time.Now()).
 		Subject(strconv.FormatUint(userId, 10)).
-		Expiration(time.Now().Add(a.Config.Jwt.Expiry)).
+		Expiration(expiresAt).
 		NotBefore(time.Now()).
-		Claim(constants.JwtClaimOwnedGuilds, ownedGuilds).
+		Claim(constants.JwtClaimGuildAccess, guildAccess).
 		Build()
 	if tokenErr != nil {
 		a.HandleError(r.Context(), w, api.NewError(tokenErr, http.StatusInternalServerError, "Failed to issue token"))
 		return
 	}
 
-	signed, signErr := jwt.Sign(token, jwt.WithKey(jwa.HS256(), []byte(a.Config.Jwt.Secret)))
+	signed, signErr := jwt.Sign(jwtToken, jwt.WithKey(jwa.HS256(), []byte(a.Config.Jwt.Secret)))
 	if signErr != nil {
 		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to issue token"))
 		return
@@ -98,74 +119,3 @@ func (a *API) Exchange(w http.ResponseWriter, r *http.Request) {
 		"guilds": guilds,
 	})
 }
-
-type tokenResponse struct {
-	AccessToken string `json:"access_token"`
-}
-
-func (a *API) exchangeToken(code string) (string, *api.Error) {
-	uri := fmt.Sprintf("%s/api/v%d/oauth2/token", a.Config.Discord.RootUrl, DiscordApiVersion)
-
-	body := url.Values{}
-	body.Set("grant_type", "authorization_code")
-	body.Set("code", code)
-	body.Set("redirect_uri", a.Config.Discord.RedirectUri)
-	body.Set("client_id", a.Config.Discord.ClientId)
-	body.Set("client_secret", a.Config.Discord.ClientSecret)
-
-	res, err := a.client.Post(uri, "application/x-www-form-urlencoded", strings.NewReader(body.Encode()))
-	if err != nil {
-		return "", api.NewError(err, http.StatusInternalServerError, "Failed to exchange code for token")
-	}
-
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(res.Body)
-		err := fmt.Errorf("unexpected status code during token exchange %d: %s", res.StatusCode, body)
-
-		return "", api.NewError(err, http.StatusInternalServerError, "Failed to exchange code for token")
-	}
-
-	var token tokenResponse
-	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
-		return "", api.NewError(err, http.StatusInternalServerError, "Failed to decode token response")
-	}
-
-	return token.AccessToken, nil
-}
-
-func (a *API) fetchUserId(token string) (uint64, *api.Error) {
-	uri := fmt.Sprintf("%s/api/v%d/users/@me", a.Config.Discord.RootUrl, DiscordApiVersion)
-
-	req, err := http.NewRequest(http.MethodGet, uri, nil)
-	if err != nil {
-		return 0, api.NewError(err, http.StatusInternalServerError, "Failed to create requests")
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-
-	res, err := a.client.Do(req)
-	if err != nil {
-		return 0, api.NewError(err, http.StatusInternalServerError, "Failed to fetch user ID")
-	}
-
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(res.Body)
-		err := fmt.Errorf("unexpected status code during user fetch %d: %s", res.StatusCode, body)
-
-		return 0, api.NewError(err, http.StatusInternalServerError, "Failed to fetch user ID")
-	}
-
-	var user struct {
-		Id uint64 `json:"id,string"`
-	}
-
-	if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
-		return 0, api.NewError(err, http.StatusInternalServerError, "Failed to fetch user")
-	}
-
-	return user.Id, nil
-}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"net/http"
+)
+
+// Logout revokes the session the request was made with.
+func (a *API) Logout(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+	sessionId := a.sessionId(r.Context())
+
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) error {
+		_, err := tx.Sessions().Revoke(ctx, sessionId, userId, utils.Ptr("Logged out"))
+		return err
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to log out"))
+		return
+	}
+
+	a.Logger.DebugContext(r.Context(), "User logged out", "user_id", userId, "session_id", sessionId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll revokes every session and API key belonging to the user, and deletes their stored Discord token, so that
+// they must log in with Discord again on every device.
+func (a *API) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+
+	var revoked, revokedKeys int64
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		revoked, err = tx.Sessions().RevokeAllForUser(ctx, userId, userId, utils.Ptr("Logged out of all sessions"))
+		if err != nil {
+			return err
+		}
+
+		// A key is as good as a session, so one left behind would undo logging out everywhere
+		revokedKeys, err = tx.ApiKeys().RevokeAllForUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		return tx.OAuthTokens().Delete(ctx, userId)
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to log out"))
+		return
+	}
+
+	a.GuildAccess.Invalidate(userId)
+
+	a.Logger.InfoContext(r.Context(), "User logged out of all sessions", "user_id", userId, "count", revoked,
+		"api_keys", revokedKeys)
+
+	a.RespondJson(w, http.StatusOK, utils.Map{
+		"revoked":          revoked,
+		"revoked_api_keys": revokedKeys,
+	})
+}
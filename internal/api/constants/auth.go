@@ -1,3 +1,3 @@
 package constants
 
-const JwtClaimOwnedGuilds = "owned_guilds"
+const JwtClaimGuildAccess = "guild_access"
@@ -3,20 +3,27 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"github.com/TicketsBot/export/internal/api/discord"
+	"github.com/TicketsBot/export/internal/api/guildaccess"
+	"github.com/TicketsBot/export/internal/api/ratelimit"
 	"github.com/TicketsBot/export/internal/artifactstore"
 	"github.com/TicketsBot/export/internal/config"
 	"github.com/TicketsBot/export/internal/repository"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"log/slog"
 	"net/http"
 )
 
 type Core struct {
-	Logger     *slog.Logger
-	Config     config.ApiConfig
-	Repository *repository.Repository
-	Validator  *validator.Validate
-	Artifacts  artifactstore.ArtifactStore
+	Logger        *slog.Logger
+	Config        config.ApiConfig
+	Repository    *repository.Repository
+	Validator     *validator.Validate
+	Artifacts     artifactstore.ArtifactStore
+	Discord       *discord.Client
+	GuildAccess   *guildaccess.Verifier
+	ApiKeyLimiter *ratelimit.Limiter[uuid.UUID]
 }
 
 func NewCore(
@@ -25,12 +32,18 @@ func NewCore(
 	repository *repository.Repository,
 	artifacts artifactstore.ArtifactStore,
 ) *Core {
+	discordClient := discord.NewClient(config)
+
 	return &Core{
 		Logger:     logger,
 		Config:     config,
 		Repository: repository,
 		Validator:  validator.New(),
 		Artifacts:  artifacts,
+		Discord:    discordClient,
+		GuildAccess: guildaccess.NewVerifier(logger.With("component", "guildaccess"), discordClient, repository,
+			[]byte(config.Discord.TokenEncryptionKey), config.GuildAccessCacheTtl),
+		ApiKeyLimiter: ratelimit.NewLimiter[uuid.UUID](config.ApiKeys.RateLimit, config.ApiKeys.RateLimitWindow),
 	}
 }
 
@@ -0,0 +1,173 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/internal/config"
+	"github.com/TicketsBot/export/internal/model"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const ApiVersion = 10
+
+// ErrUnauthorized is returned when Discord rejects a token or grant, meaning the user must log in again.
+var ErrUnauthorized = errors.New("discord rejected the token")
+
+type Client struct {
+	config config.ApiConfig
+	client *http.Client
+}
+
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+type Guild struct {
+	Id          uint64            `json:"id,string"`
+	Name        string            `json:"name"`
+	Icon        *string           `json:"icon"`
+	Owner       bool              `json:"owner"`
+	Permissions uint64            `json:"permissions,string"`
+	AccessLevel model.AccessLevel `json:"access_level"`
+}
+
+func NewClient(config config.ApiConfig) *Client {
+	return &Client{
+		config: config,
+		client: &http.Client{
+			Timeout: time.Second * 15,
+		},
+	}
+}
+
+// ExchangeCode exchanges an OAuth2 authorization code for a token pair.
+func (c *Client) ExchangeCode(ctx context.Context, code string) (Token, error) {
+	body := url.Values{}
+	body.Set("grant_type", "authorization_code")
+	body.Set("code", code)
+	body.Set("redirect_uri", c.config.Discord.RedirectUri)
+
+	return c.requestToken(ctx, body)
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Discord rotates refresh tokens, so the returned refresh
+// token must be stored in place of the old one.
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (Token, error) {
+	body := url.Values{}
+	body.Set("grant_type", "refresh_token")
+	body.Set("refresh_token", refreshToken)
+
+	return c.requestToken(ctx, body)
+}
+
+func (c *Client) requestToken(ctx context.Context, body url.Values) (Token, error) {
+	uri := fmt.Sprintf("%s/api/v%d/oauth2/token", c.config.Discord.RootUrl, ApiVersion)
+
+	body.Set("client_id", c.config.Discord.ClientId)
+	body.Set("client_secret", c.config.Discord.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(body.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return Token{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+
+		// Discord returns 400 invalid_grant for expired, revoked or already used codes and refresh tokens
+		if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized {
+			return Token{}, fmt.Errorf("%w: status code %d during token exchange: %s", ErrUnauthorized, res.StatusCode, body)
+		}
+
+		return Token{}, fmt.Errorf("unexpected status code during token exchange %d: %s", res.StatusCode, body)
+	}
+
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return Token{}, err
+	}
+
+	return Token{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func (c *Client) FetchUserId(ctx context.Context, accessToken string) (uint64, error) {
+	var user struct {
+		Id uint64 `json:"id,string"`
+	}
+
+	if err := c.get(ctx, accessToken, "/users/@me", &user); err != nil {
+		return 0, err
+	}
+
+	return user.Id, nil
+}
+
+// FetchGuilds returns the guilds the user is a member of, with the access level the user has over each.
+func (c *Client) FetchGuilds(ctx context.Context, accessToken string) ([]Guild, error) {
+	var guilds []Guild
+	if err := c.get(ctx, accessToken, "/users/@me/guilds", &guilds); err != nil {
+		return nil, err
+	}
+
+	for i, g := range guilds {
+		guilds[i].AccessLevel = model.AccessLevelFromPermissions(g.Owner, g.Permissions)
+	}
+
+	return guilds, nil
+}
+
+func (c *Client) get(ctx context.Context, accessToken, path string, out any) error {
+	uri := fmt.Sprintf("%s/api/v%d%s", c.config.Discord.RootUrl, ApiVersion, path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+
+		if res.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("%w: status code %d fetching %s: %s", ErrUnauthorized, res.StatusCode, path, body)
+		}
+
+		return fmt.Errorf("unexpected status code fetching %s %d: %s", path, res.StatusCode, body)
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
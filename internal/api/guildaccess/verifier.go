@@ -0,0 +1,241 @@
+package guildaccess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/common/encryption"
+	"github.com/TicketsBot/export/internal/api/discord"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// fetchTimeout bounds how long fetching a user's guild access from Discord, including refreshing their token, may take.
+const fetchTimeout = time.Second * 30
+
+// ErrReauthenticate is returned when there is no usable OAuth token stored for the user, for example because they
+// logged in before tokens were stored, or because they have deauthorised the application.
+var ErrReauthenticate = errors.New("no valid oauth token stored for user")
+
+// Verifier re-checks a user's access to their guilds against Discord, using the OAuth token stored when they logged
+// in. Results are cached for a short time, so that a burst of requests does not hit Discord's rate limits.
+type Verifier struct {
+	logger        *slog.Logger
+	discord       *discord.Client
+	repository    *repository.Repository
+	encryptionKey []byte
+	ttl           time.Duration
+
+	mu    sync.Mutex
+	cache map[uint64]cacheEntry
+	group singleflight.Group
+}
+
+type cacheEntry struct {
+	guildAccess map[uint64]model.AccessLevel
+	fetchedAt   time.Time
+}
+
+func NewVerifier(
+	logger *slog.Logger,
+	discord *discord.Client,
+	repository *repository.Repository,
+	encryptionKey []byte,
+	ttl time.Duration,
+) *Verifier {
+	return &Verifier{
+		logger:        logger,
+		discord:       discord,
+		repository:    repository,
+		encryptionKey: encryptionKey,
+		ttl:           ttl,
+		cache:         make(map[uint64]cacheEntry),
+	}
+}
+
+// GuildAccess returns the user's current access level over each guild they have any control over.
+func (v *Verifier) GuildAccess(ctx context.Context, userId uint64) (map[uint64]model.AccessLevel, error) {
+	v.mu.Lock()
+	entry, ok := v.cache[userId]
+	v.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < v.ttl {
+		return entry.guildAccess, nil
+	}
+
+	// Discord rotates refresh tokens, so concurrent refreshes for the same user would invalidate each other. The fetch
+	// is shared by every waiting caller, so it must not be cancelled just because the first caller's request is.
+	ch := v.group.DoChan(strconv.FormatUint(userId, 10), func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		guildAccess, err := v.fetchGuildAccess(fetchCtx, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		v.Set(userId, guildAccess)
+		return guildAccess, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		return res.Val.(map[uint64]model.AccessLevel), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Set caches the user's guild access, for example when it has just been fetched during login.
+func (v *Verifier) Set(userId uint64, guildAccess map[uint64]model.AccessLevel) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.cache[userId] = cacheEntry{
+		guildAccess: guildAccess,
+		fetchedAt:   time.Now(),
+	}
+
+	// Sweep expired entries while we hold the lock, so the cache does not grow with every user that has logged in
+	for id, entry := range v.cache {
+		if time.Since(entry.fetchedAt) >= v.ttl {
+			delete(v.cache, id)
+		}
+	}
+}
+
+// Invalidate removes the user's guild access from the cache, so that it is fetched again on next use.
+func (v *Verifier) Invalidate(userId uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.cache, userId)
+}
+
+// StoreToken encrypts and stores the user's token pair, so that their access can be re-checked later.
+func (v *Verifier) StoreToken(ctx context.Context, userId uint64, token discord.Token) error {
+	accessToken, err := encryption.Encrypt(v.encryptionKey, []byte(token.AccessToken))
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := encryption.Encrypt(v.encryptionKey, []byte(token.RefreshToken))
+	if err != nil {
+		return err
+	}
+
+	return v.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+		return tx.OAuthTokens().Set(ctx, model.OAuthToken{
+			UserId:       userId,
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresAt:    token.ExpiresAt,
+		})
+	})
+}
+
+func (v *Verifier) fetchGuildAccess(ctx context.Context, userId uint64) (map[uint64]model.AccessLevel, error) {
+	token, err := v.loadToken(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := false
+	if time.Now().After(token.ExpiresAt) {
+		if token, err = v.refresh(ctx, userId, token); err != nil {
+			return nil, err
+		}
+
+		refreshed = true
+	}
+
+	guilds, err := v.discord.FetchGuilds(ctx, token.AccessToken)
+	if err != nil && errors.Is(err, discord.ErrUnauthorized) && !refreshed {
+		// The access token may have been revoked before its expiry, so try once more with a fresh one
+		if token, err = v.refresh(ctx, userId, token); err != nil {
+			return nil, err
+		}
+
+		guilds, err = v.discord.FetchGuilds(ctx, token.AccessToken)
+	}
+
+	if err != nil {
+		if errors.Is(err, discord.ErrUnauthorized) {
+			return nil, fmt.Errorf("%w: %w", ErrReauthenticate, err)
+		}
+
+		return nil, err
+	}
+
+	guildAccess := make(map[uint64]model.AccessLevel)
+	for _, guild := range guilds {
+		if guild.AccessLevel > model.AccessLevelNone {
+			guildAccess[guild.Id] = guild.AccessLevel
+		}
+	}
+
+	return guildAccess, nil
+}
+
+func (v *Verifier) refresh(ctx context.Context, userId uint64, token discord.Token) (discord.Token, error) {
+	refreshed, err := v.discord.RefreshToken(ctx, token.RefreshToken)
+	if err != nil {
+		if errors.Is(err, discord.ErrUnauthorized) {
+			// The refresh token is no longer valid, so there is no point keeping it
+			if err := v.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+				return tx.OAuthTokens().Delete(ctx, userId)
+			}); err != nil {
+				v.logger.ErrorContext(ctx, "Failed to delete revoked OAuth token", "user_id", userId, "error", err)
+			}
+
+			return discord.Token{}, fmt.Errorf("%w: %w", ErrReauthenticate, err)
+		}
+
+		return discord.Token{}, err
+	}
+
+	if err := v.StoreToken(ctx, userId, refreshed); err != nil {
+		return discord.Token{}, err
+	}
+
+	return refreshed, nil
+}
+
+func (v *Verifier) loadToken(ctx context.Context, userId uint64) (discord.Token, error) {
+	var stored *model.OAuthToken
+	if err := v.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		stored, err = tx.OAuthTokens().Get(ctx, userId)
+		return
+	}); err != nil {
+		return discord.Token{}, err
+	}
+
+	if stored == nil {
+		return discord.Token{}, ErrReauthenticate
+	}
+
+	accessToken, err := encryption.Decrypt(v.encryptionKey, stored.AccessToken)
+	if err != nil {
+		return discord.Token{}, err
+	}
+
+	refreshToken, err := encryption.Decrypt(v.encryptionKey, stored.RefreshToken)
+	if err != nil {
+		return discord.Token{}, err
+	}
+
+	return discord.Token{
+		AccessToken:  string(accessToken),
+		RefreshToken: string(refreshToken),
+		ExpiresAt:    stored.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/utils"
+	"net/http"
+)
+
+// RequireAdmin only allows users listed in the admin config through. It must be used after Authenticate.
+func RequireAdmin(a *api.Core) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userId, ok := r.Context().Value("userId").(uint64)
+			if !ok || !utils.Contains(a.Config.AdminUserIds, userId) {
+				err := api.NewError(errors.New("user is not an admin"), http.StatusForbidden, "You do not have permission to access this resource")
+				a.HandleError(r.Context(), w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"github.com/TicketsBot/export/internal/api"
 	"github.com/TicketsBot/export/internal/api/constants"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
 	"github.com/TicketsBot/export/internal/utils"
+	"github.com/google/uuid"
 	"github.com/lestrrat-go/jwx/v3/jwa"
 	"github.com/lestrrat-go/jwx/v3/jwe"
 	"github.com/lestrrat-go/jwx/v3/jwt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func Authenticate(a *api.Core) func(handler http.Handler) http.Handler {
@@ -32,6 +36,17 @@ func Authenticate(a *api.Core) func(handler http.Handler) http.Handler {
 				return
 			}
 
+			if strings.HasPrefix(split[1], model.ApiKeyPrefix) {
+				ctx, err := authenticateApiKey(a, r.Context(), split[1])
+				if err != nil {
+					a.HandleError(r.Context(), w, err)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			decoded, err := utils.Base64Decode(split[1])
 			if err != nil {
 				err := api.NewError(fmt.Errorf("invalid token, bad encoding: %w", err), http.StatusUnauthorized, "Invalid token")
@@ -60,7 +75,29 @@ func Authenticate(a *api.Core) func(handler http.Handler) http.Handler {
 				return
 			}
 
-			ownedGuilds, extractErr := extractOwnedGuilds(token)
+			sessionId, extractErr := extractSessionId(token)
+			if extractErr != nil {
+				a.HandleError(r.Context(), w, extractErr)
+				return
+			}
+
+			// Tokens are only valid for as long as their session, so that they can be revoked before they expire
+			var session *model.Session
+			if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+				session, err = tx.Sessions().GetById(ctx, sessionId)
+				return
+			}); err != nil {
+				a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch session"))
+				return
+			}
+
+			if session == nil || session.UserId != userId || !session.Valid() {
+				err := api.NewError(errors.New("session revoked or expired"), http.StatusUnauthorized, "Your session has expired, please log in again")
+				a.HandleError(r.Context(), w, err)
+				return
+			}
+
+			guildAccess, extractErr := extractGuildAccess(token)
 			if extractErr != nil {
 				a.HandleError(r.Context(), w, extractErr)
 				return
@@ -68,13 +105,40 @@ func Authenticate(a *api.Core) func(handler http.Handler) http.Handler {
 
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, "userId", userId)
-			ctx = context.WithValue(ctx, "ownedGuilds", ownedGuilds)
+			ctx = context.WithValue(ctx, "sessionId", sessionId)
+			ctx = context.WithValue(ctx, "guildAccess", guildAccess)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticateApiKey authenticates a request made with a personal API key. Guild access for API keys is not taken from
+// a token, and is instead always re-checked against Discord.
+func authenticateApiKey(a *api.Core, ctx context.Context, rawKey string) (context.Context, *api.Error) {
+	var key *model.ApiKey
+	if err := a.Repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		key, err = tx.ApiKeys().Use(ctx, utils.HashApiKey(rawKey))
+		return
+	}); err != nil {
+		return nil, api.NewError(err, http.StatusInternalServerError, "Failed to fetch API key")
+	}
+
+	if key == nil {
+		return nil, api.NewError(errors.New("unknown, expired or revoked API key"), http.StatusUnauthorized, "Invalid API key")
+	}
+
+	if ok, retryAfter := a.ApiKeyLimiter.Allow(key.Id); !ok {
+		return nil, api.NewError(fmt.Errorf("api key %s rate limited", key.Id), http.StatusTooManyRequests,
+			fmt.Sprintf("API key rate limit exceeded, try again in %s", retryAfter.Round(time.Second)))
+	}
+
+	ctx = context.WithValue(ctx, "userId", key.UserId)
+	ctx = context.WithValue(ctx, "apiKey", key)
+
+	return ctx, nil
+}
+
 func extractUserId(claims jwt.Token) (uint64, *api.Error) {
 	var userIdRaw string
 	if err := claims.Get("sub", &userIdRaw); err != nil {
@@ -89,28 +153,48 @@ func extractUserId(claims jwt.Token) (uint64, *api.Error) {
 	return userId, nil
 }
 
-func extractOwnedGuilds(claims jwt.Token) ([]uint64, *api.Error) {
-	var guildsSlice []interface{}
-	if err := claims.Get(constants.JwtClaimOwnedGuilds, &guildsSlice); err != nil {
-		return nil, api.NewError(err, http.StatusUnauthorized, "Invalid token: invalid owned guilds")
+func extractSessionId(claims jwt.Token) (uuid.UUID, *api.Error) {
+	// Tokens issued before sessions were introduced have no ID, and so cannot be revoked
+	sessionIdRaw, ok := claims.JwtID()
+	if !ok {
+		return uuid.Nil, api.NewError(errors.New("token has no session ID"), http.StatusUnauthorized, "Your session has expired, please log in again")
 	}
 
-	ownedGuilds := make([]uint64, len(guildsSlice))
-	for i, guildIdRaw := range guildsSlice {
-		guild, ok := guildIdRaw.(string)
-		if !ok {
-			return nil, api.NewError(fmt.Errorf("invalid token, guild id was not a string"),
-				http.StatusUnauthorized, "Invalid token")
-		}
+	sessionId, err := uuid.Parse(sessionIdRaw)
+	if err != nil {
+		return uuid.Nil, api.NewError(err, http.StatusUnauthorized, "Invalid token: invalid session ID")
+	}
 
-		guildId, err := strconv.ParseUint(guild, 10, 64)
+	return sessionId, nil
+}
+
+func extractGuildAccess(claims jwt.Token) (map[uint64]model.AccessLevel, *api.Error) {
+	var guildsMap map[string]interface{}
+	if err := claims.Get(constants.JwtClaimGuildAccess, &guildsMap); err != nil {
+		return nil, api.NewError(err, http.StatusUnauthorized, "Invalid token: invalid guild access")
+	}
+
+	guildAccess := make(map[uint64]model.AccessLevel, len(guildsMap))
+	for guildIdRaw, levelRaw := range guildsMap {
+		guildId, err := strconv.ParseUint(guildIdRaw, 10, 64)
 		if err != nil {
 			return nil, api.NewError(fmt.Errorf("invalid token, guild ID was not a uint: %w", err),
 				http.StatusUnauthorized, "Invalid token")
 		}
 
-		ownedGuilds[i] = guildId
+		levelStr, ok := levelRaw.(string)
+		if !ok {
+			return nil, api.NewError(fmt.Errorf("invalid token, access level was not a string"),
+				http.StatusUnauthorized, "Invalid token")
+		}
+
+		var level model.AccessLevel
+		if err := level.UnmarshalText([]byte(levelStr)); err != nil {
+			return nil, api.NewError(fmt.Errorf("invalid token: %w", err), http.StatusUnauthorized, "Invalid token")
+		}
+
+		guildAccess[guildId] = level
 	}
 
-	return ownedGuilds, nil
+	return guildAccess, nil
 }
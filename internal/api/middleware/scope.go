@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"net/http"
+)
+
+// RequireScope only allows requests made with an API key through if the key has the given scope. Requests made with a
+// session token are always allowed. It must be used after Authenticate.
+func RequireScope(a *api.Core, scope model.ApiKeyScope) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := r.Context().Value("apiKey").(*model.ApiKey); ok && !key.HasScope(scope) {
+				err := api.NewError(fmt.Errorf("api key missing scope %s", scope), http.StatusForbidden,
+					fmt.Sprintf("This API key does not have the %s scope", scope))
+				a.HandleError(r.Context(), w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests made with an API key, for endpoints that must only be used interactively. It must
+// be used after Authenticate.
+func RequireSession(a *api.Core) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value("apiKey").(*model.ApiKey); ok {
+				err := api.NewError(errors.New("api key used for session only endpoint"), http.StatusForbidden,
+					"This endpoint cannot be used with an API key")
+				a.HandleError(r.Context(), w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
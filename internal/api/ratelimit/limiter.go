@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a fixed window rate limiter, keyed by any comparable value. State is held in memory, so limits apply per
+// API instance.
+type Limiter[K comparable] struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[K]*window
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+func NewLimiter[K comparable](limit int, period time.Duration) *Limiter[K] {
+	return &Limiter[K]{
+		limit:   limit,
+		window:  period,
+		windows: make(map[K]*window),
+	}
+}
+
+// Allow records a request for the key, and returns whether it is within the limit. If it is not, the time until the
+// current window resets is also returned.
+func (l *Limiter[K]) Allow(key K) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.sweep(now)
+
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+
+	w.count++
+	return true, 0
+}
+
+// sweep removes windows that have ended, so that keys that are no longer used do not accumulate.
+func (l *Limiter[K]) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
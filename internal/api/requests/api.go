@@ -2,7 +2,11 @@ package requests
 
 import (
 	"context"
+	"errors"
 	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/api/guildaccess"
+	"github.com/TicketsBot/export/internal/model"
+	"net/http"
 )
 
 type API struct {
@@ -19,6 +23,52 @@ func (a *API) userId(ctx context.Context) uint64 {
 	return ctx.Value("userId").(uint64)
 }
 
-func (a *API) ownedGuilds(ctx context.Context) []uint64 {
-	return ctx.Value("ownedGuilds").([]uint64)
+func (a *API) guildAccess(ctx context.Context) map[uint64]model.AccessLevel {
+	return ctx.Value("guildAccess").(map[uint64]model.AccessLevel)
+}
+
+// apiKey returns the API key the request was authenticated with, or nil if it was made with a session token.
+func (a *API) apiKey(ctx context.Context) *model.ApiKey {
+	key, _ := ctx.Value("apiKey").(*model.ApiKey)
+	return key
+}
+
+// keyAllowsGuild returns whether the API key the request was made with, if any, may be used for the given guild, or
+// for the user's own data if guildId is nil.
+func (a *API) keyAllowsGuild(ctx context.Context, guildId *uint64) bool {
+	key := a.apiKey(ctx)
+	return key == nil || key.AllowsGuild(guildId)
+}
+
+// hasGuildAccess returns whether the user has a high enough access level over the guild for the request type. The
+// level in the token is checked first, as it is free, and then re-checked against Discord, as the token may have been
+// issued before the user lost access to the guild. Requests made with an API key must also be for a guild the key is
+// limited to, if any.
+func (a *API) hasGuildAccess(ctx context.Context, requestType model.RequestType, guildId *uint64) (bool, *api.Error) {
+	if guildId == nil {
+		return false, nil
+	}
+
+	required := a.Config.RequiredAccessLevel(requestType)
+
+	// API keys have no token to check, so rely on the check against Discord alone
+	if key := a.apiKey(ctx); key != nil {
+		if !key.AllowsGuild(guildId) {
+			return false, nil
+		}
+	} else if level, ok := a.guildAccess(ctx)[*guildId]; !ok || level < required {
+		return false, nil
+	}
+
+	current, err := a.GuildAccess.GuildAccess(ctx, a.userId(ctx))
+	if err != nil {
+		if errors.Is(err, guildaccess.ErrReauthenticate) {
+			return false, api.NewError(err, http.StatusUnauthorized, "Your session has expired, please log in again")
+		}
+
+		return false, api.NewError(err, http.StatusInternalServerError, "Failed to verify guild access")
+	}
+
+	level, ok := current[*guildId]
+	return ok && level >= required, nil
 }
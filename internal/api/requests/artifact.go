@@ -2,6 +2,7 @@ package requests
 
 import (
 	"context"
+	"fmt"
 	"github.com/TicketsBot/export/internal/api"
 	"github.com/TicketsBot/export/internal/metrics"
 	"github.com/TicketsBot/export/internal/model"
@@ -9,14 +10,16 @@ import (
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 )
 
+// GetArtifact serves one part of a request's artifact. The part is taken from the URL, and defaults to the first part,
+// which is the whole artifact unless the export was split into parts.
 func (a *API) GetArtifact(w http.ResponseWriter, r *http.Request) {
 	userId := a.userId(r.Context())
-	ownedGuilds := a.ownedGuilds(r.Context())
 
 	requestId, err := uuid.Parse(chi.URLParam(r, "requestId"))
 	if err != nil {
@@ -26,7 +29,18 @@ func (a *API) GetArtifact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logger := a.Logger.With("request_id", requestId, "user_id", userId)
+	part := 1
+	if raw := chi.URLParam(r, "part"); raw != "" {
+		part, err = strconv.Atoi(raw)
+		if err != nil || part < 1 {
+			a.RespondJson(w, http.StatusBadRequest, utils.Map{
+				"error": "Invalid part number",
+			})
+			return
+		}
+	}
+
+	logger := a.Logger.With("request_id", requestId, "user_id", userId, "part", part)
 
 	var request *model.RequestWithArtifact
 	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
@@ -37,7 +51,7 @@ func (a *API) GetArtifact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if request == nil || request.Artifact == nil {
+	if request == nil || !request.HasArtifact() {
 		a.RespondJson(w, http.StatusNotFound, utils.Map{
 			"error": "Data export not found",
 		})
@@ -51,23 +65,43 @@ func (a *API) GetArtifact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if request.Artifact.ExpiresAt.Before(time.Now()) {
+	artifact := request.ArtifactPart(part)
+	if artifact == nil {
+		a.RespondJson(w, http.StatusNotFound, utils.Map{
+			"error": "Data export part not found",
+		})
+		return
+	}
+
+	if artifact.ExpiresAt.Before(time.Now()) {
 		a.RespondJson(w, http.StatusGone, utils.Map{
 			"error": "Artifact has expired",
 		})
 		return
 	}
 
-	if request.Request.GuildId == nil || !utils.Contains(ownedGuilds, *request.Request.GuildId) {
+	if !a.keyAllowsGuild(r.Context(), request.Request.GuildId) {
 		a.RespondJson(w, http.StatusForbidden, utils.Map{
-			"error": "User does not own this guild",
+			"error": "This API key cannot be used to access this request",
 		})
 		return
 	}
 
+	if request.Request.Type.GuildScoped() {
+		if ok, err := a.hasGuildAccess(r.Context(), request.Request.Type, request.Request.GuildId); err != nil {
+			a.HandleError(r.Context(), w, err)
+			return
+		} else if !ok {
+			a.RespondJson(w, http.StatusForbidden, utils.Map{
+				"error": "You no longer have permission to access this guild's data",
+			})
+			return
+		}
+	}
+
 	var limitedExceeded bool
 	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) error {
-		if err := tx.Downloads().Create(ctx, userId, request.Artifact.Id); err != nil {
+		if err := tx.Downloads().Create(ctx, userId, artifact.Id); err != nil {
 			return err
 		}
 
@@ -103,21 +137,37 @@ func (a *API) GetArtifact(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info("Fetching artifact")
 
-	// Get artifact
-	bytes, err := a.Artifacts.Fetch(r.Context(), request.Artifact.RequestId, request.Artifact.Key)
+	// The artifact is decrypted as it is sent, rather than held in memory, as parts can be up to MAX_PART_MEGABYTES
+	reader, err := a.Artifacts.Fetch(r.Context(), artifact.RequestId, artifact.Key)
 	if err != nil {
 		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch artifact"))
 		return
 	}
 
+	defer reader.Close()
+
 	logger.Info("Got artifact")
 
 	metrics.ArtifactsDownloaded.WithLabelValues(request.Request.Type.String()).Inc()
-	metrics.ArtifactsDownloadedBytes.WithLabelValues(request.Request.Type.String()).Add(float64(len(bytes)))
+	metrics.ArtifactsDownloadedBytes.WithLabelValues(request.Request.Type.String()).Add(float64(artifact.Size))
 
-	w.Header().Add("Content-Type", "application/zip")
-	w.Header().Add("Content-Length", strconv.Itoa(len(bytes)))
-	w.Header().Add("Content-Disposition", "attachment; filename=transcripts.zip")
+	w.Header().Add("Content-Type", artifact.Format.ContentType())
+	w.Header().Add("Content-Length", strconv.FormatInt(artifact.Size, 10))
+	w.Header().Add("Content-Disposition", "attachment; filename="+artifactFileName(request.Request.Type, *artifact, len(request.Artifacts)))
 	w.WriteHeader(http.StatusOK)
-	w.Write(bytes)
+
+	// The status has already been sent, so the response can only be cut short, which the client sees as a failed
+	// download
+	if _, err := io.Copy(w, reader); err != nil {
+		logger.Error("Failed to send artifact", "error", err)
+	}
+}
+
+func artifactFileName(requestType model.RequestType, artifact model.Artifact, partCount int) string {
+	name := requestType.ArtifactName()
+	if partCount == 1 {
+		return name + artifact.Format.Extension()
+	}
+
+	return fmt.Sprintf("%s-part%d-of%d%s", name, artifact.Part, partCount, artifact.Format.Extension())
 }
@@ -13,13 +13,13 @@ import (
 )
 
 type CreateRequestBody struct {
-	RequestType model.RequestType `json:"request_type"`
-	GuildId     *uint64           `json:"guild_id,string"`
+	RequestType model.RequestType    `json:"request_type"`
+	GuildId     *uint64              `json:"guild_id,string"`
+	Options     model.RequestOptions `json:"options"`
 }
 
 func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 	userId := a.userId(r.Context())
-	ownedGuilds := a.ownedGuilds(r.Context())
 
 	var body CreateRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -27,6 +27,11 @@ func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := body.Options.Validate(body.RequestType); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusBadRequest, err.Error()))
+		return
+	}
+
 	var pastRequests []model.RequestWithArtifact
 	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
 		pastRequests, err = tx.Requests().ListForUser(ctx, userId)
@@ -41,7 +46,7 @@ func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		if request.Request.GuildId != nil && *request.Request.GuildId != *body.GuildId {
+		if request.Request.GuildId != nil && (body.GuildId == nil || *request.Request.GuildId != *body.GuildId) {
 			continue
 		}
 
@@ -52,7 +57,14 @@ func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if request.Request.Status == model.RequestStatusFailed {
+		if request.Request.Status == model.RequestStatusAwaitingConfirmation {
+			a.RespondJson(w, http.StatusBadRequest, utils.Map{
+				"error": "You already have a request awaiting confirmation for this server",
+			})
+			return
+		}
+
+		if request.Request.Status == model.RequestStatusFailed || request.Request.Status == model.RequestStatusCancelled {
 			continue
 		}
 
@@ -71,8 +83,11 @@ func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if !utils.Contains(ownedGuilds, *body.GuildId) {
-			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusForbidden, "User does not own this guild"))
+		if ok, err := a.hasGuildAccess(r.Context(), body.RequestType, body.GuildId); err != nil {
+			a.HandleError(r.Context(), w, err)
+			return
+		} else if !ok {
+			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusForbidden, "You do not have permission to make this request for this guild"))
 			return
 		}
 
@@ -81,14 +96,18 @@ func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 			Type:    body.RequestType,
 			GuildId: body.GuildId,
 		}
-	} else if body.RequestType == model.RequestTypeGuildData {
+	} else if body.RequestType == model.RequestTypeGuildData || body.RequestType == model.RequestTypeGuildFull ||
+		body.RequestType == model.RequestTypeGuildErasure {
 		if body.GuildId == nil {
 			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusBadRequest, "Guild ID required for this request type"))
 			return
 		}
 
-		if !utils.Contains(ownedGuilds, *body.GuildId) {
-			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusForbidden, "User does not own this guild"))
+		if ok, err := a.hasGuildAccess(r.Context(), body.RequestType, body.GuildId); err != nil {
+			a.HandleError(r.Context(), w, err)
+			return
+		} else if !ok {
+			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusForbidden, "You do not have permission to make this request for this guild"))
 			return
 		}
 
@@ -97,19 +116,38 @@ func (a *API) CreateRequest(w http.ResponseWriter, r *http.Request) {
 			Type:    body.RequestType,
 			GuildId: body.GuildId,
 		}
+	} else if body.RequestType == model.RequestTypeUserData || body.RequestType == model.RequestTypeUserErasure {
+		// Users can always request their own data, so there is no guild to check ownership of
+		if !a.keyAllowsGuild(r.Context(), nil) {
+			a.HandleError(r.Context(), w, api.NewError(nil, http.StatusForbidden, "This API key is limited to specific servers"))
+			return
+		}
+
+		request = model.Request{
+			UserId: userId,
+			Type:   body.RequestType,
+		}
 	} else {
 		a.HandleError(r.Context(), w, api.NewError(nil, http.StatusBadRequest, "Invalid request type"))
 		return
 	}
 
+	// Erasures are destructive, so they are only queued once the user confirms them
+	status := model.RequestStatusQueued
+	if request.Type.IsErasure() {
+		status = model.RequestStatusAwaitingConfirmation
+	}
+
 	err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) error {
-		tmp, err := tx.Requests().Create(ctx, userId, request.Type, request.GuildId)
+		tmp, err := tx.Requests().Create(ctx, userId, request.Type, request.GuildId, status, body.Options)
 		if err != nil {
 			return err
 		}
 
-		if _, err := tx.Tasks().Create(ctx, tmp.Id); err != nil {
-			return err
+		if status == model.RequestStatusQueued {
+			if _, err := tx.Tasks().Create(ctx, tmp.Id, time.Now()); err != nil {
+				return err
+			}
 		}
 
 		request = tmp
@@ -0,0 +1,190 @@
+package requests
+
+import (
+	"context"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+type ConfirmRequestResponse struct {
+	model.Request
+	RunAfter time.Time `json:"run_after"`
+}
+
+func (a *API) ConfirmRequest(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+
+	request, ok := a.getErasureRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if request.Status != model.RequestStatusAwaitingConfirmation {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "This request is not awaiting confirmation",
+		})
+		return
+	}
+
+	// Permissions may have changed since the request was created
+	if request.Type.GuildScoped() {
+		if ok, err := a.hasGuildAccess(r.Context(), request.Type, request.GuildId); err != nil {
+			a.HandleError(r.Context(), w, err)
+			return
+		} else if !ok {
+			a.RespondJson(w, http.StatusForbidden, utils.Map{
+				"error": "You no longer have permission to make this request for this guild",
+			})
+			return
+		}
+	}
+
+	runAfter := time.Now().Add(a.Config.Erasure.GracePeriod)
+	var confirmed bool
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		// The request may have been confirmed or cancelled concurrently
+		confirmed, err = tx.Requests().TransitionStatus(ctx, request.Id, model.RequestStatusAwaitingConfirmation,
+			model.RequestStatusQueued)
+		if err != nil || !confirmed {
+			return err
+		}
+
+		_, err = tx.Tasks().Create(ctx, request.Id, runAfter)
+		return err
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to confirm request"))
+		return
+	}
+
+	if !confirmed {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "This request is not awaiting confirmation",
+		})
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "Erasure request confirmed", "request_id", request.Id, "user_id", userId,
+		"run_after", runAfter)
+
+	request.Status = model.RequestStatusQueued
+	a.RespondJson(w, http.StatusOK, ConfirmRequestResponse{
+		Request:  request,
+		RunAfter: runAfter,
+	})
+}
+
+func (a *API) CancelRequest(w http.ResponseWriter, r *http.Request) {
+	userId := a.userId(r.Context())
+
+	request, ok := a.getErasureRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if request.Status != model.RequestStatusAwaitingConfirmation && request.Status != model.RequestStatusQueued {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "This request can no longer be cancelled",
+		})
+		return
+	}
+
+	var gracePeriodEnded, cancelled bool
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		if request.Status == model.RequestStatusQueued {
+			deleted, err := tx.Tasks().DeletePendingForRequest(ctx, request.Id)
+			if err != nil {
+				return err
+			}
+
+			// The worker may already be purging data
+			if deleted == 0 {
+				gracePeriodEnded = true
+				return tx.Rollback(ctx)
+			}
+		}
+
+		// The request may have been confirmed or cancelled concurrently
+		cancelled, err = tx.Requests().TransitionStatus(ctx, request.Id, request.Status, model.RequestStatusCancelled)
+		if err != nil {
+			return err
+		}
+
+		if !cancelled {
+			return tx.Rollback(ctx)
+		}
+
+		return nil
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to cancel request"))
+		return
+	}
+
+	if gracePeriodEnded {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "The grace period for this request has ended",
+		})
+		return
+	}
+
+	if !cancelled {
+		a.RespondJson(w, http.StatusConflict, utils.Map{
+			"error": "This request can no longer be cancelled",
+		})
+		return
+	}
+
+	a.Logger.InfoContext(r.Context(), "Erasure request cancelled", "request_id", request.Id, "user_id", userId)
+
+	request.Status = model.RequestStatusCancelled
+	a.RespondJson(w, http.StatusOK, request)
+}
+
+func (a *API) getErasureRequest(w http.ResponseWriter, r *http.Request) (model.Request, bool) {
+	userId := a.userId(r.Context())
+
+	requestId, err := uuid.Parse(chi.URLParam(r, "requestId"))
+	if err != nil {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Invalid request ID",
+		})
+		return model.Request{}, false
+	}
+
+	var request *model.RequestWithArtifact
+	if err := a.Repository.Tx(r.Context(), func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		request, err = tx.Requests().GetById(ctx, requestId)
+		return
+	}); err != nil {
+		a.HandleError(r.Context(), w, api.NewError(err, http.StatusInternalServerError, "Failed to fetch request"))
+		return model.Request{}, false
+	}
+
+	if request == nil {
+		a.RespondJson(w, http.StatusNotFound, utils.Map{
+			"error": "Request not found",
+		})
+		return model.Request{}, false
+	}
+
+	if request.Request.UserId != userId {
+		a.RespondJson(w, http.StatusForbidden, utils.Map{
+			"error": "You do not own this request",
+		})
+		return model.Request{}, false
+	}
+
+	if !request.Request.Type.IsErasure() {
+		a.RespondJson(w, http.StatusBadRequest, utils.Map{
+			"error": "Only erasure requests can be confirmed or cancelled",
+		})
+		return model.Request{}, false
+	}
+
+	return request.Request, true
+}
@@ -12,6 +12,14 @@ import (
 type ListRequestsDto struct {
 	model.Request
 	ArtifactExpiresAt *time.Time `json:"artifact_expires_at,omitempty"`
+	// ArtifactParts lists each part of the artifact, which are downloaded separately
+	ArtifactParts  []ArtifactPartDto    `json:"artifact_parts,omitempty"`
+	ArtifactFormat *model.ArchiveFormat `json:"artifact_format,omitempty"`
+}
+
+type ArtifactPartDto struct {
+	Part int   `json:"part"`
+	Size int64 `json:"size"`
 }
 
 func (a *API) ListRequests(w http.ResponseWriter, r *http.Request) {
@@ -26,17 +34,32 @@ func (a *API) ListRequests(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dto := make([]ListRequestsDto, len(requests))
-	for i, request := range requests {
+	dto := make([]ListRequestsDto, 0, len(requests))
+	for _, request := range requests {
+		// API keys limited to specific guilds only see requests for those guilds
+		if !a.keyAllowsGuild(r.Context(), request.Request.GuildId) {
+			continue
+		}
+
 		var artifactExpiresAt *time.Time
-		if request.Artifact != nil {
-			artifactExpiresAt = &request.Artifact.ExpiresAt
+		var artifactParts []ArtifactPartDto
+		var artifactFormat *model.ArchiveFormat
+		for _, artifact := range request.Artifacts {
+			// Every part of an artifact expires at the same time, and is in the same format
+			artifactExpiresAt = &artifact.ExpiresAt
+			artifactFormat = &artifact.Format
+			artifactParts = append(artifactParts, ArtifactPartDto{
+				Part: artifact.Part,
+				Size: artifact.Size,
+			})
 		}
 
-		dto[i] = ListRequestsDto{
+		dto = append(dto, ListRequestsDto{
 			Request:           request.Request,
 			ArtifactExpiresAt: artifactExpiresAt,
-		}
+			ArtifactParts:     artifactParts,
+			ArtifactFormat:    artifactFormat,
+		})
 	}
 
 	a.RespondJson(w, http.StatusOK, dto)
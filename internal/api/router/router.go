@@ -3,13 +3,17 @@ package router
 import (
 	"crypto/ed25519"
 	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/api/admin"
+	"github.com/TicketsBot/export/internal/api/apikeys"
 	"github.com/TicketsBot/export/internal/api/auth"
 	"github.com/TicketsBot/export/internal/api/health"
 	"github.com/TicketsBot/export/internal/api/keys"
 	"github.com/TicketsBot/export/internal/api/middleware"
 	"github.com/TicketsBot/export/internal/api/requests"
+	"github.com/TicketsBot/export/internal/api/schemas"
 	"github.com/TicketsBot/export/internal/artifactstore"
 	"github.com/TicketsBot/export/internal/config"
+	"github.com/TicketsBot/export/internal/model"
 	"github.com/TicketsBot/export/internal/repository"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -37,6 +41,7 @@ func New(
 		AllowedOrigins:   config.Server.AllowedOrigins,
 		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete, http.MethodOptions},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"Content-Disposition"}, // So that the dashboard can name downloaded artifacts
 		AllowCredentials: false,
 	}))
 
@@ -49,6 +54,9 @@ func New(
 
 		r.Post("/auth/exchange", api.Exchange)
 		//r.Post("/auth/guilds", api.FetchGuilds)
+
+		r.With(middleware.Authenticate(core), middleware.RequireSession(core)).Post("/auth/logout", api.Logout)
+		r.With(middleware.Authenticate(core), middleware.RequireSession(core)).Post("/auth/logout-all", api.LogoutAll)
 	})
 
 	// /requests
@@ -57,10 +65,52 @@ func New(
 
 		r.Use(middleware.Authenticate(core))
 
-		r.Get("/requests", api.ListRequests)
-		r.Post("/requests", api.CreateRequest)
+		r.With(middleware.RequireScope(core, model.ApiKeyScopeRequestsList)).Get("/requests", api.ListRequests)
+		r.With(middleware.RequireScope(core, model.ApiKeyScopeRequestsCreate)).Post("/requests", api.CreateRequest)
+
+		r.With(middleware.RequireScope(core, model.ApiKeyScopeArtifactsDownload)).Get("/requests/{requestId}/artifact", api.GetArtifact)
+		r.With(middleware.RequireScope(core, model.ApiKeyScopeArtifactsDownload)).Get("/requests/{requestId}/artifact/parts/{part}", api.GetArtifact)
 
-		r.Get("/requests/{requestId}/artifact", api.GetArtifact)
+		// Erasures must be confirmed interactively
+		r.With(middleware.RequireSession(core)).Post("/requests/{requestId}/confirm", api.ConfirmRequest)
+		r.With(middleware.RequireSession(core)).Post("/requests/{requestId}/cancel", api.CancelRequest)
+	})
+
+	// /api-keys
+	r.Group(func(r chi.Router) {
+		api := apikeys.NewAPI(core)
+
+		// API keys can't be used to create more API keys
+		r.Use(middleware.Authenticate(core))
+		r.Use(middleware.RequireSession(core))
+
+		r.Get("/api-keys", api.ListKeys)
+		r.Post("/api-keys", api.CreateKey)
+		r.Delete("/api-keys/{keyId}", api.RevokeKey)
+	})
+
+	// /admin
+	r.Group(func(r chi.Router) {
+		api := admin.NewAPI(core)
+
+		r.Use(middleware.Authenticate(core))
+		r.Use(middleware.RequireSession(core))
+		r.Use(middleware.RequireAdmin(core))
+
+		r.Get("/admin/sessions/revoked", api.ListRevokedSessions)
+		r.Post("/admin/sessions/{sessionId}/revoke", api.RevokeSession)
+		r.Get("/admin/users/{userId}/sessions", api.ListUserSessions)
+		r.Post("/admin/users/{userId}/sessions/revoke", api.RevokeUserSessions)
+
+		r.Get("/admin/requests", api.ListRequests)
+		r.Get("/admin/requests/failed", api.ListFailedRequests)
+		r.Post("/admin/requests/{requestId}/requeue", api.RequeueRequest)
+		r.Post("/admin/requests/{requestId}/cancel", api.CancelRequest)
+		r.Post("/admin/requests/{requestId}/artifact/expire", api.ExpireArtifact)
+
+		r.Get("/admin/tasks", api.ListTasks)
+
+		r.Get("/admin/downloads", api.ListDownloadTotals)
 	})
 
 	// /keys
@@ -70,5 +120,13 @@ func New(
 		r.Get("/keys/signing", api.SigningKey)
 	})
 
+	// /schemas
+	r.Group(func(r chi.Router) {
+		api := schemas.NewAPI(core)
+
+		r.Get("/schemas", api.ListSchemas)
+		r.Get("/schemas/{name}.json", api.GetSchema)
+	})
+
 	return r
 }
@@ -0,0 +1,34 @@
+package schemas
+
+import (
+	"encoding/json"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/pkg/dto"
+)
+
+type API struct {
+	*api.Core
+
+	// Schemas only change with the binary, so are generated and encoded once
+	schemas map[string][]byte
+}
+
+func NewAPI(core *api.Core) *API {
+	schemas := make(map[string][]byte)
+	for _, name := range dto.SchemaNames() {
+		schema, _ := dto.GenerateSchema(name)
+
+		encoded, err := json.Marshal(schema)
+		if err != nil {
+			// Schemas are built from fixed types, so this can only happen if a DTO cannot be represented
+			panic(err)
+		}
+
+		schemas[name] = encoded
+	}
+
+	return &API{
+		Core:    core,
+		schemas: schemas,
+	}
+}
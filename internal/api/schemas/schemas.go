@@ -0,0 +1,42 @@
+package schemas
+
+import (
+	"fmt"
+	"github.com/TicketsBot/export/internal/api"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type SchemaDto struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func (a *API) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas := make([]SchemaDto, 0, len(a.schemas))
+	for _, name := range dto.SchemaNames() {
+		schemas = append(schemas, SchemaDto{
+			Name: name,
+			Path: fmt.Sprintf("/schemas/%s.json", name),
+		})
+	}
+
+	a.RespondJson(w, http.StatusOK, utils.Map{
+		"format_version": dto.FormatVersionLatest,
+		"schemas":        schemas,
+	})
+}
+
+func (a *API) GetSchema(w http.ResponseWriter, r *http.Request) {
+	schema, ok := a.schemas[chi.URLParam(r, "name")]
+	if !ok {
+		a.HandleError(r.Context(), w, api.NewError(nil, http.StatusNotFound, "Schema not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(schema)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"github.com/TicketsBot/common/encryption"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 	"io"
@@ -30,7 +29,7 @@ func NewS3ArtifactStore(logger *slog.Logger, client *s3.Client, bucketName strin
 	}
 }
 
-func (s *S3ArtifactStore) Fetch(ctx context.Context, requestId uuid.UUID, key string) ([]byte, error) {
+func (s *S3ArtifactStore) Fetch(ctx context.Context, requestId uuid.UUID, key string) (io.ReadCloser, error) {
 	objectKey := fmt.Sprintf("%s/%s", requestId, key)
 
 	opts := &s3.GetObjectInput{
@@ -43,22 +42,18 @@ func (s *S3ArtifactStore) Fetch(ctx context.Context, requestId uuid.UUID, key st
 		return nil, err
 	}
 
-	data, err := io.ReadAll(obj.Body)
+	reader, err := newDecryptReader(s.encryptionKey, obj.Body)
 	if err != nil {
+		obj.Body.Close()
 		return nil, err
 	}
 
-	decrypted, err := encryption.Decrypt(s.encryptionKey, data)
-	if err != nil {
-		return nil, err
-	}
-
-	return decrypted, nil
+	return reader, nil
 }
 
 func (s *S3ArtifactStore) Store(ctx context.Context, requestId uuid.UUID, key string, expiresAt time.Time, data []byte) error {
 	// Encrypt data first
-	encrypted, err := encryption.Encrypt(s.encryptionKey, data)
+	encrypted, err := encryptStream(s.encryptionKey, data)
 	if err != nil {
 		return err
 	}
@@ -80,3 +75,17 @@ func (s *S3ArtifactStore) Store(ctx context.Context, requestId uuid.UUID, key st
 	_, err = s.client.PutObject(ctx, opts)
 	return err
 }
+
+func (s *S3ArtifactStore) Delete(ctx context.Context, requestId uuid.UUID, key string) error {
+	objectKey := fmt.Sprintf("%s/%s", requestId, key)
+
+	opts := &s3.DeleteObjectInput{
+		Bucket: &s.bucketName,
+		Key:    &objectKey,
+	}
+
+	s.logger.Info("Deleting artifact", slog.String("request_id", requestId.String()))
+
+	_, err := s.client.DeleteObject(ctx, opts)
+	return err
+}
@@ -3,10 +3,14 @@ package artifactstore
 import (
 	"context"
 	"github.com/google/uuid"
+	"io"
 	"time"
 )
 
 type ArtifactStore interface {
-	Fetch(ctx context.Context, requestId uuid.UUID, key string) ([]byte, error)
+	// Fetch returns a reader that decrypts the artifact as it is read, so that it doesn't need to be held in memory. The
+	// reader must be closed.
+	Fetch(ctx context.Context, requestId uuid.UUID, key string) (io.ReadCloser, error)
 	Store(ctx context.Context, requestId uuid.UUID, key string, expiresAt time.Time, data []byte) error
+	Delete(ctx context.Context, requestId uuid.UUID, key string) error
 }
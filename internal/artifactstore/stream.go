@@ -0,0 +1,174 @@
+package artifactstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"github.com/TicketsBot/common/encryption"
+	"io"
+)
+
+// Artifacts are encrypted in chunks, so that they can be decrypted as they are downloaded, rather than being held in
+// memory while the whole artifact is authenticated. Each chunk is sealed with AES-GCM, using a nonce made up of a
+// random prefix, the chunk's index and whether it is the last chunk, so that chunks can't be reordered, and the
+// artifact can't be truncated without it being noticed.
+//
+// An encrypted artifact is streamMagic, followed by the nonce prefix, followed by the chunks. Every chunk holds
+// chunkSize bytes, except the last, which holds fewer, and may be empty.
+//
+// Artifacts stored before chunking was added were sealed in one go by the common encryption package, and start with a
+// random nonce rather than streamMagic, so are still read, although they are held in memory.
+const (
+	chunkSize       = 1024 * 1024
+	noncePrefixSize = 7
+)
+
+var streamMagic = []byte("TBEXSTR1")
+
+var errTruncated = errors.New("encrypted artifact is truncated")
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+
+	if last {
+		return append(nonce, 1)
+	}
+
+	return append(nonce, 0)
+}
+
+func encryptStream(key, data []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	chunks := len(data)/chunkSize + 1
+	encrypted := make([]byte, 0, len(streamMagic)+noncePrefixSize+len(data)+chunks*gcm.Overhead())
+	encrypted = append(encrypted, streamMagic...)
+	encrypted = append(encrypted, prefix...)
+
+	for i := 0; i < chunks; i++ {
+		chunk := data[i*chunkSize : min((i+1)*chunkSize, len(data))]
+		encrypted = gcm.Seal(encrypted, chunkNonce(prefix, uint32(i), i == chunks-1), chunk, nil)
+	}
+
+	return encrypted, nil
+}
+
+// decryptReader decrypts an artifact one chunk at a time as it is read. A chunk is only returned once it has been
+// authenticated, so an error is returned before any data from a modified chunk.
+type decryptReader struct {
+	body   io.ReadCloser
+	gcm    cipher.AEAD
+	prefix []byte
+
+	index     uint32
+	chunk     []byte // The encrypted chunk being read
+	plaintext []byte // The unread part of the current decrypted chunk
+	done      bool
+}
+
+func newDecryptReader(key []byte, body io.ReadCloser) (io.ReadCloser, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(streamMagic)+noncePrefixSize)
+	n, err := io.ReadFull(body, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	if n < len(header) || !bytes.Equal(header[:len(streamMagic)], streamMagic) {
+		return decryptLegacy(key, io.MultiReader(bytes.NewReader(header[:n]), body), body)
+	}
+
+	return &decryptReader{
+		body:   body,
+		gcm:    gcm,
+		prefix: header[len(streamMagic):],
+		chunk:  make([]byte, chunkSize+gcm.Overhead()),
+	}, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+// next reads and decrypts the next chunk. Every chunk but the last is full, so a chunk is known to be the last when
+// it is cut short by the end of the artifact.
+func (r *decryptReader) next() error {
+	n, err := io.ReadFull(r.body, r.chunk)
+	last := errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+	if err != nil && !last {
+		return err
+	}
+
+	if n < r.gcm.Overhead() {
+		return errTruncated
+	}
+
+	plaintext, err := r.gcm.Open(r.chunk[:0], chunkNonce(r.prefix, r.index, last), r.chunk[:n], nil)
+	if err != nil {
+		return err
+	}
+
+	r.index++
+	r.plaintext = plaintext
+	r.done = last
+	return nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.body.Close()
+}
+
+// decryptLegacy reads and decrypts an artifact stored before chunking was added.
+func decryptLegacy(key []byte, r io.Reader, body io.Closer) (io.ReadCloser, error) {
+	defer body.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := encryption.Decrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(decrypted)), nil
+}
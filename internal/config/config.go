@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/TicketsBot/export/internal/model"
 	"github.com/caarlos0/env/v11"
 	"time"
 )
@@ -21,6 +22,9 @@ type (
 			ClientId     string `env:"CLIENT_ID,required"`
 			ClientSecret string `env:"CLIENT_SECRET,required"`
 			RedirectUri  string `env:"REDIRECT_URI,required"`
+
+			// TokenEncryptionKey encrypts the OAuth tokens stored for re-checking guild access
+			TokenEncryptionKey string `env:"TOKEN_ENCRYPTION_KEY,required"`
 		} `envPrefix:"DISCORD_"`
 
 		Jwt struct {
@@ -33,6 +37,30 @@ type (
 			GlobalDailyDownloadGigabytes int64 `env:"GLOBAL_DAILY_DOWNLOAD_GIGABYTES" envDefault:"1000"`
 			UserDailyDownloadGigabytes   int64 `env:"USER_DAILY_DOWNLOAD_GIGABYTES" envDefault:"10"`
 		} `envPrefix:"LIMIT_"`
+
+		Erasure struct {
+			GracePeriod time.Duration `env:"GRACE_PERIOD" envDefault:"72h"`
+		} `envPrefix:"ERASURE_"`
+
+		// Access is the minimum access level (owner, admin or manager) required to make each type of guild request
+		Access struct {
+			GuildTranscripts model.AccessLevel `env:"GUILD_TRANSCRIPTS" envDefault:"owner"`
+			GuildData        model.AccessLevel `env:"GUILD_DATA" envDefault:"owner"`
+			GuildFull        model.AccessLevel `env:"GUILD_FULL" envDefault:"owner"`
+			GuildErasure     model.AccessLevel `env:"GUILD_ERASURE" envDefault:"owner"`
+		} `envPrefix:"ACCESS_"`
+
+		// AdminUserIds are the Discord user IDs allowed to use the /admin routes
+		AdminUserIds []uint64 `env:"ADMIN_USER_IDS"`
+
+		ApiKeys struct {
+			MaxPerUser      int           `env:"MAX_PER_USER" envDefault:"10"`
+			RateLimit       int           `env:"RATE_LIMIT" envDefault:"60"`
+			RateLimitWindow time.Duration `env:"RATE_LIMIT_WINDOW" envDefault:"1h"`
+		} `envPrefix:"API_KEY_"`
+
+		// GuildAccessCacheTtl is how long a user's guild access, re-checked against Discord, is trusted for
+		GuildAccessCacheTtl time.Duration `env:"GUILD_ACCESS_CACHE_TTL" envDefault:"5m"`
 	}
 
 	WorkerConfig struct {
@@ -41,10 +69,15 @@ type (
 		KeyPath string `env:"KEY_PATH" envDefault:"./key.pem"`
 
 		Daemon struct {
-			Interval         time.Duration `env:"INTERVAL" envDefault:"5s"`
-			DownloadWorkers  int           `env:"DOWNLOAD_WORKERS" envDefault:"250"`
-			SigningWorkers   int           `env:"SIGNING_WORKERS" envDefault:"100"`
-			CompressionLevel int           `env:"COMPRESSION_LEVEL" envDefault:"9"`
+			Interval            time.Duration `env:"INTERVAL" envDefault:"5s"`
+			DownloadWorkers     int           `env:"DOWNLOAD_WORKERS" envDefault:"250"`
+			SigningWorkers      int           `env:"SIGNING_WORKERS" envDefault:"100"`
+			SnapshotConnections int           `env:"SNAPSHOT_CONNECTIONS" envDefault:"4"`
+			FetchTimeout        time.Duration `env:"FETCH_TIMEOUT" envDefault:"5m"`
+			CompressionLevel    int           `env:"COMPRESSION_LEVEL" envDefault:"9"`
+
+			// MaxPartMegabytes is the size that exports are split into parts of, each downloaded separately
+			MaxPartMegabytes int64 `env:"MAX_PART_MEGABYTES" envDefault:"1024"`
 		} `envPrefix:"DAEMON_"`
 
 		TranscriptS3 struct {
@@ -66,6 +99,10 @@ type (
 
 	DatabaseConfig struct {
 		Uri string `env:"URI,required"`
+
+		// AutoMigrate applies pending migrations at startup. Otherwise, startup fails until they are applied with
+		// exportctl migrate.
+		AutoMigrate bool `env:"AUTO_MIGRATE" envDefault:"false"`
 	}
 
 	S3Config struct {
@@ -81,6 +118,27 @@ type (
 	}
 )
 
+// RequiredAccessLevel returns the minimum access level a user must have over a guild to make, or download the result
+// of, a request of the given type.
+func (c ApiConfig) RequiredAccessLevel(requestType model.RequestType) model.AccessLevel {
+	switch requestType {
+	case model.RequestTypeGuildTranscripts:
+		return c.Access.GuildTranscripts
+	case model.RequestTypeGuildData:
+		return c.Access.GuildData
+	case model.RequestTypeGuildFull:
+		return c.Access.GuildFull
+	case model.RequestTypeGuildErasure:
+		return c.Access.GuildErasure
+	default:
+		if requestType.GuildScoped() {
+			return model.AccessLevelOwner
+		}
+
+		return model.AccessLevelNone
+	}
+}
+
 func New[T any]() (cfg T, err error) {
 	err = env.Parse(&cfg)
 	return
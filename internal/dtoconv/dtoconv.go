@@ -0,0 +1,201 @@
+// Package dtoconv converts between the TicketsBot database types and the export DTOs that mirror them. Types with
+// identical fields are converted directly, so a field added to or changed in the database package becomes a compile
+// error here, rather than a silent change to the export format.
+package dtoconv
+
+import (
+	"github.com/TicketsBot/database"
+	"github.com/TicketsBot/export/pkg/dto"
+)
+
+func AutoCloseSettingsToDto(settings database.AutoCloseSettings) dto.AutoCloseSettings {
+	return dto.AutoCloseSettings(settings)
+}
+
+func AutoCloseSettingsToDatabase(settings dto.AutoCloseSettings) database.AutoCloseSettings {
+	return database.AutoCloseSettings(settings)
+}
+
+func ClaimSettingsToDto(settings database.ClaimSettings) dto.ClaimSettings {
+	return dto.ClaimSettings(settings)
+}
+
+func ClaimSettingsToDatabase(settings dto.ClaimSettings) database.ClaimSettings {
+	return database.ClaimSettings(settings)
+}
+
+func CustomEmbedToDatabase(embed dto.CustomEmbed) database.CustomEmbed {
+	return database.CustomEmbed(embed)
+}
+
+func EmbedFieldsToDatabase(fields []dto.EmbedField) []database.EmbedField {
+	return convertSlice(fields, func(field dto.EmbedField) database.EmbedField {
+		return database.EmbedField(field)
+	})
+}
+
+func FormInputToDatabase(input dto.FormInput) database.FormInput {
+	return database.FormInput(input)
+}
+
+func GuildMetadataToDto(metadata database.GuildMetadata) dto.GuildMetadata {
+	return dto.GuildMetadata(metadata)
+}
+
+func MultiPanelsToDto(multiPanels []database.MultiPanel) []dto.MultiPanel {
+	return convertSlice(multiPanels, func(multiPanel database.MultiPanel) dto.MultiPanel {
+		return dto.MultiPanel{
+			Id:                    multiPanel.Id,
+			MessageId:             multiPanel.MessageId,
+			ChannelId:             multiPanel.ChannelId,
+			GuildId:               multiPanel.GuildId,
+			SelectMenu:            multiPanel.SelectMenu,
+			SelectMenuPlaceholder: multiPanel.SelectMenuPlaceholder,
+			Embed:                 embedWithFieldsToDto(multiPanel.Embed),
+		}
+	})
+}
+
+func MultiPanelToDatabase(multiPanel dto.MultiPanel) database.MultiPanel {
+	return database.MultiPanel{
+		Id:                    multiPanel.Id,
+		MessageId:             multiPanel.MessageId,
+		ChannelId:             multiPanel.ChannelId,
+		GuildId:               multiPanel.GuildId,
+		SelectMenu:            multiPanel.SelectMenu,
+		SelectMenuPlaceholder: multiPanel.SelectMenuPlaceholder,
+		Embed:                 embedWithFieldsToDatabase(multiPanel.Embed),
+	}
+}
+
+func NamingSchemeToDto(namingScheme database.NamingScheme) dto.NamingScheme {
+	return dto.NamingScheme(namingScheme)
+}
+
+func NamingSchemeToDatabase(namingScheme dto.NamingScheme) database.NamingScheme {
+	return database.NamingScheme(namingScheme)
+}
+
+func PanelAccessControlRulesToDto(rules map[int][]database.PanelAccessControlRule) map[int][]dto.PanelAccessControlRule {
+	converted := make(map[int][]dto.PanelAccessControlRule, len(rules))
+	for panelId, panelRules := range rules {
+		converted[panelId] = convertSlice(panelRules, func(rule database.PanelAccessControlRule) dto.PanelAccessControlRule {
+			return dto.PanelAccessControlRule{
+				RoleId: rule.RoleId,
+				Action: string(rule.Action),
+			}
+		})
+	}
+
+	return converted
+}
+
+func PanelAccessControlRulesToDatabase(rules []dto.PanelAccessControlRule) []database.PanelAccessControlRule {
+	return convertSlice(rules, func(rule dto.PanelAccessControlRule) database.PanelAccessControlRule {
+		return database.PanelAccessControlRule{
+			RoleId: rule.RoleId,
+			Action: database.AccessControlAction(rule.Action),
+		}
+	})
+}
+
+func PanelsToDto(panels []database.Panel) []dto.Panel {
+	return convertSlice(panels, func(panel database.Panel) dto.Panel {
+		return dto.Panel(panel)
+	})
+}
+
+func PanelToDatabase(panel dto.Panel) database.Panel {
+	return database.Panel(panel)
+}
+
+func SettingsToDto(settings database.Settings) dto.Settings {
+	return dto.Settings(settings)
+}
+
+func SettingsToDatabase(settings dto.Settings) database.Settings {
+	return database.Settings(settings)
+}
+
+func SupportTeamsToDto(teams []database.SupportTeam) []dto.SupportTeam {
+	return convertSlice(teams, func(team database.SupportTeam) dto.SupportTeam {
+		return dto.SupportTeam(team)
+	})
+}
+
+func TagToDto(tag database.Tag) dto.Tag {
+	return dto.Tag{
+		Id:                   tag.Id,
+		GuildId:              tag.GuildId,
+		Content:              tag.Content,
+		Embed:                embedWithFieldsToDto(tag.Embed),
+		ApplicationCommandId: tag.ApplicationCommandId,
+	}
+}
+
+func TagToDatabase(tag dto.Tag) database.Tag {
+	return database.Tag{
+		Id:                   tag.Id,
+		GuildId:              tag.GuildId,
+		Content:              tag.Content,
+		Embed:                embedWithFieldsToDatabase(tag.Embed),
+		ApplicationCommandId: tag.ApplicationCommandId,
+	}
+}
+
+func TicketPermissionsToDto(permissions database.TicketPermissions) dto.TicketPermissions {
+	return dto.TicketPermissions(permissions)
+}
+
+func TicketPermissionsToDatabase(permissions dto.TicketPermissions) database.TicketPermissions {
+	return database.TicketPermissions(permissions)
+}
+
+func embedWithFieldsToDto(embed *database.CustomEmbedWithFields) *dto.CustomEmbedWithFields {
+	if embed == nil {
+		return nil
+	}
+
+	converted := &dto.CustomEmbedWithFields{
+		Fields: convertSlice(embed.Fields, func(field database.EmbedField) dto.EmbedField {
+			return dto.EmbedField(field)
+		}),
+	}
+
+	if embed.CustomEmbed != nil {
+		customEmbed := dto.CustomEmbed(*embed.CustomEmbed)
+		converted.CustomEmbed = &customEmbed
+	}
+
+	return converted
+}
+
+func embedWithFieldsToDatabase(embed *dto.CustomEmbedWithFields) *database.CustomEmbedWithFields {
+	if embed == nil {
+		return nil
+	}
+
+	converted := &database.CustomEmbedWithFields{
+		Fields: EmbedFieldsToDatabase(embed.Fields),
+	}
+
+	if embed.CustomEmbed != nil {
+		customEmbed := CustomEmbedToDatabase(*embed.CustomEmbed)
+		converted.CustomEmbed = &customEmbed
+	}
+
+	return converted
+}
+
+func convertSlice[T, U any](values []T, f func(T) U) []U {
+	if values == nil {
+		return nil
+	}
+
+	converted := make([]U, len(values))
+	for i, value := range values {
+		converted[i] = f(value)
+	}
+
+	return converted
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"github.com/TicketsBot/database"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/jackc/pgx/v4"
+	"log/slog"
+	"time"
+)
+
+type Importer struct {
+	logger   *slog.Logger
+	database *database.Database
+	options  Options
+}
+
+type Options struct {
+	// DryRun checks for conflicts and counts what would be created, without writing anything. The import is run as
+	// normal, but its transaction is rolled back rather than committed.
+	DryRun bool
+}
+
+// errDryRun is returned from the import transaction to roll it back in dry-run mode.
+var errDryRun = errors.New("dry run")
+
+// ErrPseudonymised is returned for exports whose user IDs were pseudonymised. The pseudonyms aren't real snowflakes,
+// and could even match a real user's ID, so importing them as permissions or blacklist entries would apply them to the
+// wrong users.
+var ErrPseudonymised = errors.New("export is pseudonymised, so can't be imported")
+
+type importStep struct {
+	Name string
+	F    func(ctx context.Context, state *importState) error
+}
+
+// importState is shared between steps, which run sequentially as later steps depend on the IDs assigned by earlier
+// ones (e.g. panels reference forms, embeds and support teams).
+type importState struct {
+	// tx is the transaction the whole import runs in, so that a failed import leaves the target guild untouched.
+	tx pgx.Tx
+
+	guildId uint64
+	data    *dto.GuildData
+	report  *Report
+
+	// sections holds the sections of data.json included in the export, or is nil if the export includes every section.
+	sections map[model.GuildDataSection]bool
+
+	// ids holds the mappings used to resolve references.
+	ids IdMappings
+}
+
+// included returns whether the export includes the given section of data.json.
+func (s *importState) included(section model.GuildDataSection) bool {
+	return s.sections == nil || s.sections[section]
+}
+
+func NewImporter(logger *slog.Logger, database *database.Database, options Options) *Importer {
+	return &Importer{
+		logger:   logger,
+		database: database,
+		options:  options,
+	}
+}
+
+// Import restores the contents of a guild data export into the guild with ID guildId, which may differ from the
+// guild the export was taken from. Rows that would conflict with existing data, including settings that the target
+// guild has already configured, are skipped and recorded in the report. The import runs in a single transaction, so
+// if it fails, nothing is written.
+//
+// metadata is the export's metadata.json, or nil if the export was made before metadata was added. Pseudonymised
+// exports are refused with ErrPseudonymised. Sections the export didn't include are left untouched in the target guild,
+// rather than being set to the zero values they hold in data.json.
+func (i *Importer) Import(ctx context.Context, data *dto.GuildData, metadata *dto.ExportMetadata, guildId uint64) (*Report, error) {
+	if metadata != nil && metadata.Redaction.Pseudonymised {
+		return nil, ErrPseudonymised
+	}
+
+	state := &importState{
+		guildId: guildId,
+		data:    data,
+		report:  newReport(data.GuildId, guildId, i.options.DryRun),
+		ids:     newIdMappings(),
+	}
+
+	if metadata != nil && len(metadata.Sections) > 0 {
+		state.sections = make(map[model.GuildDataSection]bool, len(metadata.Sections))
+		for _, section := range metadata.Sections {
+			state.sections[model.GuildDataSection(section)] = true
+		}
+	}
+
+	steps := []importStep{
+		{"Import Support Teams", i.importSupportTeams},
+		{"Import Forms", i.importForms},
+		{"Import Embeds", i.importEmbeds},
+		{"Import Panels", i.importPanels},
+		{"Import Multi Panels", i.importMultiPanels},
+		{"Import Tags", i.importTags},
+		{"Import Permissions", i.importPermissions},
+		{"Import Blacklists", i.importBlacklists},
+		{"Import Settings", i.importSettings},
+	}
+
+	err := i.database.WithTx(ctx, func(tx pgx.Tx) error {
+		state.tx = tx
+
+		for _, step := range steps {
+			now := time.Now()
+
+			i.logger.DebugContext(ctx, "Running step", "name", step.Name)
+			if err := step.F(ctx, state); err != nil {
+				i.logger.ErrorContext(ctx, "Failed to run step", "name", step.Name, "error", err, "elapsed", time.Since(now))
+				return err
+			}
+
+			i.logger.DebugContext(ctx, "Step completed", "name", step.Name, "elapsed", time.Since(now))
+		}
+
+		if i.options.DryRun {
+			return errDryRun
+		}
+
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !i.options.DryRun {
+		state.report.IdMappings = state.ids
+	}
+
+	return state.report, nil
+}
@@ -0,0 +1,63 @@
+package importer
+
+import "fmt"
+
+type Report struct {
+	SourceGuildId uint64         `json:"source_guild_id,string"`
+	TargetGuildId uint64         `json:"target_guild_id,string"`
+	DryRun        bool           `json:"dry_run"`
+	Created       map[string]int `json:"created"`
+	Conflicts     []Conflict     `json:"conflicts"`
+	IdMappings    IdMappings     `json:"id_mappings"`
+}
+
+type Conflict struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Reason  string `json:"reason"`
+}
+
+// IdMappings maps IDs from the export to the IDs assigned in the target database. In dry-run mode nothing is
+// inserted, so the mappings are left empty.
+type IdMappings struct {
+	Panels       map[int]int `json:"panels"`
+	MultiPanels  map[int]int `json:"multi_panels"`
+	Forms        map[int]int `json:"forms"`
+	FormInputs   map[int]int `json:"form_inputs"`
+	SupportTeams map[int]int `json:"support_teams"`
+	Embeds       map[int]int `json:"embeds"`
+}
+
+func newReport(sourceGuildId, targetGuildId uint64, dryRun bool) *Report {
+	return &Report{
+		SourceGuildId: sourceGuildId,
+		TargetGuildId: targetGuildId,
+		DryRun:        dryRun,
+		Created:       make(map[string]int),
+		Conflicts:     make([]Conflict, 0),
+		IdMappings:    newIdMappings(),
+	}
+}
+
+func newIdMappings() IdMappings {
+	return IdMappings{
+		Panels:       make(map[int]int),
+		MultiPanels:  make(map[int]int),
+		Forms:        make(map[int]int),
+		FormInputs:   make(map[int]int),
+		SupportTeams: make(map[int]int),
+		Embeds:       make(map[int]int),
+	}
+}
+
+func (r *Report) created(section string) {
+	r.Created[section]++
+}
+
+func (r *Report) conflict(section string, key any, reason string) {
+	r.Conflicts = append(r.Conflicts, Conflict{
+		Section: section,
+		Key:     fmt.Sprint(key),
+		Reason:  reason,
+	})
+}
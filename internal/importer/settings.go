@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"github.com/TicketsBot/export/internal/dtoconv"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/utils"
+	"math"
+)
+
+func (i *Importer) importPermissions(ctx context.Context, state *importState) error {
+	// Existing permissions are kept, rather than promoting or demoting staff already in the target guild
+	for _, permission := range state.data.UserPermissions {
+		if !permission.IsAdmin && !permission.IsSupport {
+			continue
+		}
+
+		res, err := state.tx.Exec(ctx, `
+INSERT INTO permissions("guild_id", "user_id", "support", "admin")
+VALUES($1, $2, true, $3)
+ON CONFLICT DO NOTHING;`,
+			state.guildId, permission.Snowflake, permission.IsAdmin)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			state.report.conflict("user_permissions", permission.Snowflake, "the user already has permissions in this guild, keeping existing permissions")
+			continue
+		}
+
+		state.report.created("user_permissions")
+	}
+
+	for _, permission := range state.data.RolePermissions {
+		if !permission.IsAdmin && !permission.IsSupport {
+			continue
+		}
+
+		res, err := state.tx.Exec(ctx, `
+INSERT INTO role_permissions("guild_id", "role_id", "support", "admin")
+VALUES($1, $2, true, $3)
+ON CONFLICT DO NOTHING;`,
+			state.guildId, permission.Snowflake, permission.IsAdmin)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			state.report.conflict("role_permissions", permission.Snowflake, "the role already has permissions, keeping existing permissions")
+			continue
+		}
+
+		state.report.created("role_permissions")
+	}
+
+	return nil
+}
+
+func (i *Importer) importBlacklists(ctx context.Context, state *importState) error {
+	for _, userId := range state.data.GuildBlacklistedUsers {
+		res, err := state.tx.Exec(ctx, `INSERT INTO blacklist("guild_id", "user_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, userId)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			state.report.conflict("guild_blacklisted_users", userId, "the user is already blacklisted")
+			continue
+		}
+
+		state.report.created("guild_blacklisted_users")
+	}
+
+	for _, roleId := range state.data.GuildBlacklistedRoles {
+		res, err := state.tx.Exec(ctx, `INSERT INTO role_blacklist("guild_id", "role_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, roleId)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			state.report.conflict("guild_blacklisted_roles", roleId, "the role is already blacklisted")
+			continue
+		}
+
+		state.report.created("guild_blacklisted_roles")
+	}
+
+	return nil
+}
+
+// importSettings sets the guild-wide settings of the target guild. Settings the target guild has already configured
+// are kept, and recorded as conflicts. It runs last, as the settings reference panels and forms.
+//
+// Settings that the export didn't include are zero values in data.json, rather than the source guild's settings, so
+// are skipped. Optional settings are nil if not included, so are skipped either way.
+func (i *Importer) importSettings(ctx context.Context, state *importState) error {
+	data := state.data
+
+	if state.included(model.GuildDataSectionSettings) {
+		if err := importGuildSettings(ctx, state); err != nil {
+			return err
+		}
+	}
+
+	if data.ActiveLanguage != nil {
+		if err := insertSetting(ctx, state, "active_language",
+			`INSERT INTO active_language("guild_id", "language") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, *data.ActiveLanguage,
+		); err != nil {
+			return err
+		}
+	}
+
+	if state.included(model.GuildDataSectionArchiveChannel) {
+		if err := insertSetting(ctx, state, "archive_channel",
+			`INSERT INTO archive_channel("guild_id", "channel_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, data.ArchiveChannel,
+		); err != nil {
+			return err
+		}
+	}
+
+	if data.AutocloseSettings != nil {
+		autoClose := dtoconv.AutoCloseSettingsToDatabase(*data.AutocloseSettings)
+		if err := insertSetting(ctx, state, "autoclose_settings", `
+INSERT INTO auto_close("guild_id", "enabled", "since_open_with_no_response", "since_last_message", "on_user_leave")
+VALUES($1, $2, $3, $4, $5)
+ON CONFLICT DO NOTHING;`,
+			state.guildId, autoClose.Enabled, autoClose.SinceOpenWithNoResponse, autoClose.SinceLastMessage,
+			autoClose.OnUserLeave,
+		); err != nil {
+			return err
+		}
+	}
+
+	if data.ChannelCategory != nil {
+		if err := insertSetting(ctx, state, "channel_category",
+			`INSERT INTO channel_category("guild_id", "category_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, *data.ChannelCategory,
+		); err != nil {
+			return err
+		}
+	}
+
+	if data.ClaimSettings != nil {
+		claimSettings := dtoconv.ClaimSettingsToDatabase(*data.ClaimSettings)
+		if err := insertSetting(ctx, state, "claim_settings",
+			`INSERT INTO claim_settings("guild_id", "support_can_view", "support_can_type") VALUES($1, $2, $3) ON CONFLICT DO NOTHING;`,
+			state.guildId, claimSettings.SupportCanView, claimSettings.SupportCanType,
+		); err != nil {
+			return err
+		}
+	}
+
+	if state.included(model.GuildDataSectionCloseConfirmationEnabled) {
+		if err := insertSetting(ctx, state, "close_confirmation_enabled",
+			`INSERT INTO close_confirmation("guild_id", "confirm") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, data.CloseConfirmationEnabled,
+		); err != nil {
+			return err
+		}
+	}
+
+	for colourId, colourCode := range data.CustomColors {
+		if err := insertSetting(ctx, state, fmt.Sprintf("custom_colors.%d", colourId),
+			`INSERT INTO custom_colours("guild_id", "colour_id", "colour_code") VALUES($1, $2, $3) ON CONFLICT DO NOTHING;`,
+			state.guildId, colourId, colourCode,
+		); err != nil {
+			return err
+		}
+	}
+
+	if state.included(model.GuildDataSectionFeedbackEnabled) {
+		if err := insertSetting(ctx, state, "feedback_enabled",
+			`INSERT INTO feedback_enabled("guild_id", "feedback_enabled") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, data.FeedbackEnabled,
+		); err != nil {
+			return err
+		}
+	}
+
+	if data.NamingScheme != nil {
+		if err := insertSetting(ctx, state, "naming_scheme",
+			`INSERT INTO naming_scheme("guild_id", "naming_scheme") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, dtoconv.NamingSchemeToDatabase(*data.NamingScheme),
+		); err != nil {
+			return err
+		}
+	}
+
+	if ticketLimit := data.TicketLimit; ticketLimit != nil {
+		if *ticketLimit < 0 || *ticketLimit > math.MaxUint8 {
+			state.report.conflict("settings", "ticket_limit", "ticket limit is out of range, skipping")
+		} else if err := insertSetting(ctx, state, "ticket_limit",
+			`INSERT INTO ticket_limit("guild_id", "limit") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, uint8(*ticketLimit),
+		); err != nil {
+			return err
+		}
+	}
+
+	if state.included(model.GuildDataSectionTicketPermissions) {
+		ticketPermissions := dtoconv.TicketPermissionsToDatabase(data.TicketPermissions)
+		if err := insertSetting(ctx, state, "ticket_permissions", `
+INSERT INTO ticket_permissions("guild_id", "attach_files", "embed_links", "add_reactions")
+VALUES($1, $2, $3, $4)
+ON CONFLICT DO NOTHING;`,
+			state.guildId, ticketPermissions.AttachFiles, ticketPermissions.EmbedLinks, ticketPermissions.AddReactions,
+		); err != nil {
+			return err
+		}
+	}
+
+	if state.included(model.GuildDataSectionUsersCanClose) {
+		if err := insertSetting(ctx, state, "users_can_close",
+			`INSERT INTO users_can_close("guild_id", "users_can_close") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, data.UsersCanClose,
+		); err != nil {
+			return err
+		}
+	}
+
+	if data.WelcomeMessage != nil {
+		if err := insertSetting(ctx, state, "welcome_message",
+			`INSERT INTO welcome_messages("guild_id", "welcome_message") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+			state.guildId, *data.WelcomeMessage,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importGuildSettings sets the target guild's row in the settings table.
+func importGuildSettings(ctx context.Context, state *importState) error {
+	settings := state.data.Settings
+	settings.ContextMenuPanel = remapOptional(state, "settings", "context_menu_panel", "panel", settings.ContextMenuPanel, state.ids.Panels)
+
+	if settings.ExitSurveyFormId != nil {
+		formId := int(*settings.ExitSurveyFormId)
+		if mapped := remapOptional(state, "settings", "exit_survey_form_id", "form", &formId, state.ids.Forms); mapped != nil {
+			settings.ExitSurveyFormId = utils.Ptr(uint64(*mapped))
+		} else {
+			settings.ExitSurveyFormId = nil
+		}
+	}
+
+	converted := dtoconv.SettingsToDatabase(settings)
+	return insertSetting(ctx, state, "settings", `
+INSERT INTO settings(
+	"guild_id",
+	"hide_claim_button",
+	"disable_open_command",
+	"context_menu_permission_level",
+	"context_menu_add_sender",
+	"context_menu_panel",
+	"store_transcripts",
+	"use_threads",
+	"ticket_notification_channel",
+	"thread_archive_duration",
+	"overflow_enabled",
+	"overflow_category_id",
+	"anonymise_dashboard_responses"
+)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+ON CONFLICT DO NOTHING;`,
+		state.guildId,
+		converted.HideClaimButton,
+		converted.DisableOpenCommand,
+		converted.ContextMenuPermissionLevel,
+		converted.ContextMenuAddSender,
+		converted.ContextMenuPanel,
+		converted.StoreTranscripts,
+		converted.UseThreads,
+		converted.TicketNotificationChannel,
+		converted.ThreadArchiveDuration,
+		converted.OverflowEnabled,
+		converted.OverflowCategoryId,
+		converted.AnonymiseDashboardResponses,
+	)
+}
+
+// insertSetting runs an insert that does nothing if the target guild already has the setting configured, recording a
+// conflict in that case. key names the setting as in the export.
+func insertSetting(ctx context.Context, state *importState, key string, query string, args ...any) error {
+	res, err := state.tx.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		state.report.conflict("settings", key, "the target guild has already configured this setting, keeping existing value")
+		return nil
+	}
+
+	state.report.created("settings")
+	return nil
+}
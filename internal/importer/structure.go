@@ -0,0 +1,307 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TicketsBot/export/internal/dtoconv"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/jackc/pgx/v4"
+	"sort"
+)
+
+// The tickets database package only offers transactional variants of a few of its queries, so the rest are repeated
+// here to run them in the import's transaction.
+
+func (i *Importer) importSupportTeams(ctx context.Context, state *importState) error {
+	for _, team := range state.data.SupportTeams {
+		var existingId int
+		err := state.tx.QueryRow(ctx, `SELECT "id" FROM support_team WHERE "guild_id" = $1 AND "name" = $2;`,
+			state.guildId, team.Name).Scan(&existingId)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		// Team names are unique per guild, so reuse the existing team rather than skipping any references to it
+		if err == nil {
+			state.report.conflict("support_teams", team.Name, "a team with this name already exists, using existing team")
+			state.ids.SupportTeams[team.Id] = existingId
+			continue
+		}
+
+		var teamId int
+		if err := state.tx.QueryRow(ctx,
+			`INSERT INTO support_team("guild_id", "name", "on_call_role_id") VALUES($1, $2, $3) RETURNING "id";`,
+			state.guildId, team.Name, team.OnCallRole,
+		).Scan(&teamId); err != nil {
+			return err
+		}
+
+		for _, userId := range state.data.SupportTeamUsers[team.Id] {
+			if _, err := state.tx.Exec(ctx,
+				`INSERT INTO support_team_members("team_id", "user_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+				teamId, userId,
+			); err != nil {
+				return err
+			}
+		}
+
+		for _, roleId := range state.data.SupportTeamRoles[team.Id] {
+			if _, err := state.tx.Exec(ctx,
+				`INSERT INTO support_team_roles("team_id", "role_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+				teamId, roleId,
+			); err != nil {
+				return err
+			}
+		}
+
+		state.ids.SupportTeams[team.Id] = teamId
+		state.report.created("support_teams")
+	}
+
+	return nil
+}
+
+func (i *Importer) importForms(ctx context.Context, state *importState) error {
+	inputs := make(map[int][]dto.FormInput)
+	for _, input := range state.data.FormInputs {
+		inputs[input.FormId] = append(inputs[input.FormId], input)
+	}
+
+	for _, form := range state.data.Forms {
+		// Custom IDs are unique across all guilds, so new ones are always generated. The source guild may still exist.
+		var formId int
+		if err := state.tx.QueryRow(ctx,
+			`INSERT INTO forms("guild_id", "title", "custom_id") VALUES($1, $2, $3) RETURNING "form_id";`,
+			state.guildId, form.Title, utils.RandomString(30),
+		).Scan(&formId); err != nil {
+			return err
+		}
+
+		state.ids.Forms[form.Id] = formId
+		state.report.created("forms")
+
+		formInputs := inputs[form.Id]
+		sort.Slice(formInputs, func(a, b int) bool {
+			return formInputs[a].Position < formInputs[b].Position
+		})
+
+		for position, input := range formInputs {
+			var inputId int
+			if err := state.tx.QueryRow(ctx, `
+INSERT INTO form_input("form_id", "position", "custom_id", "style", "label", "placeholder", "required", "min_length", "max_length")
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING "id";`,
+				formId, position+1, utils.RandomString(30), input.Style, input.Label, input.Placeholder, input.Required,
+				input.MinLength, input.MaxLength,
+			).Scan(&inputId); err != nil {
+				return err
+			}
+
+			state.ids.FormInputs[input.Id] = inputId
+			state.report.created("form_inputs")
+		}
+	}
+
+	return nil
+}
+
+func (i *Importer) importEmbeds(ctx context.Context, state *importState) error {
+	fields := make(map[int][]dto.EmbedField)
+	for _, field := range state.data.EmbedFields {
+		fields[field.EmbedId] = append(fields[field.EmbedId], field)
+	}
+
+	for _, embed := range state.data.Embeds {
+		embed.GuildId = state.guildId
+
+		embedId, err := i.database.Embeds.CreateWithFieldsTx(ctx, state.tx,
+			utils.Ptr(dtoconv.CustomEmbedToDatabase(embed)), dtoconv.EmbedFieldsToDatabase(fields[embed.Id]))
+		if err != nil {
+			return err
+		}
+
+		state.ids.Embeds[embed.Id] = embedId
+		state.report.created("embeds")
+	}
+
+	return nil
+}
+
+func (i *Importer) importPanels(ctx context.Context, state *importState) error {
+	for _, panel := range state.data.Panels {
+		var exists bool
+		if err := state.tx.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM panels WHERE "guild_id" = $1 AND "custom_id" = $2);`,
+			state.guildId, panel.CustomId,
+		).Scan(&exists); err != nil {
+			return err
+		}
+
+		if exists {
+			state.report.conflict("panels", panel.CustomId, "a panel with this custom ID already exists")
+			continue
+		}
+
+		sourceId := panel.PanelId
+		panel.GuildId = state.guildId
+		panel.WelcomeMessageEmbed = remapOptional(state, "panels", sourceId, "welcome message embed", panel.WelcomeMessageEmbed, state.ids.Embeds)
+		panel.FormId = remapOptional(state, "panels", sourceId, "form", panel.FormId, state.ids.Forms)
+		panel.ExitSurveyFormId = remapOptional(state, "panels", sourceId, "exit survey form", panel.ExitSurveyFormId, state.ids.Forms)
+
+		teamIds := make([]int, 0, len(state.data.PanelTeams[sourceId]))
+		for _, teamId := range state.data.PanelTeams[sourceId] {
+			if mapped, ok := state.ids.SupportTeams[teamId]; ok {
+				teamIds = append(teamIds, mapped)
+			} else {
+				state.report.conflict("panels", sourceId, "referenced support team was not imported")
+			}
+		}
+
+		// The insert is a no-op if the message ID is already registered to another panel. Nothing else has been
+		// written for the panel at that point.
+		panelId, err := i.database.Panel.CreateWithTx(ctx, state.tx, dtoconv.PanelToDatabase(panel))
+		if errors.Is(err, pgx.ErrNoRows) {
+			state.report.conflict("panels", sourceId, "the panel message is already registered to another panel")
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if err := i.database.PanelTeams.ReplaceWithTx(ctx, state.tx, panelId, teamIds); err != nil {
+			return err
+		}
+
+		if err := i.database.PanelRoleMentions.ReplaceWithTx(ctx, state.tx, panelId, state.data.PanelRoleMentions[sourceId]); err != nil {
+			return err
+		}
+
+		if err := i.database.PanelUserMention.SetWithTx(ctx, state.tx, panelId, state.data.PanelMentionUser[sourceId]); err != nil {
+			return err
+		}
+
+		if err := i.database.PanelAccessControlRules.ReplaceWithTx(ctx, state.tx, panelId,
+			dtoconv.PanelAccessControlRulesToDatabase(state.data.PanelAccessControlRules[sourceId])); err != nil {
+			return err
+		}
+
+		state.ids.Panels[sourceId] = panelId
+		state.report.created("panels")
+	}
+
+	return nil
+}
+
+func (i *Importer) importMultiPanels(ctx context.Context, state *importState) error {
+	for _, multiPanel := range state.data.MultiPanels {
+		var exists bool
+		if err := state.tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM multi_panels WHERE "message_id" = $1);`,
+			multiPanel.MessageId).Scan(&exists); err != nil {
+			return err
+		}
+
+		if exists {
+			state.report.conflict("multi_panels", multiPanel.Id, "the multi-panel message is already registered")
+			continue
+		}
+
+		sourceId := multiPanel.Id
+		multiPanel.GuildId = state.guildId
+		converted := dtoconv.MultiPanelToDatabase(multiPanel)
+
+		var embedRaw *string
+		if converted.Embed != nil {
+			marshalled, err := json.Marshal(converted.Embed)
+			if err != nil {
+				return err
+			}
+
+			embedRaw = utils.Ptr(string(marshalled))
+		}
+
+		var multiPanelId int
+		if err := state.tx.QueryRow(ctx, `
+INSERT INTO multi_panels("message_id", "channel_id", "guild_id", "select_menu", "select_menu_placeholder", "embed")
+VALUES($1, $2, $3, $4, $5, $6)
+RETURNING "id";`,
+			converted.MessageId, converted.ChannelId, converted.GuildId, converted.SelectMenu,
+			converted.SelectMenuPlaceholder, embedRaw,
+		).Scan(&multiPanelId); err != nil {
+			return err
+		}
+
+		for _, panelId := range state.data.MultiPanelTargets[sourceId] {
+			mapped, ok := state.ids.Panels[panelId]
+			if !ok {
+				state.report.conflict("multi_panels", sourceId, "referenced panel was not imported")
+				continue
+			}
+
+			if _, err := state.tx.Exec(ctx,
+				`INSERT INTO multi_panel_targets("multi_panel_id", "panel_id") VALUES($1, $2) ON CONFLICT DO NOTHING;`,
+				multiPanelId, mapped,
+			); err != nil {
+				return err
+			}
+		}
+
+		state.ids.MultiPanels[sourceId] = multiPanelId
+		state.report.created("multi_panels")
+	}
+
+	return nil
+}
+
+func (i *Importer) importTags(ctx context.Context, state *importState) error {
+	for _, tag := range state.data.Tags {
+		tag.GuildId = state.guildId
+		tag.ApplicationCommandId = nil // Slash commands must be registered again in the target guild
+		converted := dtoconv.TagToDatabase(tag)
+
+		var embedRaw *string
+		if converted.Embed != nil {
+			marshalled, err := json.Marshal(converted.Embed)
+			if err != nil {
+				return err
+			}
+
+			embedRaw = utils.Ptr(string(marshalled))
+		}
+
+		res, err := state.tx.Exec(ctx, `
+INSERT INTO tags("tag_id", "guild_id", "content", "embed", "application_command_id")
+VALUES(LOWER($1), $2, $3, $4, $5)
+ON CONFLICT DO NOTHING;`,
+			converted.Id, converted.GuildId, converted.Content, embedRaw, converted.ApplicationCommandId,
+		)
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			state.report.conflict("tags", tag.Id, "a tag with this ID already exists")
+			continue
+		}
+
+		state.report.created("tags")
+	}
+
+	return nil
+}
+
+// remapOptional translates an optional reference to the ID assigned in the target database, recording a conflict and
+// clearing the reference if the referenced row was not imported.
+func remapOptional(state *importState, section string, key any, name string, id *int, mappings map[int]int) *int {
+	if id == nil {
+		return nil
+	}
+
+	mapped, ok := mappings[*id]
+	if !ok {
+		state.report.conflict(section, key, "referenced "+name+" was not imported, clearing reference")
+		return nil
+	}
+
+	return &mapped
+}
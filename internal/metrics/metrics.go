@@ -16,6 +16,8 @@ var (
 	RequestsProcessed      = promauto.NewCounterVec(counterOpsWorker("requests_processed"), []string{"type", "status"})
 	ArtifactsUploaded      = promauto.NewCounterVec(counterOpsWorker("artifacts_uploaded"), []string{"type"})
 	ArtifactsUploadedBytes = promauto.NewCounterVec(counterOpsWorker("artifacts_uploaded_bytes"), []string{"type"})
+
+	GuildDataTaskDuration = promauto.NewHistogramVec(histogramOpsWorker("guild_data_task_duration_seconds"), []string{"section", "status"})
 )
 
 func counterOpsApi(name string) prometheus.CounterOpts {
@@ -33,3 +35,12 @@ func counterOpsWorker(name string) prometheus.CounterOpts {
 		Name:      name,
 	}
 }
+
+func histogramOpsWorker(name string) prometheus.HistogramOpts {
+	return prometheus.HistogramOpts{
+		Namespace: "tickets",
+		Subsystem: "export_worker",
+		Name:      name,
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15), // 10ms to ~2.7m
+	}
+}
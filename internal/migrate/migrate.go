@@ -0,0 +1,275 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is a single SQL file from the migrations directory. Files are named NNNN-description.sql, and are
+// applied in order of their number.
+type Migration struct {
+	Version int
+	Name    string
+	Sql     string
+}
+
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// ErrOutdated is returned by Check when there are migrations that have not been applied to the database.
+var ErrOutdated = errors.New("database schema is out of date")
+
+// lockKey is the advisory lock held while migrations are applied, so that several instances starting at once do not
+// race each other. It is an arbitrary constant, shared by every version of the service.
+const lockKey int64 = 0x6578706f7274
+
+const createTableQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    int4 PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`
+
+// Embedded returns the migrations built into the binary.
+func Embedded() ([]Migration, error) {
+	return Load(migrations.FS)
+}
+
+// Load reads every migration in the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		versionRaw, _, ok := strings.Cut(entry.Name(), "-")
+		if !ok {
+			return nil, fmt.Errorf("migration %s is not named NNNN-description.sql", entry.Name())
+		}
+
+		version, err := strconv.Atoi(versionRaw)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s is not named NNNN-description.sql: %w", entry.Name(), err)
+		}
+
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, entry.Name())
+		}
+
+		seen[version] = entry.Name()
+
+		sql, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    entry.Name(),
+			Sql:     string(sql),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Applied returns the migrations that have already been applied to the database, sorted by version. If the
+// schema_migrations table does not exist yet, no migrations have been applied.
+func Applied(ctx context.Context, db querier) ([]AppliedMigration, error) {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL;`).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return []AppliedMigration{}, nil
+	}
+
+	rows, err := db.Query(ctx, `SELECT version, name, applied_at FROM schema_migrations ORDER BY version ASC;`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make([]AppliedMigration, 0)
+	for rows.Next() {
+		var migration AppliedMigration
+		if err := rows.Scan(&migration.Version, &migration.Name, &migration.AppliedAt); err != nil {
+			return nil, err
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, rows.Err()
+}
+
+// Pending returns the migrations that have not yet been applied.
+func Pending(migrations []Migration, applied []AppliedMigration) []Migration {
+	done := make(map[int]bool, len(applied))
+	for _, migration := range applied {
+		done[migration.Version] = true
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending
+}
+
+// Check returns ErrOutdated if any of the migrations have not been applied to the database. Migrations that have been
+// applied but are not known to this binary are ignored, as a newer version of the service may have applied them.
+func Check(ctx context.Context, pool *pgxpool.Pool, migrations []Migration) error {
+	applied, err := Applied(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	pending := Pending(migrations, applied)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(pending))
+	for i, migration := range pending {
+		names[i] = migration.Name
+	}
+
+	return fmt.Errorf("%w, pending migrations: %s", ErrOutdated, strings.Join(names, ", "))
+}
+
+// Apply applies every pending migration, in order, each in its own transaction. The migrations applied are returned,
+// including when an error occurs part way through.
+func Apply(ctx context.Context, pool *pgxpool.Pool, migrations []Migration) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := withLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		// Another instance may have applied migrations while we were waiting for the lock
+		applied, err := Applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range Pending(migrations, applied) {
+			// Migrations are run with the simple protocol, as they may contain several statements
+			if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Sql, pgx.QueryExecModeSimpleProtocol); err != nil {
+					return err
+				}
+
+				return record(ctx, tx, migration)
+			}); err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Baseline marks every migration up to and including version as applied, without running them. This is for databases
+// whose schema was created by hand before migrations were tracked.
+func Baseline(ctx context.Context, pool *pgxpool.Pool, migrations []Migration, version int) ([]Migration, error) {
+	marked := make([]Migration, 0)
+	if err := withLock(ctx, pool, func(conn *pgxpool.Conn) error {
+		applied, err := Applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			for _, migration := range Pending(migrations, applied) {
+				if migration.Version > version {
+					continue
+				}
+
+				if err := record(ctx, tx, migration); err != nil {
+					return err
+				}
+
+				marked = append(marked, migration)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return marked, nil
+}
+
+// withLock runs f on a single connection while holding the migration advisory lock, creating the schema_migrations
+// table if it does not exist. Session level locks belong to the connection, so f must only use the connection given.
+func withLock(ctx context.Context, pool *pgxpool.Pool, f func(conn *pgxpool.Conn) error) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, lockKey); err != nil {
+		return err
+	}
+
+	defer func() {
+		// Use a fresh context, as the lock must be released even if ctx has been cancelled. If unlocking fails, the
+		// connection is closed rather than returned to the pool still holding the lock.
+		unlockCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1);`, lockKey); err != nil {
+			conn.Conn().Close(unlockCtx)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createTableQuery); err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+func record(ctx context.Context, tx pgx.Tx, migration Migration) error {
+	_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, migration.Version, migration.Name)
+	return err
+}
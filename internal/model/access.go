@@ -0,0 +1,65 @@
+package model
+
+import "fmt"
+
+// AccessLevel is the level of control a user has over a guild, derived from the guild list returned by Discord.
+// Levels are ordered, so a user with a higher level also has every lower level.
+type AccessLevel uint8
+
+const (
+	AccessLevelNone    AccessLevel = iota
+	AccessLevelManager             // MANAGE_GUILD permission
+	AccessLevelAdmin               // ADMINISTRATOR permission
+	AccessLevelOwner
+)
+
+const (
+	permissionAdministrator uint64 = 1 << 3
+	permissionManageGuild   uint64 = 1 << 5
+)
+
+func AccessLevelFromPermissions(owner bool, permissions uint64) AccessLevel {
+	if owner {
+		return AccessLevelOwner
+	} else if permissions&permissionAdministrator == permissionAdministrator {
+		return AccessLevelAdmin
+	} else if permissions&permissionManageGuild == permissionManageGuild {
+		return AccessLevelManager
+	} else {
+		return AccessLevelNone
+	}
+}
+
+func (l AccessLevel) String() string {
+	switch l {
+	case AccessLevelManager:
+		return "manager"
+	case AccessLevelAdmin:
+		return "admin"
+	case AccessLevelOwner:
+		return "owner"
+	default:
+		return "none"
+	}
+}
+
+func (l AccessLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *AccessLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "none":
+		*l = AccessLevelNone
+	case "manager":
+		*l = AccessLevelManager
+	case "admin":
+		*l = AccessLevelAdmin
+	case "owner":
+		*l = AccessLevelOwner
+	default:
+		return fmt.Errorf("invalid access level %s", text)
+	}
+
+	return nil
+}
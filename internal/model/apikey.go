@@ -0,0 +1,56 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"slices"
+	"time"
+)
+
+// ApiKeyPrefix is prepended to every API key, so that they can be told apart from session tokens, and recognised by
+// secret scanners.
+const ApiKeyPrefix = "tbexp_"
+
+type ApiKey struct {
+	Id     uuid.UUID     `json:"id"`
+	UserId uint64        `json:"user_id,string"`
+	Name   string        `json:"name"`
+	Scopes []ApiKeyScope `json:"scopes"`
+	// GuildIds limits the key to requests for these guilds. If nil, the key can be used for any guild the user has
+	// access to, and for the user's own data.
+	GuildIds   []uint64   `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type ApiKeyScope string
+
+const (
+	ApiKeyScopeRequestsCreate    ApiKeyScope = "requests:create"
+	ApiKeyScopeRequestsList      ApiKeyScope = "requests:list"
+	ApiKeyScopeArtifactsDownload ApiKeyScope = "artifacts:download"
+)
+
+func (s ApiKeyScope) Valid() bool {
+	switch s {
+	case ApiKeyScopeRequestsCreate, ApiKeyScopeRequestsList, ApiKeyScopeArtifactsDownload:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k ApiKey) HasScope(scope ApiKeyScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// AllowsGuild returns whether the key may be used for requests for the given guild, or for the user's own data if
+// guildId is nil.
+func (k ApiKey) AllowsGuild(guildId *uint64) bool {
+	if k.GuildIds == nil {
+		return true
+	}
+
+	return guildId != nil && slices.Contains(k.GuildIds, *guildId)
+}
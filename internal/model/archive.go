@@ -0,0 +1,42 @@
+package model
+
+// ArchiveFormat is the container and compression that an export's files are packaged in.
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip ArchiveFormat = "zip"
+	// ArchiveFormatTarGz is a gzip compressed tar archive. It compresses better than zip, as files are compressed
+	// together rather than individually.
+	ArchiveFormatTarGz ArchiveFormat = "tar.gz"
+	// ArchiveFormatTarZst is a zstd compressed tar archive, which is faster to produce than tar.gz, and smaller.
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+)
+
+func (f ArchiveFormat) String() string {
+	return string(f)
+}
+
+func (f ArchiveFormat) Valid() bool {
+	switch f {
+	case ArchiveFormatZip, ArchiveFormatTarGz, ArchiveFormatTarZst:
+		return true
+	default:
+		return false
+	}
+}
+
+// Extension returns the file extension of archives in this format, including the leading dot.
+func (f ArchiveFormat) Extension() string {
+	return "." + string(f)
+}
+
+func (f ArchiveFormat) ContentType() string {
+	switch f {
+	case ArchiveFormatTarGz:
+		return "application/gzip"
+	case ArchiveFormatTarZst:
+		return "application/zstd"
+	default:
+		return "application/zip"
+	}
+}
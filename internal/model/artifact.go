@@ -5,9 +5,15 @@ import (
 	"time"
 )
 
+// Artifact is one part of the output of a request. Small exports have a single part, numbered 1, while large exports
+// are split into several, each an archive of at most the configured maximum part size.
 type Artifact struct {
 	Id        uuid.UUID `json:"id"`
 	RequestId uuid.UUID `json:"request_id"`
 	Key       string    `json:"key"`
 	ExpiresAt time.Time `json:"expires_at"`
+	Part      int       `json:"part"`
+	Size      int64     `json:"size"`
+	// Format is the format that every part of the artifact is packaged in
+	Format ArchiveFormat `json:"format"`
 }
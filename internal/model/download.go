@@ -0,0 +1,7 @@
+package model
+
+type DownloadTotal struct {
+	UserId    uint64 `json:"user_id,string"`
+	Downloads int64  `json:"downloads"`
+	Bytes     int64  `json:"bytes"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// ErasureProgress records what a guild erasure has deleted so far. The guild's rows are deleted in a single
+// transaction, but its transcripts are deleted separately, and if that fails the erasure is retried. The progress is
+// kept so that a retried erasure does not delete the rows again, and still reports them in its receipt.
+type ErasureProgress struct {
+	DeletedRows        map[string]int64 `json:"deleted_rows"`
+	DeletedTranscripts int              `json:"deleted_transcripts"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// OAuthToken is a user's Discord OAuth2 token pair. The tokens are stored encrypted, and are only decrypted by the
+// API when it needs to re-check the user's guild access.
+type OAuthToken struct {
+	UserId       uint64
+	AccessToken  []byte
+	RefreshToken []byte
+	ExpiresAt    time.Time
+}
@@ -1,17 +1,181 @@
 package model
 
 import (
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"slices"
 	"time"
 )
 
 type Request struct {
-	Id        uuid.UUID     `json:"id"`
-	UserId    uint64        `json:"user_id,string"`
-	Type      RequestType   `json:"type"`
-	CreatedAt time.Time     `json:"created_at"`
-	GuildId   *uint64       `json:"guild_id,string"`
-	Status    RequestStatus `json:"status"`
+	Id        uuid.UUID      `json:"id"`
+	UserId    uint64         `json:"user_id,string"`
+	Type      RequestType    `json:"type"`
+	CreatedAt time.Time      `json:"created_at"`
+	GuildId   *uint64        `json:"guild_id,string"`
+	Status    RequestStatus  `json:"status"`
+	Options   RequestOptions `json:"options"`
+}
+
+// RequestOptions holds optional, per-request settings chosen by the user. It is stored as JSON, so new fields must
+// default to the existing behaviour when absent.
+type RequestOptions struct {
+	// RenderHtml adds a rendered HTML page for each transcript, and an index.html listing tickets.
+	RenderHtml bool `json:"render_html,omitempty"`
+
+	// IncludeCsv adds a flattened CSV file for each collection in the guild data export.
+	IncludeCsv bool `json:"include_csv,omitempty"`
+
+	// Format selects the file format of a guild data export. Defaults to ExportFormatJson.
+	Format ExportFormat `json:"format,omitempty"`
+
+	// IncludeTranscriptMessages adds the messages of every transcript to a SQLite guild data export.
+	IncludeTranscriptMessages bool `json:"include_transcript_messages,omitempty"`
+
+	// Redaction selects how personal data is redacted from guild exports. Defaults to RedactionProfileNone.
+	Redaction RedactionProfile `json:"redaction,omitempty"`
+
+	// Sections limits a guild data export to the listed sections. If empty, the sections are chosen by SectionPreset.
+	Sections []GuildDataSection `json:"sections,omitempty"`
+
+	// SectionPreset selects the sections of a guild data export by name. Defaults to SectionPresetEverything.
+	SectionPreset SectionPreset `json:"section_preset,omitempty"`
+
+	// Archive selects the format of the archive that the export is packaged in. Defaults to ArchiveFormatZip.
+	Archive ArchiveFormat `json:"archive_format,omitempty"`
+}
+
+// ArchiveFormat returns the format to package the export in.
+func (o RequestOptions) ArchiveFormat() ArchiveFormat {
+	if o.Archive == "" {
+		return ArchiveFormatZip
+	}
+
+	return o.Archive
+}
+
+// GuildDataSections returns the sections to include in a guild data export, in the order they appear in data.json.
+func (o RequestOptions) GuildDataSections() []GuildDataSection {
+	if len(o.Sections) == 0 {
+		if o.SectionPreset == "" {
+			return SectionPresetEverything.Sections()
+		}
+
+		return o.SectionPreset.Sections()
+	}
+
+	var sections []GuildDataSection
+	for _, section := range guildDataSections {
+		if slices.Contains(o.Sections, section) {
+			sections = append(sections, section)
+		}
+	}
+
+	return sections
+}
+
+type ExportFormat string
+
+const (
+	ExportFormatJson   ExportFormat = "json"
+	ExportFormatSqlite ExportFormat = "sqlite"
+)
+
+// RedactionProfile controls how identifying data is removed from an export, for example before sharing it with an
+// external auditor.
+type RedactionProfile string
+
+const (
+	RedactionProfileNone RedactionProfile = "none"
+	// RedactionProfilePseudonymise replaces user IDs with pseudonyms, which are consistent within an export but can't
+	// be linked back to the user, and removes usernames and avatars from transcripts.
+	RedactionProfilePseudonymise RedactionProfile = "pseudonymise"
+	// RedactionProfilePseudonymiseStripContent also removes message content, embeds, attachments, close reasons and
+	// exit survey responses.
+	RedactionProfilePseudonymiseStripContent RedactionProfile = "pseudonymise_strip_content"
+)
+
+// Pseudonymise returns whether user IDs are replaced with pseudonyms.
+func (p RedactionProfile) Pseudonymise() bool {
+	return p == RedactionProfilePseudonymise || p == RedactionProfilePseudonymiseStripContent
+}
+
+// StripContent returns whether free text written by users is removed.
+func (p RedactionProfile) StripContent() bool {
+	return p == RedactionProfilePseudonymiseStripContent
+}
+
+// Validate returns an error describing the first option that does not apply to the given request type.
+func (o RequestOptions) Validate(requestType RequestType) error {
+	if o.RenderHtml && requestType != RequestTypeGuildTranscripts {
+		return errors.New("HTML rendering is only available for transcript exports")
+	}
+
+	if o.IncludeCsv && requestType != RequestTypeGuildData {
+		return errors.New("CSV files are only available for server data exports")
+	}
+
+	switch o.Format {
+	case "", ExportFormatJson:
+	case ExportFormatSqlite:
+		if requestType != RequestTypeGuildData {
+			return errors.New("The SQLite format is only available for server data exports")
+		}
+	default:
+		return errors.New("Invalid export format")
+	}
+
+	if o.IncludeTranscriptMessages && o.Format != ExportFormatSqlite {
+		return errors.New("Transcript messages can only be included in SQLite exports")
+	}
+
+	switch o.Redaction {
+	case "", RedactionProfileNone:
+	case RedactionProfilePseudonymise, RedactionProfilePseudonymiseStripContent:
+		if requestType != RequestTypeGuildData && requestType != RequestTypeGuildTranscripts &&
+			requestType != RequestTypeGuildFull {
+			return errors.New("Redaction is only available for server data and transcript exports")
+		}
+	default:
+		return errors.New("Invalid redaction profile")
+	}
+
+	hasSections := len(o.Sections) > 0 || o.SectionPreset != ""
+	if hasSections && requestType != RequestTypeGuildData && requestType != RequestTypeGuildFull {
+		return errors.New("Sections can only be selected for server data exports")
+	}
+
+	if len(o.Sections) > 0 && o.SectionPreset != "" {
+		return errors.New("Either a list of sections or a preset can be selected, not both")
+	}
+
+	for _, section := range o.Sections {
+		if !section.Valid() {
+			return fmt.Errorf("Invalid section %q", section)
+		}
+	}
+
+	if o.SectionPreset != "" && !o.SectionPreset.Valid() {
+		return errors.New("Invalid section preset")
+	}
+
+	if o.Archive != "" && !o.Archive.Valid() {
+		return errors.New("Invalid archive format")
+	}
+
+	return nil
+}
+
+// RequestFilter selects requests to list for operators. Nil fields match any value.
+type RequestFilter struct {
+	UserId  *uint64
+	GuildId *uint64
+	Type    *RequestType
+	Status  *RequestStatus
+	// Before only matches requests created before this time, for paginating through results
+	Before *time.Time
+	Limit  int
 }
 
 type RequestType string
@@ -19,20 +183,82 @@ type RequestType string
 const (
 	RequestTypeGuildTranscripts RequestType = "guild_transcripts"
 	RequestTypeGuildData        RequestType = "guild_data"
+	RequestTypeUserData         RequestType = "user_data"
+	RequestTypeGuildErasure     RequestType = "guild_erasure"
+	RequestTypeUserErasure      RequestType = "user_erasure"
+	// RequestTypeGuildFull exports a guild's data and transcripts together, in one archive with a single signed index
+	RequestTypeGuildFull RequestType = "guild_full"
 )
 
 func (r RequestType) String() string {
 	return string(r)
 }
 
+func (r RequestType) Valid() bool {
+	switch r {
+	case RequestTypeGuildTranscripts, RequestTypeGuildData, RequestTypeUserData, RequestTypeGuildErasure, RequestTypeUserErasure,
+		RequestTypeGuildFull:
+		return true
+	default:
+		return false
+	}
+}
+
+// ArtifactName returns the name that artifacts of this type are downloaded as, without the archive extension. Both
+// erasure types produce a receipt of the erasure.
+func (r RequestType) ArtifactName() string {
+	switch r {
+	case RequestTypeGuildTranscripts:
+		return "transcripts"
+	case RequestTypeGuildData:
+		return "guild-data"
+	case RequestTypeUserData:
+		return "user-data"
+	case RequestTypeGuildErasure, RequestTypeUserErasure:
+		return "erasure-receipt"
+	case RequestTypeGuildFull:
+		return "guild-export"
+	default:
+		return "export"
+	}
+}
+
+// GuildScoped returns whether requests of this type target a single guild, and therefore require the user to own it.
+func (r RequestType) GuildScoped() bool {
+	return r != RequestTypeUserData && r != RequestTypeUserErasure
+}
+
+// IsErasure returns whether requests of this type delete data, and therefore must be confirmed by the user before
+// being queued.
+func (r RequestType) IsErasure() bool {
+	return r == RequestTypeGuildErasure || r == RequestTypeUserErasure
+}
+
 type RequestStatus string
 
 const (
-	RequestStatusQueued    RequestStatus = "queued"
-	RequestStatusFailed    RequestStatus = "failed"
-	RequestStatusCompleted RequestStatus = "completed"
+	RequestStatusAwaitingConfirmation RequestStatus = "awaiting_confirmation"
+	RequestStatusQueued               RequestStatus = "queued"
+	RequestStatusFailed               RequestStatus = "failed"
+	RequestStatusCompleted            RequestStatus = "completed"
+	RequestStatusCancelled            RequestStatus = "cancelled"
 )
 
 func (r RequestStatus) String() string {
 	return string(r)
 }
+
+// Requeueable returns whether a request with this status can be queued to run again. Completed requests can't, as
+// they already have an artifact.
+func (r RequestStatus) Requeueable() bool {
+	return r == RequestStatusFailed || r == RequestStatusQueued
+}
+
+func (r RequestStatus) Valid() bool {
+	switch r {
+	case RequestStatusAwaitingConfirmation, RequestStatusQueued, RequestStatusFailed, RequestStatusCompleted, RequestStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
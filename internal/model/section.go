@@ -0,0 +1,169 @@
+package model
+
+import "slices"
+
+// GuildDataSection is a top level field of a guild data export, named by its key in data.json.
+type GuildDataSection string
+
+const (
+	GuildDataSectionActiveLanguage             GuildDataSection = "active_language"
+	GuildDataSectionArchiveChannel             GuildDataSection = "archive_channel"
+	GuildDataSectionArchiveMessages            GuildDataSection = "archive_messages"
+	GuildDataSectionAutocloseSettings          GuildDataSection = "autoclose_settings"
+	GuildDataSectionAutocloseExcluded          GuildDataSection = "autoclose_excluded"
+	GuildDataSectionGuildBlacklistedUsers      GuildDataSection = "guild_blacklisted_users"
+	GuildDataSectionChannelCategory            GuildDataSection = "channel_category"
+	GuildDataSectionClaimSettings              GuildDataSection = "claim_settings"
+	GuildDataSectionCloseConfirmationEnabled   GuildDataSection = "close_confirmation_enabled"
+	GuildDataSectionCloseReasons               GuildDataSection = "close_reasons"
+	GuildDataSectionCustomColors               GuildDataSection = "custom_colors"
+	GuildDataSectionEmbedFields                GuildDataSection = "embed_fields"
+	GuildDataSectionEmbeds                     GuildDataSection = "embeds"
+	GuildDataSectionExitSurveyResponses        GuildDataSection = "exit_survey_responses"
+	GuildDataSectionFeedbackEnabled            GuildDataSection = "feedback_enabled"
+	GuildDataSectionFirstResponseTimes         GuildDataSection = "first_response_times"
+	GuildDataSectionFormInputs                 GuildDataSection = "form_inputs"
+	GuildDataSectionForms                      GuildDataSection = "forms"
+	GuildDataSectionGuildIsGloballyBlacklisted GuildDataSection = "guild_is_globally_blacklisted"
+	GuildDataSectionGuildMetadata              GuildDataSection = "guild_metadata"
+	GuildDataSectionMultiPanels                GuildDataSection = "multi_panels"
+	GuildDataSectionMultiPanelTargets          GuildDataSection = "multi_panel_targets"
+	GuildDataSectionNamingScheme               GuildDataSection = "naming_scheme"
+	GuildDataSectionOnCallUsers                GuildDataSection = "on_call_users"
+	GuildDataSectionPanelAccessControlRules    GuildDataSection = "panel_access_control_rules"
+	GuildDataSectionPanelMentionUser           GuildDataSection = "panel_mention_user"
+	GuildDataSectionPanelRoleMentions          GuildDataSection = "panel_role_mentions"
+	GuildDataSectionPanels                     GuildDataSection = "panels"
+	GuildDataSectionPanelTeams                 GuildDataSection = "panel_teams"
+	GuildDataSectionParticipants               GuildDataSection = "participants"
+	GuildDataSectionUserPermissions            GuildDataSection = "user_permissions"
+	GuildDataSectionGuildBlacklistedRoles      GuildDataSection = "guild_blacklisted_roles"
+	GuildDataSectionRolePermissions            GuildDataSection = "role_permissions"
+	GuildDataSectionServiceRatings             GuildDataSection = "service_ratings"
+	GuildDataSectionSettings                   GuildDataSection = "settings"
+	GuildDataSectionSupportTeamUsers           GuildDataSection = "support_team_users"
+	GuildDataSectionSupportTeamRoles           GuildDataSection = "support_team_roles"
+	GuildDataSectionSupportTeams               GuildDataSection = "support_teams"
+	GuildDataSectionTags                       GuildDataSection = "tags"
+	GuildDataSectionTicketClaims               GuildDataSection = "ticket_claims"
+	GuildDataSectionTicketLastMessages         GuildDataSection = "ticket_last_messages"
+	GuildDataSectionTicketLimit                GuildDataSection = "ticket_limit"
+	GuildDataSectionTicketAdditionalMembers    GuildDataSection = "ticket_additional_members"
+	GuildDataSectionTicketPermissions          GuildDataSection = "ticket_permissions"
+	GuildDataSectionTickets                    GuildDataSection = "tickets"
+	GuildDataSectionUsersCanClose              GuildDataSection = "users_can_close"
+	GuildDataSectionWelcomeMessage             GuildDataSection = "welcome_message"
+)
+
+// ticketSections are the sections holding data about individual tickets. Every other section is configuration.
+var ticketSections = []GuildDataSection{
+	GuildDataSectionArchiveMessages,
+	GuildDataSectionAutocloseExcluded,
+	GuildDataSectionCloseReasons,
+	GuildDataSectionExitSurveyResponses,
+	GuildDataSectionFirstResponseTimes,
+	GuildDataSectionParticipants,
+	GuildDataSectionServiceRatings,
+	GuildDataSectionTicketClaims,
+	GuildDataSectionTicketLastMessages,
+	GuildDataSectionTicketAdditionalMembers,
+	GuildDataSectionTickets,
+}
+
+var guildDataSections = []GuildDataSection{
+	GuildDataSectionActiveLanguage,
+	GuildDataSectionArchiveChannel,
+	GuildDataSectionArchiveMessages,
+	GuildDataSectionAutocloseSettings,
+	GuildDataSectionAutocloseExcluded,
+	GuildDataSectionGuildBlacklistedUsers,
+	GuildDataSectionChannelCategory,
+	GuildDataSectionClaimSettings,
+	GuildDataSectionCloseConfirmationEnabled,
+	GuildDataSectionCloseReasons,
+	GuildDataSectionCustomColors,
+	GuildDataSectionEmbedFields,
+	GuildDataSectionEmbeds,
+	GuildDataSectionExitSurveyResponses,
+	GuildDataSectionFeedbackEnabled,
+	GuildDataSectionFirstResponseTimes,
+	GuildDataSectionFormInputs,
+	GuildDataSectionForms,
+	GuildDataSectionGuildIsGloballyBlacklisted,
+	GuildDataSectionGuildMetadata,
+	GuildDataSectionMultiPanels,
+	GuildDataSectionMultiPanelTargets,
+	GuildDataSectionNamingScheme,
+	GuildDataSectionOnCallUsers,
+	GuildDataSectionPanelAccessControlRules,
+	GuildDataSectionPanelMentionUser,
+	GuildDataSectionPanelRoleMentions,
+	GuildDataSectionPanels,
+	GuildDataSectionPanelTeams,
+	GuildDataSectionParticipants,
+	GuildDataSectionUserPermissions,
+	GuildDataSectionGuildBlacklistedRoles,
+	GuildDataSectionRolePermissions,
+	GuildDataSectionServiceRatings,
+	GuildDataSectionSettings,
+	GuildDataSectionSupportTeamUsers,
+	GuildDataSectionSupportTeamRoles,
+	GuildDataSectionSupportTeams,
+	GuildDataSectionTags,
+	GuildDataSectionTicketClaims,
+	GuildDataSectionTicketLastMessages,
+	GuildDataSectionTicketLimit,
+	GuildDataSectionTicketAdditionalMembers,
+	GuildDataSectionTicketPermissions,
+	GuildDataSectionTickets,
+	GuildDataSectionUsersCanClose,
+	GuildDataSectionWelcomeMessage,
+}
+
+func (s GuildDataSection) String() string {
+	return string(s)
+}
+
+func (s GuildDataSection) Valid() bool {
+	return slices.Contains(guildDataSections, s)
+}
+
+// IsTicketData returns whether the section holds data about individual tickets, rather than configuration.
+func (s GuildDataSection) IsTicketData() bool {
+	return slices.Contains(ticketSections, s)
+}
+
+// SectionPreset is a predefined set of guild data sections.
+type SectionPreset string
+
+const (
+	SectionPresetEverything SectionPreset = "everything"
+	// SectionPresetConfig includes the server's configuration, such as panels, forms, settings and teams.
+	SectionPresetConfig SectionPreset = "config"
+	// SectionPresetTickets includes tickets and the data recorded about them, such as claims and ratings.
+	SectionPresetTickets SectionPreset = "tickets"
+)
+
+func (p SectionPreset) Valid() bool {
+	switch p {
+	case SectionPresetEverything, SectionPresetConfig, SectionPresetTickets:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sections returns the sections included by the preset, in the order they appear in data.json.
+func (p SectionPreset) Sections() []GuildDataSection {
+	var sections []GuildDataSection
+	for _, section := range guildDataSections {
+		switch {
+		case p == SectionPresetConfig && section.IsTicketData():
+		case p == SectionPresetTickets && !section.IsTicketData():
+		default:
+			sections = append(sections, section)
+		}
+	}
+
+	return sections
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type Session struct {
+	Id            uuid.UUID  `json:"id"`
+	UserId        uint64     `json:"user_id,string"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	RevokedBy     *uint64    `json:"revoked_by,string"`
+	RevokedReason *string    `json:"revoked_reason"`
+}
+
+// Valid returns whether the session can still be used to authenticate.
+func (s Session) Valid() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type QueueStats struct {
+	// Due is the number of queued tasks that are due to run
+	Due int64 `json:"due"`
+	// Waiting is the number of queued tasks that are not yet due, for example erasures in their grace period
+	Waiting int64 `json:"waiting"`
+	// OldestDue is when the oldest request with a task that is due to run was created
+	OldestDue *time.Time `json:"oldest_due"`
+}
+
+type RequestCount struct {
+	Type   RequestType   `json:"type"`
+	Status RequestStatus `json:"status"`
+	Count  int64         `json:"count"`
+}
@@ -1,8 +1,16 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// TaskTimeout is how long a worker may spend running a task. A task started longer ago than this is assumed to have
+// been abandoned.
+const TaskTimeout = time.Minute * 10
 
 type Task struct {
 	Id        uuid.UUID `json:"id"`
 	RequestId uuid.UUID `json:"request_id"`
+	RunAfter  time.Time `json:"run_after"`
 }
@@ -12,14 +12,39 @@ func NewUnion[T any, U any](first T, second U) Union[T, U] {
 	}
 }
 
+// RequestWithArtifact is a request along with the parts of its artifact, ordered by part number. Artifacts is empty
+// until the request has completed.
 type RequestWithArtifact struct {
-	Request  Request
-	Artifact *Artifact
+	Request   Request
+	Artifacts []Artifact
 }
 
-func NewRequestWithArtifact(request Request, artifact *Artifact) RequestWithArtifact {
+func NewRequestWithArtifact(request Request, artifacts []Artifact) RequestWithArtifact {
 	return RequestWithArtifact{
-		Request:  request,
-		Artifact: artifact,
+		Request:   request,
+		Artifacts: artifacts,
 	}
 }
+
+// HasArtifact returns whether the request has produced an artifact.
+func (r RequestWithArtifact) HasArtifact() bool {
+	return len(r.Artifacts) > 0
+}
+
+// ArtifactPart returns the given part of the request's artifact, or nil if there is no such part.
+func (r RequestWithArtifact) ArtifactPart(part int) *Artifact {
+	for i := range r.Artifacts {
+		if r.Artifacts[i].Part == part {
+			return &r.Artifacts[i]
+		}
+	}
+
+	return nil
+}
+
+// RequestDetails is a request as seen by operators, including details that are not shown to users.
+type RequestDetails struct {
+	Request       Request    `json:"request"`
+	Artifacts     []Artifact `json:"artifacts"`
+	FailureReason *string    `json:"failure_reason"`
+}
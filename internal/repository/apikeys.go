@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type ApiKeyRepository struct {
+	tx pgx.Tx
+}
+
+var (
+	//go:embed sql/api_keys/create.sql
+	queryApiKeysCreate string
+
+	//go:embed sql/api_keys/list_for_user.sql
+	queryApiKeysListForUser string
+
+	//go:embed sql/api_keys/count_active_for_user.sql
+	queryApiKeysCountActiveForUser string
+
+	//go:embed sql/api_keys/use.sql
+	queryApiKeysUse string
+
+	//go:embed sql/api_keys/revoke.sql
+	queryApiKeysRevoke string
+
+	//go:embed sql/api_keys/revoke_all_for_user.sql
+	queryApiKeysRevokeAllForUser string
+)
+
+func NewApiKeyRepository(tx pgx.Tx) *ApiKeyRepository {
+	return &ApiKeyRepository{
+		tx: tx,
+	}
+}
+
+func (r *ApiKeyRepository) Create(
+	ctx context.Context,
+	userId uint64,
+	name string,
+	keyHash []byte,
+	scopes []model.ApiKeyScope,
+	guildIds []uint64,
+	expiresAt *time.Time,
+) (model.ApiKey, error) {
+	key := model.ApiKey{
+		UserId:    userId,
+		Name:      name,
+		Scopes:    scopes,
+		GuildIds:  guildIds,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := r.tx.QueryRow(ctx, queryApiKeysCreate, userId, name, keyHash, scopes, guildIds, expiresAt).Scan(
+		&key.Id, &key.CreatedAt,
+	); err != nil {
+		return model.ApiKey{}, err
+	}
+
+	return key, nil
+}
+
+// ListForUser returns the user's keys that have not been revoked, including expired keys.
+func (r *ApiKeyRepository) ListForUser(ctx context.Context, userId uint64) ([]model.ApiKey, error) {
+	rows, err := r.tx.Query(ctx, queryApiKeysListForUser, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := make([]model.ApiKey, 0)
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *ApiKeyRepository) CountActiveForUser(ctx context.Context, userId uint64) (int, error) {
+	var count int
+	if err := r.tx.QueryRow(ctx, queryApiKeysCountActiveForUser, userId).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Use looks up a valid key by its hash, and records that it has been used. Returns nil if there is no such key, or if
+// it has expired or been revoked.
+func (r *ApiKeyRepository) Use(ctx context.Context, keyHash []byte) (*model.ApiKey, error) {
+	key, err := scanApiKey(r.tx.QueryRow(ctx, queryApiKeysUse, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *ApiKeyRepository) Revoke(ctx context.Context, keyId uuid.UUID, userId uint64) (bool, error) {
+	res, err := r.tx.Exec(ctx, queryApiKeysRevoke, keyId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
+
+// RevokeAllForUser revokes every key belonging to the user that has not already been revoked, returning the number
+// revoked.
+func (r *ApiKeyRepository) RevokeAllForUser(ctx context.Context, userId uint64) (int64, error) {
+	res, err := r.tx.Exec(ctx, queryApiKeysRevokeAllForUser, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+func scanApiKey(row pgx.Row) (model.ApiKey, error) {
+	var key model.ApiKey
+	err := row.Scan(
+		&key.Id,
+		&key.UserId,
+		&key.Name,
+		&key.Scopes,
+		&key.GuildIds,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+
+	return key, err
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	_ "embed"
+	"github.com/TicketsBot/export/internal/model"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"time"
@@ -18,6 +19,9 @@ var (
 
 	//go:embed sql/artifacts/get_global_size.sql
 	queryArtifactsGetGlobalSize string
+
+	//go:embed sql/artifacts/expire.sql
+	queryArtifactsExpire string
 )
 
 func NewArtifactRepository(tx pgx.Tx) *ArtifactRepository {
@@ -26,8 +30,17 @@ func NewArtifactRepository(tx pgx.Tx) *ArtifactRepository {
 	}
 }
 
-func (r *ArtifactRepository) Create(ctx context.Context, requestId uuid.UUID, key string, expiresAt time.Time, size int64) error {
-	_, err := r.tx.Exec(ctx, queryArtifactsCreate, requestId, key, expiresAt, size)
+// Create records one part of a request's artifact. Parts are numbered from 1.
+func (r *ArtifactRepository) Create(
+	ctx context.Context,
+	requestId uuid.UUID,
+	part int,
+	key string,
+	expiresAt time.Time,
+	size int64,
+	format model.ArchiveFormat,
+) error {
+	_, err := r.tx.Exec(ctx, queryArtifactsCreate, requestId, part, key, expiresAt, size, format)
 	return err
 }
 
@@ -39,3 +52,14 @@ func (r *ArtifactRepository) GetGlobalSize(ctx context.Context) (int64, error) {
 
 	return size, nil
 }
+
+// Expire marks every part of the request's artifact as expired, so that it can no longer be downloaded. Returns false if
+// there is no artifact, or it has already expired.
+func (r *ArtifactRepository) Expire(ctx context.Context, requestId uuid.UUID) (bool, error) {
+	res, err := r.tx.Exec(ctx, queryArtifactsExpire, requestId)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	_ "embed"
+	"github.com/TicketsBot/export/internal/model"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 type DownloadRepository struct {
@@ -20,6 +22,9 @@ var (
 
 	//go:embed sql/downloads/get_daily_bytes.sql
 	queryDownloadsGetDailyBytes string
+
+	//go:embed sql/downloads/list_user_totals.sql
+	queryDownloadsListUserTotals string
 )
 
 func NewDownloadRepository(tx pgx.Tx) *DownloadRepository {
@@ -50,3 +55,26 @@ func (r *DownloadRepository) GetDailyBytes(ctx context.Context) (int64, error) {
 
 	return size, nil
 }
+
+// ListUserTotals returns the number of downloads and bytes downloaded by each user within the period, largest first.
+// If userId is not nil, only that user's total is returned.
+func (r *DownloadRepository) ListUserTotals(ctx context.Context, period time.Duration, userId *uint64, limit int) ([]model.DownloadTotal, error) {
+	rows, err := r.tx.Query(ctx, queryDownloadsListUserTotals, period, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	totals := make([]model.DownloadTotal, 0)
+	for rows.Next() {
+		var total model.DownloadTotal
+		if err := rows.Scan(&total.UserId, &total.Downloads, &total.Bytes); err != nil {
+			return nil, err
+		}
+
+		totals = append(totals, total)
+	}
+
+	return totals, rows.Err()
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type OAuthTokenRepository struct {
+	tx pgx.Tx
+}
+
+var (
+	//go:embed sql/oauth_tokens/get.sql
+	queryOAuthTokensGet string
+
+	//go:embed sql/oauth_tokens/set.sql
+	queryOAuthTokensSet string
+
+	//go:embed sql/oauth_tokens/delete.sql
+	queryOAuthTokensDelete string
+)
+
+func NewOAuthTokenRepository(tx pgx.Tx) *OAuthTokenRepository {
+	return &OAuthTokenRepository{
+		tx: tx,
+	}
+}
+
+func (r *OAuthTokenRepository) Get(ctx context.Context, userId uint64) (*model.OAuthToken, error) {
+	var token model.OAuthToken
+	if err := r.tx.QueryRow(ctx, queryOAuthTokensGet, userId).Scan(
+		&token.UserId,
+		&token.AccessToken,
+		&token.RefreshToken,
+		&token.ExpiresAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *OAuthTokenRepository) Set(ctx context.Context, token model.OAuthToken) error {
+	_, err := r.tx.Exec(ctx, queryOAuthTokensSet, token.UserId, token.AccessToken, token.RefreshToken, token.ExpiresAt)
+	return err
+}
+
+func (r *OAuthTokenRepository) Delete(ctx context.Context, userId uint64) error {
+	_, err := r.tx.Exec(ctx, queryOAuthTokensDelete, userId)
+	return err
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ErrTaskRunning is returned when a request can't be changed because a worker is running it. Once the worker finishes,
+// it records the result and removes the request's tasks, which would strand any task created in the meantime.
+var ErrTaskRunning = errors.New("request is being run by a worker")
+
+// Requeue queues a request to run again immediately, replacing any tasks it already has. The caller should check the
+// request's status is model.RequestStatus.Requeueable first. Returns ErrTaskRunning if a worker is running the request.
+func Requeue(ctx context.Context, tx TransactionContext, requestId uuid.UUID) error {
+	if running, err := tx.Tasks().HasRunningForRequest(ctx, requestId); err != nil {
+		return err
+	} else if running {
+		return ErrTaskRunning
+	}
+
+	if _, err := tx.Tasks().DeleteForRequest(ctx, requestId); err != nil {
+		return err
+	}
+
+	if err := tx.Requests().SetStatus(ctx, requestId, model.RequestStatusQueued); err != nil {
+		return err
+	}
+
+	if err := tx.Requests().SetFailureReason(ctx, requestId, nil); err != nil {
+		return err
+	}
+
+	_, err := tx.Tasks().Create(ctx, requestId, time.Now())
+	return err
+}
@@ -23,6 +23,14 @@ func Connect(ctx context.Context, conf config.DatabaseConfig) (*Repository, erro
 	return NewRepository(pool), nil
 }
 
+// Pool returns the underlying connection pool, for operations that cannot run inside a TransactionContext, such as
+// applying migrations.
+func (r *Repository) Pool() *pgxpool.Pool {
+	return r.db
+}
+
+// Tx runs f in a transaction, committing it if f returns nil. f may instead roll the transaction back itself by calling
+// tx.Rollback and returning its result, in which case Tx returns nil.
 func (r *Repository) Tx(ctx context.Context, f func(ctx context.Context, tx TransactionContext) error) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -38,8 +46,10 @@ func (r *Repository) Tx(ctx context.Context, f func(ctx context.Context, tx Tran
 		return err
 	}
 
+	// The transaction has already been rolled back explicitly. Rolling back again would return pgx.ErrTxClosed, and
+	// callers that roll back to signal a handled outcome would see it as a failure.
 	if transactionContext.aborted {
-		return tx.Rollback(ctx)
+		return nil
 	} else {
 		return tx.Commit(ctx)
 	}
@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	_ "embed"
-	"errors"
 	"github.com/TicketsBot/export/internal/model"
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/google/uuid"
@@ -28,8 +27,26 @@ var (
 	//go:embed sql/requests/set_status.sql
 	queryRequestsSetStatus string
 
+	//go:embed sql/requests/transition_status.sql
+	queryRequestsTransitionStatus string
+
 	//go:embed sql/requests/delete_old.sql
 	queryRequestsDeleteOld string
+
+	//go:embed sql/requests/list_filtered.sql
+	queryRequestsListFiltered string
+
+	//go:embed sql/requests/set_failure_reason.sql
+	queryRequestsSetFailureReason string
+
+	//go:embed sql/requests/count_by_status.sql
+	queryRequestsCountByStatus string
+
+	//go:embed sql/requests/get_erasure_progress.sql
+	queryRequestsGetErasureProgress string
+
+	//go:embed sql/requests/set_erasure_progress.sql
+	queryRequestsSetErasureProgress string
 )
 
 func NewRequestRepository(tx pgx.Tx) *RequestRepository {
@@ -38,15 +55,23 @@ func NewRequestRepository(tx pgx.Tx) *RequestRepository {
 	}
 }
 
-func (r *RequestRepository) Create(ctx context.Context, userId uint64, requestType model.RequestType, guildId *uint64) (model.Request, error) {
+func (r *RequestRepository) Create(
+	ctx context.Context,
+	userId uint64,
+	requestType model.RequestType,
+	guildId *uint64,
+	status model.RequestStatus,
+	options model.RequestOptions,
+) (model.Request, error) {
 	request := model.Request{
 		UserId:  userId,
 		Type:    requestType,
 		GuildId: guildId,
-		Status:  model.RequestStatusQueued,
+		Status:  status,
+		Options: options,
 	}
 
-	if err := r.tx.QueryRow(ctx, queryRequestsCreate, userId, requestType, guildId).Scan(
+	if err := r.tx.QueryRow(ctx, queryRequestsCreate, userId, requestType, guildId, status, options).Scan(
 		&request.Id, &request.CreatedAt,
 	); err != nil {
 		return model.Request{}, err
@@ -66,13 +91,7 @@ func (r *RequestRepository) ListForUser(ctx context.Context, userId uint64) ([]m
 	defer rows.Close()
 	for rows.Next() {
 		var request model.Request
-
-		var (
-			artifactId        *uuid.UUID
-			artifactRequestId *uuid.UUID
-			artifactKey       *string
-			artifactExpiresAt *time.Time
-		)
+		var artifact artifactColumns
 
 		if err := rows.Scan(
 			&request.Id,
@@ -81,73 +100,105 @@ func (r *RequestRepository) ListForUser(ctx context.Context, userId uint64) ([]m
 			&request.CreatedAt,
 			&request.GuildId,
 			&request.Status,
-			&artifactId,
-			&artifactRequestId,
-			&artifactKey,
-			&artifactExpiresAt,
+			&request.Options,
+			&artifact.id,
+			&artifact.requestId,
+			&artifact.key,
+			&artifact.expiresAt,
+			&artifact.part,
+			&artifact.size,
+			&artifact.format,
 		); err != nil {
 			return nil, err
 		}
 
-		var artifact *model.Artifact
-		if artifactId != nil {
-			artifact = &model.Artifact{
-				Id:        *artifactId,
-				RequestId: *artifactRequestId,
-				Key:       *artifactKey,
-				ExpiresAt: *artifactExpiresAt,
-			}
+		// Each part of a request's artifact is returned as its own row, ordered by part
+		if len(requests) == 0 || requests[len(requests)-1].Request.Id != request.Id {
+			requests = append(requests, model.NewRequestWithArtifact(request, nil))
 		}
 
-		requests = append(requests, model.RequestWithArtifact{
-			Request:  request,
-			Artifact: artifact,
-		})
+		if artifact, ok := artifact.toArtifact(); ok {
+			last := &requests[len(requests)-1]
+			last.Artifacts = append(last.Artifacts, artifact)
+		}
 	}
 
-	return requests, nil
+	return requests, rows.Err()
 }
 
 func (r *RequestRepository) GetById(ctx context.Context, requestId uuid.UUID) (*model.RequestWithArtifact, error) {
-	var request model.Request
-
-	var (
-		artifactId        *uuid.UUID
-		artifactRequestId *uuid.UUID
-		artifactKey       *string
-		artifactExpiresAt *time.Time
-	)
-
-	if err := r.tx.QueryRow(ctx, queryRequestsGetById, requestId).Scan(
-		&request.Id,
-		&request.UserId,
-		&request.Type,
-		&request.CreatedAt,
-		&request.GuildId,
-		&request.Status,
-		&artifactId,
-		&artifactRequestId,
-		&artifactKey,
-		&artifactExpiresAt,
-	); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
+	rows, err := r.tx.Query(ctx, queryRequestsGetById, requestId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var request *model.RequestWithArtifact
+	for rows.Next() {
+		var row model.Request
+		var artifact artifactColumns
+
+		if err := rows.Scan(
+			&row.Id,
+			&row.UserId,
+			&row.Type,
+			&row.CreatedAt,
+			&row.GuildId,
+			&row.Status,
+			&row.Options,
+			&artifact.id,
+			&artifact.requestId,
+			&artifact.key,
+			&artifact.expiresAt,
+			&artifact.part,
+			&artifact.size,
+			&artifact.format,
+		); err != nil {
+			return nil, err
+		}
+
+		if request == nil {
+			request = utils.Ptr(model.NewRequestWithArtifact(row, nil))
 		}
 
+		if artifact, ok := artifact.toArtifact(); ok {
+			request.Artifacts = append(request.Artifacts, artifact)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	var artifact *model.Artifact
-	if artifactId != nil {
-		artifact = &model.Artifact{
-			Id:        *artifactId,
-			RequestId: *artifactRequestId,
-			Key:       *artifactKey,
-			ExpiresAt: *artifactExpiresAt,
-		}
+	return request, nil
+}
+
+// artifactColumns holds the artifact columns of a request row, which are null if the request has no artifact.
+type artifactColumns struct {
+	id        *uuid.UUID
+	requestId *uuid.UUID
+	key       *string
+	expiresAt *time.Time
+	part      *int
+	size      *int64
+	format    *model.ArchiveFormat
+}
+
+func (c artifactColumns) toArtifact() (model.Artifact, bool) {
+	if c.id == nil {
+		return model.Artifact{}, false
 	}
 
-	return utils.Ptr(model.NewRequestWithArtifact(request, artifact)), nil
+	return model.Artifact{
+		Id:        *c.id,
+		RequestId: *c.requestId,
+		Key:       *c.key,
+		ExpiresAt: *c.expiresAt,
+		Part:      *c.part,
+		Size:      *c.size,
+		Format:    *c.format,
+	}, true
 }
 
 func (r *RequestRepository) SetStatus(ctx context.Context, requestId uuid.UUID, status model.RequestStatus) error {
@@ -155,7 +206,119 @@ func (r *RequestRepository) SetStatus(ctx context.Context, requestId uuid.UUID,
 	return err
 }
 
+// TransitionStatus sets the status of the request to the given status, only if its status is currently from. It
+// returns whether the status was changed.
+func (r *RequestRepository) TransitionStatus(
+	ctx context.Context,
+	requestId uuid.UUID,
+	from, to model.RequestStatus,
+) (bool, error) {
+	res, err := r.tx.Exec(ctx, queryRequestsTransitionStatus, to, requestId, from)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
+
+// DeleteOld deletes requests older than the threshold. Completed erasures are kept as a record of the erasure, as are
+// erasures that have started deleting data but are yet to finish.
 func (r *RequestRepository) DeleteOld(ctx context.Context, threshold time.Duration) (int64, error) {
 	res, err := r.tx.Exec(ctx, queryRequestsDeleteOld, threshold)
 	return res.RowsAffected(), err
 }
+
+// ListFiltered returns requests matching the filter, most recent first, including their failure reasons.
+func (r *RequestRepository) ListFiltered(ctx context.Context, filter model.RequestFilter) ([]model.RequestDetails, error) {
+	rows, err := r.tx.Query(ctx, queryRequestsListFiltered,
+		filter.UserId, filter.GuildId, filter.Type, filter.Status, filter.Before, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	requests := make([]model.RequestDetails, 0)
+	for rows.Next() {
+		var request model.Request
+		var failureReason *string
+		var artifact artifactColumns
+
+		if err := rows.Scan(
+			&request.Id,
+			&request.UserId,
+			&request.Type,
+			&request.CreatedAt,
+			&request.GuildId,
+			&request.Status,
+			&request.Options,
+			&failureReason,
+			&artifact.id,
+			&artifact.requestId,
+			&artifact.key,
+			&artifact.expiresAt,
+			&artifact.part,
+			&artifact.size,
+			&artifact.format,
+		); err != nil {
+			return nil, err
+		}
+
+		if len(requests) == 0 || requests[len(requests)-1].Request.Id != request.Id {
+			requests = append(requests, model.RequestDetails{
+				Request:       request,
+				FailureReason: failureReason,
+			})
+		}
+
+		if artifact, ok := artifact.toArtifact(); ok {
+			last := &requests[len(requests)-1]
+			last.Artifacts = append(last.Artifacts, artifact)
+		}
+	}
+
+	return requests, rows.Err()
+}
+
+// SetFailureReason records why a request failed, for operators. Pass nil to clear it when a request is requeued.
+func (r *RequestRepository) SetFailureReason(ctx context.Context, requestId uuid.UUID, reason *string) error {
+	_, err := r.tx.Exec(ctx, queryRequestsSetFailureReason, reason, requestId)
+	return err
+}
+
+// CountByStatus returns the number of requests of each type and status created within the period.
+func (r *RequestRepository) CountByStatus(ctx context.Context, period time.Duration) ([]model.RequestCount, error) {
+	rows, err := r.tx.Query(ctx, queryRequestsCountByStatus, period)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := make([]model.RequestCount, 0)
+	for rows.Next() {
+		var count model.RequestCount
+		if err := rows.Scan(&count.Type, &count.Status, &count.Count); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetErasureProgress returns what an erasure has deleted so far, or nil if it has not yet deleted anything.
+func (r *RequestRepository) GetErasureProgress(ctx context.Context, requestId uuid.UUID) (*model.ErasureProgress, error) {
+	var progress *model.ErasureProgress
+	if err := r.tx.QueryRow(ctx, queryRequestsGetErasureProgress, requestId).Scan(&progress); err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+func (r *RequestRepository) SetErasureProgress(ctx context.Context, requestId uuid.UUID, progress model.ErasureProgress) error {
+	_, err := r.tx.Exec(ctx, queryRequestsSetErasureProgress, progress, requestId)
+	return err
+}
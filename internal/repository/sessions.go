@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type SessionRepository struct {
+	tx pgx.Tx
+}
+
+var (
+	//go:embed sql/sessions/create.sql
+	querySessionsCreate string
+
+	//go:embed sql/sessions/get_by_id.sql
+	querySessionsGetById string
+
+	//go:embed sql/sessions/list_for_user.sql
+	querySessionsListForUser string
+
+	//go:embed sql/sessions/list_revoked.sql
+	querySessionsListRevoked string
+
+	//go:embed sql/sessions/revoke.sql
+	querySessionsRevoke string
+
+	//go:embed sql/sessions/revoke_all_for_user.sql
+	querySessionsRevokeAllForUser string
+
+	//go:embed sql/sessions/delete_expired.sql
+	querySessionsDeleteExpired string
+)
+
+func NewSessionRepository(tx pgx.Tx) *SessionRepository {
+	return &SessionRepository{
+		tx: tx,
+	}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, userId uint64, expiresAt time.Time) (model.Session, error) {
+	session := model.Session{
+		UserId:    userId,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := r.tx.QueryRow(ctx, querySessionsCreate, userId, expiresAt).Scan(&session.Id, &session.CreatedAt); err != nil {
+		return model.Session{}, err
+	}
+
+	return session, nil
+}
+
+func (r *SessionRepository) GetById(ctx context.Context, sessionId uuid.UUID) (*model.Session, error) {
+	session, err := scanSession(r.tx.QueryRow(ctx, querySessionsGetById, sessionId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListForUser returns the user's sessions that have not yet expired, including revoked sessions.
+func (r *SessionRepository) ListForUser(ctx context.Context, userId uint64) ([]model.Session, error) {
+	return r.list(ctx, querySessionsListForUser, userId)
+}
+
+// ListRevoked returns every revoked session that would otherwise still be valid.
+func (r *SessionRepository) ListRevoked(ctx context.Context) ([]model.Session, error) {
+	return r.list(ctx, querySessionsListRevoked)
+}
+
+// Revoke revokes a single session. revokedBy is the user who revoked it, which is the session owner when logging out.
+func (r *SessionRepository) Revoke(ctx context.Context, sessionId uuid.UUID, revokedBy uint64, reason *string) (bool, error) {
+	res, err := r.tx.Exec(ctx, querySessionsRevoke, sessionId, revokedBy, reason)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
+
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userId, revokedBy uint64, reason *string) (int64, error) {
+	res, err := r.tx.Exec(ctx, querySessionsRevokeAllForUser, userId, revokedBy, reason)
+	return res.RowsAffected(), err
+}
+
+func (r *SessionRepository) DeleteExpired(ctx context.Context, threshold time.Duration) (int64, error) {
+	res, err := r.tx.Exec(ctx, querySessionsDeleteExpired, threshold)
+	return res.RowsAffected(), err
+}
+
+func (r *SessionRepository) list(ctx context.Context, query string, args ...any) ([]model.Session, error) {
+	rows, err := r.tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sessions := make([]model.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func scanSession(row pgx.Row) (model.Session, error) {
+	var session model.Session
+	err := row.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.RevokedBy,
+		&session.RevokedReason,
+	)
+
+	return session, err
+}
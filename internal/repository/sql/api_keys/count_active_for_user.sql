@@ -0,0 +1,3 @@
+SELECT COUNT(*)
+FROM api_keys
+WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW());
\ No newline at end of file
@@ -0,0 +1,3 @@
+INSERT INTO api_keys (user_id, name, key_hash, scopes, guild_ids, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at;
\ No newline at end of file
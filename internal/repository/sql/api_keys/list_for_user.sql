@@ -0,0 +1,4 @@
+SELECT id, user_id, name, scopes, guild_ids, created_at, expires_at, last_used_at, revoked_at
+FROM api_keys
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
\ No newline at end of file
@@ -0,0 +1,4 @@
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+RETURNING id, user_id, name, scopes, guild_ids, created_at, expires_at, last_used_at, revoked_at;
\ No newline at end of file
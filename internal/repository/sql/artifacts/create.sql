@@ -1,2 +1,2 @@
-INSERT INTO artifacts (request_id, key, expires_at, size)
-VALUES ($1, $2, $3, $4);
\ No newline at end of file
+INSERT INTO artifacts (request_id, part, key, expires_at, size, format)
+VALUES ($1, $2, $3, $4, $5, $6);
\ No newline at end of file
@@ -0,0 +1,3 @@
+UPDATE artifacts
+SET expires_at = NOW()
+WHERE request_id = $1 AND expires_at > NOW();
\ No newline at end of file
@@ -1,3 +1,4 @@
-SELECT id, request_id, key, expires_at
+SELECT id, request_id, key, expires_at, part, size, format
 FROM artifacts
-WHERE request_id = $1;
\ No newline at end of file
+WHERE request_id = $1
+ORDER BY part;
\ No newline at end of file
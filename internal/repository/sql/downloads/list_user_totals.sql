@@ -0,0 +1,8 @@
+SELECT downloads.user_id, COUNT(*), COALESCE(SUM(artifacts.size), 0)
+FROM downloads
+INNER JOIN artifacts ON artifacts.id = downloads.artifact_id
+WHERE downloads.download_time > NOW() - $1::INTERVAL
+  AND ($2::int8 IS NULL OR downloads.user_id = $2)
+GROUP BY downloads.user_id
+ORDER BY 3 DESC
+LIMIT $3;
\ No newline at end of file
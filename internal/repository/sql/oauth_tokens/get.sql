@@ -0,0 +1,3 @@
+SELECT user_id, access_token, refresh_token, expires_at
+FROM oauth_tokens
+WHERE user_id = $1;
\ No newline at end of file
@@ -0,0 +1,7 @@
+INSERT INTO oauth_tokens (user_id, access_token, refresh_token, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE SET
+    access_token = EXCLUDED.access_token,
+    refresh_token = EXCLUDED.refresh_token,
+    expires_at = EXCLUDED.expires_at,
+    updated_at = NOW();
\ No newline at end of file
@@ -0,0 +1,5 @@
+SELECT request_type, status, COUNT(*)
+FROM requests
+WHERE created_at > NOW() - $1::INTERVAL
+GROUP BY request_type, status
+ORDER BY request_type, status;
\ No newline at end of file
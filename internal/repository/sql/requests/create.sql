@@ -1,3 +1,3 @@
-INSERT INTO requests (user_id, request_type, guild_id)
-VALUES ($1, $2, $3)
+INSERT INTO requests (user_id, request_type, guild_id, status, options)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id, created_at;
\ No newline at end of file
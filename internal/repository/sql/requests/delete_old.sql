@@ -1,2 +1,3 @@
 DELETE FROM requests
-WHERE created_at < NOW() - $1::INTERVAL;
\ No newline at end of file
+WHERE created_at < NOW() - $1::INTERVAL
+  AND NOT (request_type IN ('guild_erasure', 'user_erasure') AND (status = 'completed' OR erasure_progress IS NOT NULL));
\ No newline at end of file
@@ -0,0 +1,3 @@
+SELECT erasure_progress
+FROM requests
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,17 @@
+SELECT
+    requests.id, requests.user_id, requests.request_type, requests.created_at, requests.guild_id, requests.status,
+    requests.options, requests.failure_reason,
+    artifacts.id, artifacts.request_id, artifacts.key, artifacts.expires_at, artifacts.part, artifacts.size, artifacts.format
+FROM (
+    SELECT *
+    FROM requests
+    WHERE ($1::int8 IS NULL OR requests.user_id = $1)
+      AND ($2::int8 IS NULL OR requests.guild_id = $2)
+      AND ($3::request_type IS NULL OR requests.request_type = $3)
+      AND ($4::request_status IS NULL OR requests.status = $4)
+      AND ($5::TIMESTAMPTZ IS NULL OR requests.created_at < $5)
+    ORDER BY requests.created_at DESC
+    LIMIT $6
+) AS requests
+LEFT OUTER JOIN artifacts ON requests.id = artifacts.request_id
+ORDER BY requests.created_at DESC, requests.id, artifacts.part;
\ No newline at end of file
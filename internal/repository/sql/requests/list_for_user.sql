@@ -1,7 +1,8 @@
 SELECT
     requests.id, requests.user_id, requests.request_type, requests.created_at, requests.guild_id, requests.status,
-    artifacts.id, artifacts.request_id, artifacts.key, artifacts.expires_at
+    requests.options,
+    artifacts.id, artifacts.request_id, artifacts.key, artifacts.expires_at, artifacts.part, artifacts.size, artifacts.format
 FROM requests
 LEFT OUTER JOIN artifacts ON requests.id = artifacts.request_id
 WHERE requests.user_id = $1
-ORDER BY requests.created_at DESC;
\ No newline at end of file
+ORDER BY requests.created_at DESC, requests.id, artifacts.part;
\ No newline at end of file
@@ -0,0 +1,3 @@
+UPDATE requests
+SET erasure_progress = $1
+WHERE id = $2;
\ No newline at end of file
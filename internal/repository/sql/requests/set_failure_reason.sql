@@ -0,0 +1,3 @@
+UPDATE requests
+SET failure_reason = $1
+WHERE id = $2;
\ No newline at end of file
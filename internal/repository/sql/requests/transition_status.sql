@@ -0,0 +1,3 @@
+UPDATE requests
+SET status = $1
+WHERE id = $2 AND status = $3;
\ No newline at end of file
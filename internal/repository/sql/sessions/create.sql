@@ -0,0 +1,3 @@
+INSERT INTO sessions (user_id, expires_at)
+VALUES ($1, $2)
+RETURNING id, created_at;
\ No newline at end of file
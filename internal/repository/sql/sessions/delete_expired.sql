@@ -0,0 +1,2 @@
+DELETE FROM sessions
+WHERE expires_at < NOW() - $1::INTERVAL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+SELECT id, user_id, created_at, expires_at, revoked_at, revoked_by, revoked_reason
+FROM sessions
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,4 @@
+SELECT id, user_id, created_at, expires_at, revoked_at, revoked_by, revoked_reason
+FROM sessions
+WHERE revoked_at IS NOT NULL AND expires_at > NOW()
+ORDER BY revoked_at DESC;
\ No newline at end of file
@@ -0,0 +1,3 @@
+UPDATE sessions
+SET revoked_at = NOW(), revoked_by = $2, revoked_reason = $3
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW();
\ No newline at end of file
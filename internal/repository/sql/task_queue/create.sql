@@ -1,3 +1,3 @@
-INSERT INTO task_queue (request_id)
-VALUES ($1)
+INSERT INTO task_queue (request_id, run_after)
+VALUES ($1, $2)
 RETURNING "id";
\ No newline at end of file
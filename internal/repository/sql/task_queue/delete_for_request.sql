@@ -0,0 +1,2 @@
+DELETE FROM task_queue
+WHERE request_id = $1;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DELETE FROM task_queue
+WHERE request_id = $1 AND run_after > NOW()
+  AND NOT EXISTS (SELECT 1 FROM requests WHERE id = $1 AND erasure_progress IS NOT NULL);
\ No newline at end of file
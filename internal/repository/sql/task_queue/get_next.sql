@@ -1,6 +1,6 @@
-SELECT task_queue.id, task_queue.request_id, requests.id, requests.user_id, requests.request_type, requests.created_at, requests.guild_id, requests.status
+SELECT task_queue.id, task_queue.request_id, task_queue.run_after, requests.id, requests.user_id, requests.request_type, requests.created_at, requests.guild_id, requests.status, requests.options
 FROM task_queue
 INNER JOIN requests ON task_queue.request_id = requests.id
-WHERE requests.status = 'queued'
+WHERE requests.status = 'queued' AND task_queue.run_after <= NOW()
 ORDER BY requests.created_at ASC
 LIMIT 1
\ No newline at end of file
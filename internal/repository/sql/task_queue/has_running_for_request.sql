@@ -0,0 +1,5 @@
+SELECT EXISTS(
+    SELECT 1
+    FROM task_queue
+    WHERE request_id = $1 AND started_at > NOW() - $2::INTERVAL
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+UPDATE task_queue
+SET started_at = NOW()
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,7 @@
+SELECT
+    COUNT(*) FILTER (WHERE task_queue.run_after <= NOW()),
+    COUNT(*) FILTER (WHERE task_queue.run_after > NOW()),
+    MIN(requests.created_at) FILTER (WHERE task_queue.run_after <= NOW())
+FROM task_queue
+INNER JOIN requests ON task_queue.request_id = requests.id
+WHERE requests.status = 'queued';
\ No newline at end of file
@@ -8,6 +8,7 @@ import (
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 type TaskRepository struct {
@@ -23,6 +24,24 @@ var (
 
 	//go:embed sql/task_queue/delete.sql
 	queryTaskQueueDelete string
+
+	//go:embed sql/task_queue/delete_pending_for_request.sql
+	queryTaskQueueDeletePendingForRequest string
+
+	//go:embed sql/task_queue/list.sql
+	queryTaskQueueList string
+
+	//go:embed sql/task_queue/delete_for_request.sql
+	queryTaskQueueDeleteForRequest string
+
+	//go:embed sql/task_queue/stats.sql
+	queryTaskQueueStats string
+
+	//go:embed sql/task_queue/start.sql
+	queryTaskQueueStart string
+
+	//go:embed sql/task_queue/has_running_for_request.sql
+	queryTaskQueueHasRunningForRequest string
 )
 
 func NewTaskRepository(tx pgx.Tx) *TaskRepository {
@@ -31,9 +50,9 @@ func NewTaskRepository(tx pgx.Tx) *TaskRepository {
 	}
 }
 
-func (r *TaskRepository) Create(ctx context.Context, requestId uuid.UUID) (uuid.UUID, error) {
+func (r *TaskRepository) Create(ctx context.Context, requestId uuid.UUID, runAfter time.Time) (uuid.UUID, error) {
 	var taskId uuid.UUID
-	if err := r.tx.QueryRow(ctx, queryTaskQueueCreate, requestId).Scan(&taskId); err != nil {
+	if err := r.tx.QueryRow(ctx, queryTaskQueueCreate, requestId, runAfter).Scan(&taskId); err != nil {
 		return uuid.Nil, err
 	}
 
@@ -47,12 +66,14 @@ func (r *TaskRepository) GetNext(ctx context.Context) (*model.Union[model.Task,
 	if err := r.tx.QueryRow(ctx, queryTaskQueueGetNext).Scan(
 		&task.Id,
 		&task.RequestId,
+		&task.RunAfter,
 		&request.Id,
 		&request.UserId,
 		&request.Type,
 		&request.CreatedAt,
 		&request.GuildId,
 		&request.Status,
+		&request.Options,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -64,7 +85,81 @@ func (r *TaskRepository) GetNext(ctx context.Context) (*model.Union[model.Task,
 	return utils.Ptr(model.NewUnion(task, request)), nil
 }
 
+// Start records that a worker has started running the task.
+func (r *TaskRepository) Start(ctx context.Context, taskId uuid.UUID) error {
+	_, err := r.tx.Exec(ctx, queryTaskQueueStart, taskId)
+	return err
+}
+
+// HasRunningForRequest returns whether a worker is running a task for the request. A task started more than
+// model.TaskTimeout ago is assumed to have been abandoned, for example because the worker crashed.
+func (r *TaskRepository) HasRunningForRequest(ctx context.Context, requestId uuid.UUID) (bool, error) {
+	var running bool
+	if err := r.tx.QueryRow(ctx, queryTaskQueueHasRunningForRequest, requestId, model.TaskTimeout).Scan(&running); err != nil {
+		return false, err
+	}
+
+	return running, nil
+}
+
 func (r *TaskRepository) Delete(ctx context.Context, taskId uuid.UUID) error {
 	_, err := r.tx.Exec(ctx, queryTaskQueueDelete, taskId)
 	return err
 }
+
+// DeletePendingForRequest removes any tasks for the request that are not yet due to run, returning the number removed.
+// Tasks of erasures that have already started deleting data, and are waiting to be retried, are not removed.
+func (r *TaskRepository) DeletePendingForRequest(ctx context.Context, requestId uuid.UUID) (int64, error) {
+	res, err := r.tx.Exec(ctx, queryTaskQueueDeletePendingForRequest, requestId)
+	return res.RowsAffected(), err
+}
+
+// List returns tasks in the queue, including those not yet due to run, in the order they will run.
+func (r *TaskRepository) List(ctx context.Context, limit int) ([]model.Union[model.Task, model.Request], error) {
+	rows, err := r.tx.Query(ctx, queryTaskQueueList, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tasks := make([]model.Union[model.Task, model.Request], 0)
+	for rows.Next() {
+		var task model.Task
+		var request model.Request
+
+		if err := rows.Scan(
+			&task.Id,
+			&task.RequestId,
+			&task.RunAfter,
+			&request.Id,
+			&request.UserId,
+			&request.Type,
+			&request.CreatedAt,
+			&request.GuildId,
+			&request.Status,
+			&request.Options,
+		); err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, model.NewUnion(task, request))
+	}
+
+	return tasks, rows.Err()
+}
+
+// DeleteForRequest removes every task for the request, whether or not it is due to run.
+func (r *TaskRepository) DeleteForRequest(ctx context.Context, requestId uuid.UUID) (int64, error) {
+	res, err := r.tx.Exec(ctx, queryTaskQueueDeleteForRequest, requestId)
+	return res.RowsAffected(), err
+}
+
+func (r *TaskRepository) Stats(ctx context.Context) (model.QueueStats, error) {
+	var stats model.QueueStats
+	if err := r.tx.QueryRow(ctx, queryTaskQueueStats).Scan(&stats.Due, &stats.Waiting, &stats.OldestDue); err != nil {
+		return model.QueueStats{}, err
+	}
+
+	return stats, nil
+}
@@ -11,6 +11,9 @@ type TransactionContext interface {
 	Tasks() *TaskRepository
 	Artifacts() *ArtifactRepository
 	Downloads() *DownloadRepository
+	OAuthTokens() *OAuthTokenRepository
+	Sessions() *SessionRepository
+	ApiKeys() *ApiKeyRepository
 }
 
 type PostgresTransactionContext struct {
@@ -40,3 +43,15 @@ func (t *PostgresTransactionContext) Artifacts() *ArtifactRepository {
 func (t *PostgresTransactionContext) Downloads() *DownloadRepository {
 	return NewDownloadRepository(t.tx)
 }
+
+func (t *PostgresTransactionContext) OAuthTokens() *OAuthTokenRepository {
+	return NewOAuthTokenRepository(t.tx)
+}
+
+func (t *PostgresTransactionContext) Sessions() *SessionRepository {
+	return NewSessionRepository(t.tx)
+}
+
+func (t *PostgresTransactionContext) ApiKeys() *ApiKeyRepository {
+	return NewApiKeyRepository(t.tx)
+}
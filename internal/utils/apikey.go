@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/TicketsBot/export/internal/model"
+)
+
+// GenerateApiKey returns a new random API key. Only its hash should be stored.
+func GenerateApiKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return model.ApiKeyPrefix + Base64Encode(secret), nil
+}
+
+// HashApiKey returns the hash an API key is stored and looked up by. Keys are random and long, so a fast hash is
+// sufficient.
+func HashApiKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"io"
+)
+
+type ArchiveFile struct {
+	Name    string
+	Content []byte
+}
+
+// ArchiveWriter writes a single archive as files are added to it, keeping track of an upper bound on its size, so that
+// exports can be split into parts of a maximum size.
+type ArchiveWriter interface {
+	// AddIfFits adds the files to the archive, unless the archive already holds files and would then exceed maxSize
+	// bytes, and returns whether they were added. Files are always added to an empty archive.
+	AddIfFits(maxSize int64, files ...ArchiveFile) (bool, error)
+	Add(files ...ArchiveFile) error
+	// Suspend releases the memory held to compress files into the archive, so that several archives can be written at
+	// once, while only compressing one at a time. Files can still be added afterwards.
+	Suspend() error
+	// Close finishes writing the archive. No files can be added after the archive is closed.
+	Close() error
+}
+
+// NewArchiveWriter returns a writer for an archive in the given format, written to w. The compression level is a
+// DEFLATE level for zip and tar.gz archives, or a zstd level for tar.zst archives.
+func NewArchiveWriter(format model.ArchiveFormat, level int, w io.Writer) (ArchiveWriter, error) {
+	switch format {
+	case model.ArchiveFormatZip:
+		return newZipWriter(level, w), nil
+	case model.ArchiveFormatTarGz, model.ArchiveFormatTarZst:
+		return newTarWriter(format, level, w)
+	default:
+		return nil, fmt.Errorf("unknown archive format %s", format)
+	}
+}
+
+// CountingWriter counts the bytes written to W. If W is nil, they are discarded, so that the size of an archive can be
+// found without holding it in memory.
+type CountingWriter struct {
+	W io.Writer
+	N int64
+}
+
+func (w *CountingWriter) Write(p []byte) (int, error) {
+	if w.W == nil {
+		w.N += int64(len(p))
+		return len(p), nil
+	}
+
+	n, err := w.W.Write(p)
+	w.N += int64(n)
+	return n, err
+}
@@ -37,6 +37,11 @@ func LoadPublicKeyFromDisk(path string) (ed25519.PublicKey, error) {
 		return nil, err
 	}
 
+	return ParsePublicKey(raw)
+}
+
+// ParsePublicKey parses a PEM encoded Ed25519 public key, as served by the API and written by EncodePublicKey.
+func ParsePublicKey(raw []byte) (ed25519.PublicKey, error) {
 	block, _ := pem.Decode(raw)
 	if block == nil {
 		return nil, errors.New("failed to decode PEM block")
@@ -53,3 +58,23 @@ func LoadPublicKeyFromDisk(path string) (ed25519.PublicKey, error) {
 		return nil, errors.New("key is not an Ed25519 public key")
 	}
 }
+
+// EncodePrivateKey encodes the key in the PEM format read by LoadKeyFromDisk.
+func EncodePrivateKey(key ed25519.PrivateKey) ([]byte, error) {
+	marshalled, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: marshalled}), nil
+}
+
+// EncodePublicKey encodes the key in the PEM format read by LoadPublicKeyFromDisk.
+func EncodePublicKey(key ed25519.PublicKey) ([]byte, error) {
+	marshalled, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: marshalled}), nil
+}
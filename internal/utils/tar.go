@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"archive/tar"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"math"
+	"time"
+)
+
+// tarEntryOverhead is an upper bound on the bytes a tar entry takes up besides its data and its name, which is only
+// written again if it is too long for the header: its header, the padding after its data, and a PAX header along with
+// the padding after its records.
+const tarEntryOverhead = 5 * 512
+
+// tarEndOverhead is the size of the blocks marking the end of a tar archive.
+const tarEndOverhead = 2 * 512
+
+// compressionOverhead is an upper bound on the bytes that gzip or zstd adds to data that doesn't compress, besides a
+// fraction of a percent of its size.
+const compressionOverhead = 1024
+
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// tarWriter compresses files as they are added. Unlike zip, files are compressed together, so the compressed size of
+// a file isn't known until it has been written. Instead, the size of the archive is bounded by assuming that data
+// written since the compressor was last flushed doesn't compress at all, and the compressor is flushed when the bound
+// would exceed the maximum size.
+//
+// Suspending the writer closes the compressor, ending a gzip member or zstd frame, and a new one is started when files
+// are next added. Readers decompress consecutive members or frames as a single stream.
+type tarWriter struct {
+	out        *CountingWriter // Counts the compressed bytes written so far, which the size bound is based on
+	compressor flushWriteCloser
+	tar        *tar.Writer
+
+	format model.ArchiveFormat
+	level  int
+
+	modTime time.Time
+	files   int
+	pending int64 // Uncompressed bytes written since the compressor was last flushed
+}
+
+func newTarWriter(format model.ArchiveFormat, level int, out io.Writer) (*tarWriter, error) {
+	w := &tarWriter{
+		out:     &CountingWriter{W: out},
+		format:  format,
+		level:   level,
+		modTime: time.Now(),
+	}
+
+	if err := w.resume(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// resume starts compressing into a new gzip member or zstd frame.
+func (w *tarWriter) resume() error {
+	var err error
+	switch w.format {
+	case model.ArchiveFormatTarGz:
+		w.compressor, err = gzip.NewWriterLevel(w.out, w.level)
+	case model.ArchiveFormatTarZst:
+		w.compressor, err = zstd.NewWriter(w.out, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(w.level)))
+	default:
+		err = fmt.Errorf("%s is not a tar format", w.format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	w.tar = tar.NewWriter(w.compressor)
+	return nil
+}
+
+func (w *tarWriter) AddIfFits(maxSize int64, files ...ArchiveFile) (bool, error) {
+	if w.compressor == nil {
+		if err := w.resume(); err != nil {
+			return false, err
+		}
+	}
+
+	var size int64
+	for _, file := range files {
+		size += tarEntrySize(file)
+	}
+
+	if w.files > 0 && w.sizeBound(size) > maxSize && w.pending > 0 {
+		if err := w.flush(); err != nil {
+			return false, err
+		}
+	}
+
+	if w.files > 0 && w.sizeBound(size) > maxSize {
+		return false, nil
+	}
+
+	for _, file := range files {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.Name,
+			Size:     int64(len(file.Content)),
+			Mode:     0644,
+			ModTime:  w.modTime,
+		}
+
+		if err := w.tar.WriteHeader(header); err != nil {
+			return false, err
+		}
+
+		if _, err := w.tar.Write(file.Content); err != nil {
+			return false, err
+		}
+	}
+
+	w.files += len(files)
+	w.pending += size
+	return true, nil
+}
+
+func (w *tarWriter) Add(files ...ArchiveFile) error {
+	_, err := w.AddIfFits(math.MaxInt64, files...)
+	return err
+}
+
+// Suspend ends the current gzip member or zstd frame, without writing the blocks that mark the end of the archive.
+func (w *tarWriter) Suspend() error {
+	if w.compressor == nil {
+		return nil
+	}
+
+	if err := w.tar.Flush(); err != nil {
+		return err
+	}
+
+	if err := w.compressor.Close(); err != nil {
+		return err
+	}
+
+	w.compressor, w.tar = nil, nil
+	w.pending = 0
+	return nil
+}
+
+func (w *tarWriter) Close() error {
+	if w.compressor == nil {
+		if err := w.resume(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+
+	return w.compressor.Close()
+}
+
+// sizeBound returns an upper bound on the size of the archive, if it were closed after writing size more bytes.
+func (w *tarWriter) sizeBound(size int64) int64 {
+	uncompressed := w.pending + size + tarEndOverhead
+	return w.out.N + uncompressed + uncompressed/100 + compressionOverhead
+}
+
+func (w *tarWriter) flush() error {
+	if err := w.tar.Flush(); err != nil {
+		return err
+	}
+
+	if err := w.compressor.Flush(); err != nil {
+		return err
+	}
+
+	w.pending = 0
+	return nil
+}
+
+func tarEntrySize(file ArchiveFile) int64 {
+	// Data is padded to a multiple of the block size, which is included in the overhead
+	return int64(len(file.Content)) + int64(len(file.Name)) + tarEntryOverhead
+}
@@ -4,32 +4,119 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/flate"
-	"github.com/TicketsBot/export/internal/config"
+	"hash/crc32"
 	"io"
+	"math"
 )
 
-func BuildZip(cfg config.WorkerConfig, files map[string][]byte) ([]byte, error) {
-	var buf bytes.Buffer
+// zipEntryOverhead is an upper bound on the bytes a zip entry takes up besides its name and data: its local header,
+// data descriptor, central directory header and zip64 extra fields. The name is written twice, once in each header.
+const zipEntryOverhead = 128
+
+// zipEndOverhead is an upper bound on the size of the end of central directory records.
+const zipEndOverhead = 128
+
+// zipWriter deflates each file as it is added, so that the size it will take up in the archive is known before it is
+// written.
+type zipWriter struct {
+	level int
+	zip   *zip.Writer
+	files int
+	size  int64
+}
+
+type compressedFile struct {
+	header zip.FileHeader
+	data   []byte
+}
+
+func newZipWriter(level int, w io.Writer) *zipWriter {
+	return &zipWriter{
+		level: level,
+		zip:   zip.NewWriter(w),
+		size:  zipEndOverhead,
+	}
+}
+
+func (w *zipWriter) AddIfFits(maxSize int64, files ...ArchiveFile) (bool, error) {
+	compressed := make([]compressedFile, 0, len(files))
+
+	size := w.size
+	for _, file := range files {
+		c, err := compressFile(w.level, file)
+		if err != nil {
+			return false, err
+		}
+
+		compressed = append(compressed, c)
+		size += c.size()
+	}
+
+	if w.files > 0 && size > maxSize {
+		return false, nil
+	}
 
-	w := zip.NewWriter(&buf)
-	w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
-		return flate.NewWriter(out, cfg.Daemon.CompressionLevel)
-	})
+	for _, file := range compressed {
+		header := file.header
 
-	for name, content := range files {
-		f, err := w.Create(name)
+		f, err := w.zip.CreateRaw(&header)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 
-		if _, err := f.Write(content); err != nil {
-			return nil, err
+		if _, err := f.Write(file.data); err != nil {
+			return false, err
 		}
 	}
 
+	w.files += len(compressed)
+	w.size = size
+	return true, nil
+}
+
+func (w *zipWriter) Add(files ...ArchiveFile) error {
+	_, err := w.AddIfFits(math.MaxInt64, files...)
+	return err
+}
+
+// Suspend does nothing, as each file is compressed on its own when it is added.
+func (w *zipWriter) Suspend() error {
+	return nil
+}
+
+func (w *zipWriter) Close() error {
+	return w.zip.Close()
+}
+
+func compressFile(level int, file ArchiveFile) (compressedFile, error) {
+	var buf bytes.Buffer
+
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return compressedFile{}, err
+	}
+
+	if _, err := w.Write(file.Content); err != nil {
+		return compressedFile{}, err
+	}
+
 	if err := w.Close(); err != nil {
-		return nil, err
+		return compressedFile{}, err
 	}
 
-	return buf.Bytes(), nil
+	return compressedFile{
+		header: zip.FileHeader{
+			Name:               file.Name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE(file.Content),
+			CompressedSize64:   uint64(buf.Len()),
+			UncompressedSize64: uint64(len(file.Content)),
+		},
+		data: buf.Bytes(),
+	}, nil
+}
+
+// size returns an upper bound on the number of bytes that the file will take up in a zip.
+func (f compressedFile) size() int64 {
+	return int64(len(f.data)) + int64(2*len(f.header.Name)) + zipEntryOverhead
 }
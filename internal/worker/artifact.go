@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"github.com/TicketsBot/export/internal/metrics"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"log/slog"
+	"time"
+)
+
+// uploadArtifact builds the export's archive in the request's archive format, split into parts if it is too large,
+// and stores each part as its own artifact, before marking the request as completed. Parts are built in temporary
+// files, and stored one at a time, so only one is held in memory at once.
+func (d *Daemon) uploadArtifact(ctx context.Context, logger *slog.Logger, request model.Request, files map[string][]byte) error {
+	format := request.Options.ArchiveFormat()
+
+	plan, err := d.writeParts(request, files)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to build archive", "format", format, "error", err)
+		return err
+	}
+
+	defer plan.remove()
+
+	if err := d.finish(plan, plan.partsIndex(request, files)); err != nil {
+		logger.ErrorContext(ctx, "Failed to build archive", "format", format, "error", err)
+		return err
+	}
+
+	artifactSize := plan.size()
+
+	var globalArtifactSize int64
+	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		globalArtifactSize, err = tx.Artifacts().GetGlobalSize(ctx)
+		return err
+	}); err != nil {
+		logger.ErrorContext(ctx, "Failed to get global artifact size", "error", err)
+		return err
+	}
+
+	if globalArtifactSize+artifactSize > maxActiveSize {
+		logger.ErrorContext(ctx, "Artifact size exceeds maximum", slog.Int64("size", globalArtifactSize+artifactSize))
+		return fmt.Errorf("artifact size exceeds maximum")
+	}
+
+	logger.InfoContext(ctx, "Uploading artifact",
+		slog.Int64("size", artifactSize), slog.Int("parts", len(plan.parts)), slog.String("format", format.String()))
+
+	expiresAt := time.Now().Add(transcriptExpiry)
+
+	artifacts := make([]model.Artifact, 0, len(plan.parts))
+	for i := range plan.parts {
+		artifact, err := plan.read(i)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to read archive", "part", i+1, "error", err)
+			d.deleteParts(ctx, logger, artifacts)
+			return err
+		}
+
+		key := utils.RandomString(32)
+		if err := d.artifacts.Store(ctx, request.Id, key, expiresAt, artifact); err != nil {
+			logger.ErrorContext(ctx, "Failed to store artifact", "part", i+1, "error", err)
+			d.deleteParts(ctx, logger, artifacts)
+			return err
+		}
+
+		artifacts = append(artifacts, model.Artifact{
+			RequestId: request.Id,
+			Key:       key,
+			ExpiresAt: expiresAt,
+			Part:      i + 1,
+			Size:      int64(len(artifact)),
+			Format:    format,
+		})
+
+		metrics.ArtifactsUploadedBytes.WithLabelValues(request.Type.String()).Add(float64(len(artifact)))
+	}
+
+	metrics.ArtifactsUploaded.WithLabelValues(request.Type.String()).Inc()
+
+	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+		if err := tx.Requests().SetStatus(ctx, request.Id, model.RequestStatusCompleted); err != nil {
+			return err
+		}
+
+		for _, artifact := range artifacts {
+			if err := tx.Artifacts().Create(ctx, request.Id, artifact.Part, artifact.Key, expiresAt, artifact.Size, format); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		logger.ErrorContext(ctx, "Failed to update request status", "error", err)
+		d.deleteParts(ctx, logger, artifacts)
+		return err
+	}
+
+	return nil
+}
+
+// deleteParts removes stored parts that were never recorded against the request, because a later part or recording
+// them failed, as they can never be downloaded.
+func (d *Daemon) deleteParts(ctx context.Context, logger *slog.Logger, artifacts []model.Artifact) {
+	for _, artifact := range artifacts {
+		if err := d.artifacts.Delete(ctx, artifact.RequestId, artifact.Key); err != nil {
+			logger.WarnContext(ctx, "Failed to delete artifact part", "part", artifact.Part, "error", err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type csvTable struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// buildGuildDataCsv flattens the collections in GuildData into one CSV file per collection, for use in spreadsheet
+// software. data.json remains the complete export; settings and other single values are not included.
+func buildGuildDataCsv(data dto.GuildData) (map[string][]byte, error) {
+	tables := []csvTable{
+		ticketsCsv(data),
+		ticketUnionCsv("ticket_claims", []string{"user_id"}, data.TicketClaims, func(userId uint64) []string {
+			return []string{formatUint(userId)}
+		}),
+		ticketUnionCsv("service_ratings", []string{"rating"}, data.ServiceRatings, func(rating int16) []string {
+			return []string{strconv.Itoa(int(rating))}
+		}),
+		ticketUnionCsv("close_reasons", []string{"reason", "closed_by"}, data.CloseReasons, func(reason dto.CloseMetadata) []string {
+			return []string{formatOptional(reason.Reason), formatOptional(reason.ClosedBy)}
+		}),
+		ticketUnionCsv("exit_survey_responses", []string{"form_id", "question_id", "response"}, data.ExitSurveyResponses, func(res dto.ExitSurveyResponse) []string {
+			return []string{formatOptional(res.FormId), formatOptional(res.QuestionId), formatOptional(res.Response)}
+		}),
+		ticketUnionCsv("ticket_last_messages", []string{"last_message_id", "last_message_time", "user_id", "user_is_staff"}, data.TicketLastMessages, func(msg dto.TicketLastMessage) []string {
+			return []string{formatOptional(msg.LastMessageId), formatOptionalTime(msg.LastMessageTime), formatOptional(msg.UserId), formatOptional(msg.UserIsStaff)}
+		}),
+		firstResponseTimesCsv(data),
+		idSnowflakesCsv("participants", "ticket_id", "user_id", data.Participants),
+		idSnowflakesCsv("ticket_additional_members", "ticket_id", "user_id", data.TicketAdditionalMembers),
+		panelsCsv(data),
+		supportTeamsCsv(data),
+		idSnowflakesCsv("support_team_users", "team_id", "user_id", data.SupportTeamUsers),
+		idSnowflakesCsv("support_team_roles", "team_id", "role_id", data.SupportTeamRoles),
+		permissionsCsv("user_permissions", data.UserPermissions),
+		permissionsCsv("role_permissions", data.RolePermissions),
+		snowflakesCsv("guild_blacklisted_users", "user_id", data.GuildBlacklistedUsers),
+		snowflakesCsv("guild_blacklisted_roles", "role_id", data.GuildBlacklistedRoles),
+	}
+
+	files := make(map[string][]byte, len(tables))
+	for _, table := range tables {
+		var buf bytes.Buffer
+
+		w := csv.NewWriter(&buf)
+		if err := w.Write(table.Header); err != nil {
+			return nil, err
+		}
+
+		for _, row := range table.Rows {
+			for i, cell := range row {
+				row[i] = escapeCsvFormula(cell)
+			}
+		}
+
+		if err := w.WriteAll(table.Rows); err != nil {
+			return nil, err
+		}
+
+		files[fmt.Sprintf("csv/%s.csv", table.Name)] = buf.Bytes()
+	}
+
+	return files, nil
+}
+
+// csvNumberRegex matches plain decimal numbers, which spreadsheet software reads as numbers rather than formulas, even
+// when they start with a sign.
+var csvNumberRegex = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// escapeCsvFormula prefixes cells that spreadsheet software would interpret as a formula with a single quote, so that
+// user-controlled text, such as panel titles and close reasons, is always displayed as text. Numbers, such as negative
+// colours, are left as they are, so that they can still be used as numbers.
+func escapeCsvFormula(cell string) string {
+	if len(cell) > 0 && strings.ContainsRune("=+-@\t\r", rune(cell[0])) && !csvNumberRegex.MatchString(cell) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+func ticketsCsv(data dto.GuildData) csvTable {
+	table := csvTable{
+		Name:   "tickets",
+		Header: []string{"id", "user_id", "channel_id", "panel_id", "open", "open_time", "close_time", "is_thread", "has_transcript", "status"},
+		Rows:   make([][]string, 0, len(data.Tickets)),
+	}
+
+	for _, ticket := range data.Tickets {
+		table.Rows = append(table.Rows, []string{
+			strconv.Itoa(ticket.Id),
+			formatUint(ticket.UserId),
+			formatOptional(ticket.ChannelId),
+			formatOptional(ticket.PanelId),
+			strconv.FormatBool(ticket.Open),
+			formatTime(ticket.OpenTime),
+			formatOptionalTime(ticket.CloseTime),
+			strconv.FormatBool(ticket.IsThread),
+			strconv.FormatBool(ticket.HasTranscript),
+			string(ticket.Status),
+		})
+	}
+
+	return table
+}
+
+func firstResponseTimesCsv(data dto.GuildData) csvTable {
+	table := csvTable{
+		Name:   "first_response_times",
+		Header: []string{"ticket_id", "user_id", "response_time_seconds"},
+		Rows:   make([][]string, 0, len(data.FirstResponseTimes)),
+	}
+
+	for _, frt := range data.FirstResponseTimes {
+		table.Rows = append(table.Rows, []string{
+			strconv.Itoa(frt.TicketId),
+			formatUint(frt.UserId),
+			strconv.FormatFloat(frt.ResponseTime.Seconds(), 'f', -1, 64),
+		})
+	}
+
+	return table
+}
+
+func panelsCsv(data dto.GuildData) csvTable {
+	table := csvTable{
+		Name:   "panels",
+		Header: []string{"panel_id", "title", "channel_id", "category_id", "form_id", "exit_survey_form_id", "default_team", "disabled"},
+		Rows:   make([][]string, 0, len(data.Panels)),
+	}
+
+	for _, panel := range data.Panels {
+		table.Rows = append(table.Rows, []string{
+			strconv.Itoa(panel.PanelId),
+			panel.Title,
+			formatUint(panel.ChannelId),
+			formatUint(panel.TargetCategory),
+			formatOptional(panel.FormId),
+			formatOptional(panel.ExitSurveyFormId),
+			strconv.FormatBool(panel.WithDefaultTeam),
+			strconv.FormatBool(panel.Disabled || panel.ForceDisabled),
+		})
+	}
+
+	return table
+}
+
+func supportTeamsCsv(data dto.GuildData) csvTable {
+	table := csvTable{
+		Name:   "support_teams",
+		Header: []string{"id", "name", "on_call_role_id"},
+		Rows:   make([][]string, 0, len(data.SupportTeams)),
+	}
+
+	for _, team := range data.SupportTeams {
+		table.Rows = append(table.Rows, []string{
+			strconv.Itoa(team.Id),
+			team.Name,
+			formatOptional(team.OnCallRole),
+		})
+	}
+
+	return table
+}
+
+func permissionsCsv(name string, permissions []dto.Permission) csvTable {
+	table := csvTable{
+		Name:   name,
+		Header: []string{"snowflake", "is_admin", "is_support"},
+		Rows:   make([][]string, 0, len(permissions)),
+	}
+
+	for _, permission := range permissions {
+		table.Rows = append(table.Rows, []string{
+			formatUint(permission.Snowflake),
+			strconv.FormatBool(permission.IsAdmin),
+			strconv.FormatBool(permission.IsSupport),
+		})
+	}
+
+	return table
+}
+
+func snowflakesCsv(name, column string, snowflakes []uint64) csvTable {
+	table := csvTable{
+		Name:   name,
+		Header: []string{column},
+		Rows:   make([][]string, 0, len(snowflakes)),
+	}
+
+	for _, snowflake := range snowflakes {
+		table.Rows = append(table.Rows, []string{formatUint(snowflake)})
+	}
+
+	return table
+}
+
+// idSnowflakesCsv flattens a map of ID -> user or role IDs into one row per pair, ordered by ID.
+func idSnowflakesCsv(name, idColumn, snowflakeColumn string, m map[int][]uint64) csvTable {
+	table := csvTable{
+		Name:   name,
+		Header: []string{idColumn, snowflakeColumn},
+		Rows:   make([][]string, 0, len(m)),
+	}
+
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		for _, snowflake := range m[id] {
+			table.Rows = append(table.Rows, []string{strconv.Itoa(id), formatUint(snowflake)})
+		}
+	}
+
+	return table
+}
+
+func ticketUnionCsv[T any](name string, columns []string, data []dto.TicketUnion[T], f func(T) []string) csvTable {
+	table := csvTable{
+		Name:   name,
+		Header: append([]string{"ticket_id"}, columns...),
+		Rows:   make([][]string, 0, len(data)),
+	}
+
+	for _, union := range data {
+		table.Rows = append(table.Rows, append([]string{strconv.Itoa(union.TicketId)}, f(union.Data)...))
+	}
+
+	return table
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return formatTime(*t)
+}
+
+func formatOptional[T any](v *T) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(*v)
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"github.com/TicketsBot/database"
 	"github.com/TicketsBot/export/internal/artifactstore"
@@ -10,7 +11,9 @@ import (
 	"github.com/TicketsBot/export/internal/metrics"
 	"github.com/TicketsBot/export/internal/model"
 	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
 	"github.com/TicketsBot/export/internal/worker/transcriptstore"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"log/slog"
 	"time"
 )
@@ -23,6 +26,7 @@ type Daemon struct {
 	transcripts transcriptstore.Client
 	artifacts   artifactstore.ArtifactStore
 	database    *database.Database
+	pool        *pgxpool.Pool
 
 	shutdownCh chan struct{}
 }
@@ -34,7 +38,7 @@ func NewDaemon(
 	repository *repository.Repository,
 	transcripts transcriptstore.Client,
 	artifacts artifactstore.ArtifactStore,
-	database *database.Database,
+	pool *pgxpool.Pool,
 ) *Daemon {
 	return &Daemon{
 		logger:      logger,
@@ -43,7 +47,8 @@ func NewDaemon(
 		repository:  repository,
 		transcripts: transcripts,
 		artifacts:   artifacts,
-		database:    database,
+		database:    database.NewDatabase(pool),
+		pool:        pool,
 		shutdownCh:  make(chan struct{}),
 	}
 }
@@ -65,7 +70,7 @@ func (d *Daemon) Start() {
 		case <-ticker.C:
 			task, err := d.getNextTask(context.Background())
 			if err != nil {
-				d.logger.Error("Failed to get next task", err)
+				d.logger.Error("Failed to get next task", "error", err)
 				ticker.Reset(d.config.Daemon.Interval)
 				continue
 			}
@@ -75,28 +80,14 @@ func (d *Daemon) Start() {
 				continue
 			}
 
-			var status model.RequestStatus
-			ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
-			switch d.handleNext(ctx, task) {
-			case nil:
-				d.logger.Info("Task handled successfully", slog.String("request_id", task.First.RequestId.String()))
-				status = model.RequestStatusCompleted
-			default:
-				d.logger.Error("Task failed", slog.String("request_id", task.First.RequestId.String()))
-				status = model.RequestStatusFailed
-			}
+			ctx, cancel := context.WithTimeout(context.Background(), model.TaskTimeout)
+			err = d.handleNext(ctx, task)
 			cancel()
 
-			metrics.RequestsProcessed.WithLabelValues(task.Second.Type.String(), status.String()).Inc()
-
-			if err := d.repository.Tx(context.Background(), func(ctx context.Context, tx repository.TransactionContext) error {
-				if err := tx.Requests().SetStatus(ctx, task.First.RequestId, status); err != nil {
-					return err
-				}
-
+			if err := d.recordResult(context.Background(), task.Second, err, func(ctx context.Context, tx repository.TransactionContext) error {
 				return tx.Tasks().Delete(ctx, task.First.Id)
 			}); err != nil {
-				d.logger.Error("Failed to update task status", err)
+				d.logger.Error("Failed to update task status", "error", err)
 			}
 
 			ticker.Reset(d.config.Daemon.Interval)
@@ -108,6 +99,102 @@ func (d *Daemon) Shutdown() {
 	close(d.shutdownCh)
 }
 
+// RunRequest runs a request immediately in the current process, regardless of its status or position in the queue,
+// and records the result as the daemon would. Any tasks for the request are removed, so that it is not run again. It
+// is intended for debugging.
+func (d *Daemon) RunRequest(ctx context.Context, request model.Request) error {
+	task := model.Task{
+		RequestId: request.Id,
+		RunAfter:  time.Now(),
+	}
+
+	err := d.handleNext(ctx, utils.Ptr(model.NewUnion(task, request)))
+
+	if recordErr := d.recordResult(context.Background(), request, err, func(ctx context.Context, tx repository.TransactionContext) error {
+		_, err := tx.Tasks().DeleteForRequest(ctx, request.Id)
+		return err
+	}); recordErr != nil {
+		return fmt.Errorf("failed to record result: %w", recordErr)
+	}
+
+	return err
+}
+
+// retryableError is returned by a task that has failed part way through, and must be run again to finish, rather
+// than being marked as failed.
+type retryableError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// recordResult sets the status of the request after its task has run, and removes the task using deleteTask. If the
+// task returned a retryableError, the request is instead queued again to run after the error's delay.
+func (d *Daemon) recordResult(
+	ctx context.Context,
+	request model.Request,
+	taskErr error,
+	deleteTask func(ctx context.Context, tx repository.TransactionContext) error,
+) error {
+	var retryErr *retryableError
+	if errors.As(taskErr, &retryErr) {
+		d.logger.Warn("Task failed, retrying", slog.String("request_id", request.Id.String()),
+			slog.Duration("delay", retryErr.delay), "error", taskErr)
+
+		return d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+			if err := tx.Requests().SetStatus(ctx, request.Id, model.RequestStatusQueued); err != nil {
+				return err
+			}
+
+			if err := tx.Requests().SetFailureReason(ctx, request.Id, utils.Ptr(taskErr.Error())); err != nil {
+				return err
+			}
+
+			if _, err := tx.Tasks().DeleteForRequest(ctx, request.Id); err != nil {
+				return err
+			}
+
+			_, err := tx.Tasks().Create(ctx, request.Id, time.Now().Add(retryErr.delay))
+			return err
+		})
+	}
+
+	var status model.RequestStatus
+	var failureReason *string
+	if taskErr == nil {
+		d.logger.Info("Task handled successfully", slog.String("request_id", request.Id.String()))
+		status = model.RequestStatusCompleted
+	} else {
+		d.logger.Error("Task failed", slog.String("request_id", request.Id.String()), "error", taskErr)
+		status = model.RequestStatusFailed
+		failureReason = utils.Ptr(taskErr.Error())
+	}
+
+	metrics.RequestsProcessed.WithLabelValues(request.Type.String(), status.String()).Inc()
+
+	return d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+		if err := tx.Requests().SetStatus(ctx, request.Id, status); err != nil {
+			return err
+		}
+
+		// Kept for operators diagnosing failed exports, this is not shown to users
+		if failureReason != nil {
+			if err := tx.Requests().SetFailureReason(ctx, request.Id, failureReason); err != nil {
+				return err
+			}
+		}
+
+		return deleteTask(ctx, tx)
+	})
+}
+
 func (d *Daemon) handleNext(ctx context.Context, next *model.Union[model.Task, model.Request]) error {
 	task := next.First
 	request := next.Second
@@ -120,6 +207,14 @@ func (d *Daemon) handleNext(ctx context.Context, next *model.Union[model.Task, m
 		return d.handleGuildTranscriptsTask(ctx, task, request)
 	case model.RequestTypeGuildData:
 		return d.handleGuildDataTask(ctx, task, request)
+	case model.RequestTypeGuildFull:
+		return d.handleGuildFullTask(ctx, task, request)
+	case model.RequestTypeUserData:
+		return d.handleUserDataTask(ctx, task, request)
+	case model.RequestTypeGuildErasure:
+		return d.handleGuildErasureTask(ctx, task, request)
+	case model.RequestTypeUserErasure:
+		return d.handleUserErasureTask(ctx, task, request)
 	default:
 		d.logger.Error("Unknown request type", slog.String("type", string(request.Type)))
 		return fmt.Errorf("unknown request type: %s", request.Type)
@@ -133,7 +228,11 @@ func (d *Daemon) getNextTask(ctx context.Context) (*model.Union[model.Task, mode
 	var task *model.Union[model.Task, model.Request]
 	if err := d.repository.Tx(timedCtx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
 		task, err = tx.Tasks().GetNext(ctx)
-		return
+		if err != nil || task == nil {
+			return err
+		}
+
+		return tx.Tasks().Start(ctx, task.First.Id)
 	}); err != nil {
 		return nil, err
 	}
@@ -154,6 +253,14 @@ func (d *Daemon) deleteOldRequests(ctx context.Context) error {
 		}
 
 		d.logger.Info("Deleted old requests", slog.Int64("count", deleted))
+
+		// Revoked sessions are kept until they would have expired anyway, so they stay on the revocation list
+		deleted, err = tx.Sessions().DeleteExpired(ctx, time.Hour*24)
+		if err != nil {
+			return err
+		}
+
+		d.logger.Info("Deleted expired sessions", slog.Int64("count", deleted))
 		return nil
 	})
 }
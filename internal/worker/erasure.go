@@ -0,0 +1,251 @@
+package worker
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/repository"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/jackc/pgx/v4"
+	"log/slog"
+	"time"
+)
+
+// erasureRetryDelay is how long to wait before retrying an erasure that has failed part way through.
+const erasureRetryDelay = time.Minute * 15
+
+type erasureStatement struct {
+	Table string
+	Query string
+}
+
+func newErasureStatement(table, query string) erasureStatement {
+	return erasureStatement{
+		Table: table,
+		Query: query,
+	}
+}
+
+func deleteByGuild(table string) erasureStatement {
+	return newErasureStatement(table, fmt.Sprintf(`DELETE FROM %s WHERE guild_id = $1;`, table))
+}
+
+func deleteByUser(table string) erasureStatement {
+	return newErasureStatement(table, fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1;`, table))
+}
+
+// Tables referencing tickets must be purged before the tickets themselves. Panel mentions, panel teams, multi panel
+// targets, access control rules, support team members, form inputs and embed fields are removed by ON DELETE CASCADE.
+// The server blacklist is retained to prevent abuse.
+var guildErasureStatements = []erasureStatement{
+	deleteByGuild("archive_messages"),
+	deleteByGuild("auto_close_exclude"),
+	deleteByGuild("category_update_queue"),
+	deleteByGuild("close_reason"),
+	deleteByGuild("close_request"),
+	deleteByGuild("exit_survey_responses"),
+	deleteByGuild("first_response_time"),
+	deleteByGuild("participant"),
+	deleteByGuild("service_ratings"),
+	deleteByGuild("ticket_claims"),
+	deleteByGuild("ticket_last_message"),
+	deleteByGuild("ticket_members"),
+	deleteByGuild("tickets"),
+	deleteByGuild("multi_panels"),
+	deleteByGuild("panels"),
+	deleteByGuild("support_team"),
+	deleteByGuild("settings"),
+	deleteByGuild("forms"),
+	deleteByGuild("embeds"),
+	deleteByGuild("active_language"),
+	deleteByGuild("archive_channel"),
+	deleteByGuild("auto_close"),
+	deleteByGuild("blacklist"),
+	deleteByGuild("channel_category"),
+	deleteByGuild("claim_settings"),
+	deleteByGuild("close_confirmation"),
+	deleteByGuild("custom_colours"),
+	deleteByGuild("feedback_enabled"),
+	deleteByGuild("guild_metadata"),
+	deleteByGuild("naming_scheme"),
+	deleteByGuild("on_call"),
+	deleteByGuild("permissions"),
+	deleteByGuild("role_blacklist"),
+	deleteByGuild("role_permissions"),
+	deleteByGuild("tags"),
+	deleteByGuild("ticket_limit"),
+	deleteByGuild("ticket_permissions"),
+	deleteByGuild("users_can_close"),
+	deleteByGuild("welcome_messages"),
+}
+
+// Tickets opened by the user, and their transcripts, form part of the guild's records and are retained, as are
+// blacklist entries. The user's staff permissions and support team memberships are part of each guild's configuration,
+// which is not the user's to change, so are also retained; the guild owner can remove them.
+var userErasureStatements = []erasureStatement{
+	newErasureStatement("exit_survey_responses", `
+DELETE FROM exit_survey_responses r
+USING tickets t
+WHERE r.guild_id = t.guild_id AND r.ticket_id = t.id AND t.user_id = $1;`),
+	newErasureStatement("service_ratings", `
+DELETE FROM service_ratings r
+USING tickets t
+WHERE r.guild_id = t.guild_id AND r.ticket_id = t.id AND t.user_id = $1;`),
+	deleteByUser("participant"),
+	deleteByUser("ticket_members"),
+	deleteByUser("ticket_claims"),
+	deleteByUser("first_response_time"),
+	deleteByUser("on_call"),
+}
+
+func (d *Daemon) handleGuildErasureTask(ctx context.Context, task model.Task, request model.Request) error {
+	if request.GuildId == nil || *request.GuildId == 0 {
+		d.logger.Error("Guild ID is nil", slog.String("task_id", task.Id.String()))
+		return fmt.Errorf("guild ID is nil")
+	}
+
+	guildId := *request.GuildId
+
+	logger := d.logger.With(slog.Uint64("guild_id", guildId), "request_id", request.Id)
+
+	progress, err := d.getErasureProgress(ctx, request)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get erasure progress", "error", err)
+		return err
+	}
+
+	// The rows have already been erased if this is a retry
+	if progress == nil {
+		deletedRows, err := d.eraseRows(ctx, guildErasureStatements, guildId)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to erase rows", "error", err)
+			return err
+		}
+
+		logger.InfoContext(ctx, "Erased rows for guild")
+
+		progress = &model.ErasureProgress{DeletedRows: deletedRows}
+		if err := d.setErasureProgress(ctx, request, *progress); err != nil {
+			logger.ErrorContext(ctx, "Failed to save erasure progress", "error", err)
+			return &retryableError{err: err, delay: erasureRetryDelay}
+		}
+	}
+
+	// Deleting transcripts is idempotent, so on failure the erasure is retried until all of them are gone. Only then
+	// is the request completed.
+	deletedTranscripts, err := d.transcripts.DeleteTranscriptsForGuild(ctx, guildId)
+	progress.DeletedTranscripts += deletedTranscripts
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to delete transcripts", "error", err)
+
+		if err := d.setErasureProgress(ctx, request, *progress); err != nil {
+			logger.ErrorContext(ctx, "Failed to save erasure progress", "error", err)
+		}
+
+		return &retryableError{err: err, delay: erasureRetryDelay}
+	}
+
+	if err := d.setErasureProgress(ctx, request, *progress); err != nil {
+		logger.ErrorContext(ctx, "Failed to save erasure progress", "error", err)
+		return &retryableError{err: err, delay: erasureRetryDelay}
+	}
+
+	if err := d.uploadErasureReceipt(ctx, logger, request, progress.DeletedRows, progress.DeletedTranscripts); err != nil {
+		return &retryableError{err: err, delay: erasureRetryDelay}
+	}
+
+	return nil
+}
+
+func (d *Daemon) getErasureProgress(ctx context.Context, request model.Request) (*model.ErasureProgress, error) {
+	var progress *model.ErasureProgress
+	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
+		progress, err = tx.Requests().GetErasureProgress(ctx, request.Id)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+func (d *Daemon) setErasureProgress(ctx context.Context, request model.Request, progress model.ErasureProgress) error {
+	return d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
+		return tx.Requests().SetErasureProgress(ctx, request.Id, progress)
+	})
+}
+
+func (d *Daemon) handleUserErasureTask(ctx context.Context, task model.Task, request model.Request) error {
+	if request.UserId == 0 {
+		d.logger.Error("User ID is zero", slog.String("task_id", task.Id.String()))
+		return fmt.Errorf("user ID is zero")
+	}
+
+	logger := d.logger.With(slog.Uint64("user_id", request.UserId), "request_id", request.Id)
+
+	deletedRows, err := d.eraseRows(ctx, userErasureStatements, request.UserId)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to erase rows", "error", err)
+		return err
+	}
+
+	logger.InfoContext(ctx, "Erased rows for user")
+
+	return d.uploadErasureReceipt(ctx, logger, request, deletedRows, 0)
+}
+
+// eraseRows runs every statement in a single transaction, so that a failure leaves the data untouched.
+func (d *Daemon) eraseRows(ctx context.Context, statements []erasureStatement, id uint64) (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	if err := d.database.WithTx(ctx, func(tx pgx.Tx) error {
+		for _, statement := range statements {
+			res, err := tx.Exec(ctx, statement.Query, id)
+			if err != nil {
+				return fmt.Errorf("failed to erase from %s: %w", statement.Table, err)
+			}
+
+			deleted[statement.Table] += res.RowsAffected()
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+func (d *Daemon) uploadErasureReceipt(
+	ctx context.Context,
+	logger *slog.Logger,
+	request model.Request,
+	deletedRows map[string]int64,
+	deletedTranscripts int,
+) error {
+	receipt := dto.ErasureReceipt{
+		RequestId:          request.Id.String(),
+		RequestType:        request.Type.String(),
+		UserId:             request.UserId,
+		GuildId:            request.GuildId,
+		RequestedAt:        request.CreatedAt,
+		ErasedAt:           time.Now(),
+		DeletedRows:        deletedRows,
+		DeletedTranscripts: deletedTranscripts,
+	}
+
+	marshalled, err := json.Marshal(receipt)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to marshal receipt", "error", err)
+		return err
+	}
+
+	files := map[string][]byte{
+		"receipt.json":     marshalled,
+		"receipt.json.sig": []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled))),
+	}
+
+	return d.uploadArtifact(ctx, logger, request, files)
+}
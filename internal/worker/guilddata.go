@@ -4,28 +4,38 @@ import (
 	"context"
 	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/TicketsBot/database"
+	"github.com/TicketsBot/export/internal/dtoconv"
 	"github.com/TicketsBot/export/internal/metrics"
 	"github.com/TicketsBot/export/internal/model"
-	"github.com/TicketsBot/export/internal/repository"
 	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/internal/worker/redact"
+	"github.com/TicketsBot/export/internal/worker/sqlitebundle"
 	"github.com/TicketsBot/export/pkg/dto"
 	"github.com/jackc/pgx/v4"
 	"golang.org/x/sync/errgroup"
 	"log/slog"
+	"slices"
 	"time"
 )
 
 type guildDataTask struct {
-	Name string
-	F    func(ctx context.Context, guildId uint64, guildData *dto.GuildData) error
+	Name    string
+	Section model.GuildDataSection
+	F       func(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error
 }
 
-func newGuildDataTask(name string, f func(ctx context.Context, guildId uint64, guildData *dto.GuildData) error) guildDataTask {
+func newGuildDataTask(
+	name string,
+	section model.GuildDataSection,
+	f func(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error,
+) guildDataTask {
 	return guildDataTask{
-		Name: name,
-		F:    f,
+		Name:    name,
+		Section: section,
+		F:       f,
 	}
 }
 
@@ -39,186 +49,274 @@ func (d *Daemon) handleGuildDataTask(ctx context.Context, task model.Task, reque
 
 	logger := d.logger.With(slog.Uint64("guild_id", guildId), "request_id", request.Id)
 
-	data := dto.GuildData{
-		GuildId: guildId,
+	// Sections that weren't selected are left as their zero value, and are listed in metadata.json
+	sections := request.Options.GuildDataSections()
+
+	data, err := d.fetchGuildData(ctx, logger, guildId, sections)
+	if err != nil {
+		return err
 	}
 
-	tasks := []guildDataTask{
-		newGuildDataTask("Fetch Active Language", d.fetchActiveLanguage),
-		newGuildDataTask("Fetch Archive Channel", d.fetchArchiveChannel),
-		newGuildDataTask("Fetch Archive Messages", d.fetchArchiveMessages),
-		newGuildDataTask("Fetch Autoclose Settings", d.fetchAutocloseSettings),
-		newGuildDataTask("Fetch Autoclose Excluded", d.fetchAutocloseExcluded),
-		newGuildDataTask("Fetch Guild Blacklisted Users", d.fetchGuildBlacklistedUsers),
-		newGuildDataTask("Fetch Channel Category", d.fetchChannelCategory),
-		newGuildDataTask("Fetch Claim Settings", d.fetchClaimSettings),
-		newGuildDataTask("Fetch Close Confirmation Enabled", d.fetchCloseConfirmationEnabled),
-		newGuildDataTask("Fetch Close Reasons", d.fetchCloseReasons),
-		newGuildDataTask("Fetch Custom Colours", d.fetchCustomColours),
-		newGuildDataTask("Fetch Embed Fields", d.fetchEmbedFields),
-		newGuildDataTask("Fetch Embeds", d.fetchEmbeds),
-		newGuildDataTask("Fetch Exit Survey Responses", d.fetchExitSurveyResponses),
-		newGuildDataTask("Fetch Feedback Enabled", d.fetchFeedbackEnabled),
-		newGuildDataTask("Fetch First Response Times", d.fetchFirstResponseTimes),
-		newGuildDataTask("Fetch Form Inputs", d.fetchFormInputs),
-		newGuildDataTask("Fetch Forms", d.fetchForms),
-		newGuildDataTask("Fetch Guild Is Globally Blacklisted", d.fetchGuildIsGloballyBlacklisted),
-		newGuildDataTask("Fetch Guild Metadata", d.fetchGuildMetadata),
-		newGuildDataTask("Fetch Multi Panels", d.fetchMultiPanels),
-		newGuildDataTask("Fetch Multi Panel Targets", d.fetchMultiPanelTargets),
-		newGuildDataTask("Fetch Naming Scheme", d.fetchNamingScheme),
-		newGuildDataTask("Fetch On Call Users", d.fetchOnCallUsers),
-		newGuildDataTask("Fetch Panel Access Control Rules", d.fetchPanelAccessControlRules),
-		newGuildDataTask("Fetch Panel Mention User", d.fetchPanelMentionUser),
-		newGuildDataTask("Fetch Panel Role Mentions", d.fetchPanelRoleMentions),
-		newGuildDataTask("Fetch Panels", d.fetchPanels),
-		newGuildDataTask("Fetch Panel Teams", d.fetchPanelTeams),
-		newGuildDataTask("Fetch Participants", d.fetchParticipants),
-		newGuildDataTask("Fetch User Permissions", d.fetchUserPermissions),
-		newGuildDataTask("Fetch Guild Blacklisted Roles", d.fetchGuildBlacklistedRoles),
-		newGuildDataTask("Fetch Role Permissions", d.fetchRolePermissions),
-		newGuildDataTask("Fetch Service Ratings", d.fetchServiceRatings),
-		newGuildDataTask("Fetch Settings", d.fetchSettings),
-		newGuildDataTask("Fetch Support Team Users", d.fetchSupportTeamUsers),
-		newGuildDataTask("Fetch Support Team Roles", d.fetchSupportTeamRoles),
-		newGuildDataTask("Fetch Support Teams", d.fetchSupportTeams),
-		newGuildDataTask("Fetch Tags", d.fetchTags),
-		newGuildDataTask("Fetch Ticket Claims", d.fetchTicketClaims),
-		newGuildDataTask("Fetch Ticket Last Messages", d.fetchTicketLastMessages),
-		newGuildDataTask("Fetch Ticket Limit", d.fetchTicketLimit),
-		newGuildDataTask("Fetch Ticket Additional Members", d.fetchTicketAdditionalMembers),
-		newGuildDataTask("Fetch Ticket Permissions", d.fetchTicketPermissions),
-		newGuildDataTask("Fetch Tickets", d.fetchTickets),
-		newGuildDataTask("Fetch Users Can Close", d.fetchUsersCanClose),
-		newGuildDataTask("Fetch Welcome Message", d.fetchWelcomeMessage),
+	redactor, err := redact.New(request.Options.Redaction)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to create redactor", "error", err)
+		return err
 	}
 
-	group, groupCtx := errgroup.WithContext(ctx)
-	for _, task := range tasks {
-		task := task
+	redactor.GuildData(&data)
 
-		group.Go(func() error {
-			now := time.Now()
+	files := make(map[string][]byte)
+	if err := d.addMetadata(files, request, guildId, redactor, sections); err != nil {
+		logger.ErrorContext(ctx, "Failed to build metadata", "error", err)
+		return err
+	}
 
-			logger.DebugContext(groupCtx, "Running task", "name", task.Name)
-			if err := task.F(groupCtx, guildId, &data); err != nil {
-				logger.ErrorContext(ctx, "Failed to run task", "error", err, "elapsed", time.Since(now))
+	if request.Options.Format == model.ExportFormatSqlite {
+		var transcripts map[int][]byte
+		var failed []int
+		if request.Options.IncludeTranscriptMessages {
+			res, err := d.transcripts.GetTranscriptsForGuild(ctx, guildId)
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to get transcripts for guild", "error", err)
 				return err
 			}
 
-			logger.DebugContext(groupCtx, "Task completed", "name", task.Name, "elapsed", time.Since(now))
-			return nil
-		})
-	}
+			failed = res.Failed
+			transcripts = make(map[int][]byte, len(res.Transcripts))
+			for ticketId, transcript := range res.Transcripts {
+				// A transcript that can't be redacted is left out, rather than exported unredacted
+				redacted, err := redactor.Transcript(transcript)
+				if err != nil {
+					logger.WarnContext(ctx, "Failed to redact transcript", "ticket_id", ticketId, "error", err)
+					failed = append(failed, ticketId)
+					continue
+				}
+
+				transcripts[ticketId] = redacted
+			}
+		}
 
-	if err := group.Wait(); err != nil {
-		d.logger.Error("Failed to run tasks", "error", err)
-		return err
-	}
+		bundle, undecodable, err := sqlitebundle.Build(ctx, data, transcripts)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to build SQLite bundle", "error", err)
+			return err
+		}
 
-	logger.InfoContext(ctx, "All tasks completed")
+		if len(undecodable) > 0 {
+			logger.WarnContext(ctx, "Failed to decode transcripts", "ticket_ids", undecodable)
+			failed = append(failed, undecodable...)
+		}
 
-	files := make(map[string][]byte)
+		files["data.sqlite"] = bundle
+		files["data.sqlite.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, bundle)))
 
-	marshalled, err := json.Marshal(data)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to marshal data", "error", err)
-		return err
+		d.addFailed(files, failed, true)
+	} else {
+		marshalled, err := json.Marshal(data)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to marshal data", "error", err)
+			return err
+		}
+
+		files["data.json"] = marshalled
+		files["data.json.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled)))
 	}
 
-	files["data.json"] = marshalled
-	files["data.json.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled)))
+	if request.Options.IncludeCsv {
+		csvFiles, err := buildGuildDataCsv(data)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to build CSV files", "error", err)
+			return err
+		}
 
-	artifact, err := utils.BuildZip(d.config, files)
-	if err != nil {
-		logger.ErrorContext(ctx, "Failed to build zip", "error", err)
-		return err
+		for name, content := range csvFiles {
+			files[name] = content
+			files[name+".sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, content)))
+		}
 	}
 
-	artifactSize := int64(len(artifact))
+	return d.uploadArtifact(ctx, logger, request, files)
+}
 
-	var globalArtifactSize int64
-	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
-		globalArtifactSize, err = tx.Artifacts().GetGlobalSize(ctx)
-		return err
-	}); err != nil {
-		d.logger.Error("Failed to get global artifact size", "error", err)
-		return err
+// fetchGuildData reads the given sections of a guild's data from a single snapshot of the tickets database. Sections
+// that aren't given are left as their zero value.
+func (d *Daemon) fetchGuildData(
+	ctx context.Context,
+	logger *slog.Logger,
+	guildId uint64,
+	sections []model.GuildDataSection,
+) (dto.GuildData, error) {
+	data := dto.GuildData{
+		FormatVersion: dto.FormatVersionLatest,
+		GuildId:       guildId,
 	}
 
-	if globalArtifactSize+artifactSize > maxActiveSize {
-		d.logger.Error("Artifact size exceeds maximum", slog.Int64("size", globalArtifactSize+artifactSize))
-		return fmt.Errorf("artifact size exceeds maximum")
+	tasks := []guildDataTask{
+		newGuildDataTask("Fetch Active Language", model.GuildDataSectionActiveLanguage, d.fetchActiveLanguage),
+		newGuildDataTask("Fetch Archive Channel", model.GuildDataSectionArchiveChannel, d.fetchArchiveChannel),
+		newGuildDataTask("Fetch Archive Messages", model.GuildDataSectionArchiveMessages, d.fetchArchiveMessages),
+		newGuildDataTask("Fetch Autoclose Settings", model.GuildDataSectionAutocloseSettings, d.fetchAutocloseSettings),
+		newGuildDataTask("Fetch Autoclose Excluded", model.GuildDataSectionAutocloseExcluded, d.fetchAutocloseExcluded),
+		newGuildDataTask("Fetch Guild Blacklisted Users", model.GuildDataSectionGuildBlacklistedUsers, d.fetchGuildBlacklistedUsers),
+		newGuildDataTask("Fetch Channel Category", model.GuildDataSectionChannelCategory, d.fetchChannelCategory),
+		newGuildDataTask("Fetch Claim Settings", model.GuildDataSectionClaimSettings, d.fetchClaimSettings),
+		newGuildDataTask("Fetch Close Confirmation Enabled", model.GuildDataSectionCloseConfirmationEnabled, d.fetchCloseConfirmationEnabled),
+		newGuildDataTask("Fetch Close Reasons", model.GuildDataSectionCloseReasons, d.fetchCloseReasons),
+		newGuildDataTask("Fetch Custom Colours", model.GuildDataSectionCustomColors, d.fetchCustomColours),
+		newGuildDataTask("Fetch Embed Fields", model.GuildDataSectionEmbedFields, d.fetchEmbedFields),
+		newGuildDataTask("Fetch Embeds", model.GuildDataSectionEmbeds, d.fetchEmbeds),
+		newGuildDataTask("Fetch Exit Survey Responses", model.GuildDataSectionExitSurveyResponses, d.fetchExitSurveyResponses),
+		newGuildDataTask("Fetch Feedback Enabled", model.GuildDataSectionFeedbackEnabled, d.fetchFeedbackEnabled),
+		newGuildDataTask("Fetch First Response Times", model.GuildDataSectionFirstResponseTimes, d.fetchFirstResponseTimes),
+		newGuildDataTask("Fetch Form Inputs", model.GuildDataSectionFormInputs, d.fetchFormInputs),
+		newGuildDataTask("Fetch Forms", model.GuildDataSectionForms, d.fetchForms),
+		newGuildDataTask("Fetch Guild Is Globally Blacklisted", model.GuildDataSectionGuildIsGloballyBlacklisted, d.fetchGuildIsGloballyBlacklisted),
+		newGuildDataTask("Fetch Guild Metadata", model.GuildDataSectionGuildMetadata, d.fetchGuildMetadata),
+		newGuildDataTask("Fetch Multi Panels", model.GuildDataSectionMultiPanels, d.fetchMultiPanels),
+		newGuildDataTask("Fetch Multi Panel Targets", model.GuildDataSectionMultiPanelTargets, d.fetchMultiPanelTargets),
+		newGuildDataTask("Fetch Naming Scheme", model.GuildDataSectionNamingScheme, d.fetchNamingScheme),
+		newGuildDataTask("Fetch On Call Users", model.GuildDataSectionOnCallUsers, d.fetchOnCallUsers),
+		newGuildDataTask("Fetch Panel Access Control Rules", model.GuildDataSectionPanelAccessControlRules, d.fetchPanelAccessControlRules),
+		newGuildDataTask("Fetch Panel Mention User", model.GuildDataSectionPanelMentionUser, d.fetchPanelMentionUser),
+		newGuildDataTask("Fetch Panel Role Mentions", model.GuildDataSectionPanelRoleMentions, d.fetchPanelRoleMentions),
+		newGuildDataTask("Fetch Panels", model.GuildDataSectionPanels, d.fetchPanels),
+		newGuildDataTask("Fetch Panel Teams", model.GuildDataSectionPanelTeams, d.fetchPanelTeams),
+		newGuildDataTask("Fetch Participants", model.GuildDataSectionParticipants, d.fetchParticipants),
+		newGuildDataTask("Fetch User Permissions", model.GuildDataSectionUserPermissions, d.fetchUserPermissions),
+		newGuildDataTask("Fetch Guild Blacklisted Roles", model.GuildDataSectionGuildBlacklistedRoles, d.fetchGuildBlacklistedRoles),
+		newGuildDataTask("Fetch Role Permissions", model.GuildDataSectionRolePermissions, d.fetchRolePermissions),
+		newGuildDataTask("Fetch Service Ratings", model.GuildDataSectionServiceRatings, d.fetchServiceRatings),
+		newGuildDataTask("Fetch Settings", model.GuildDataSectionSettings, d.fetchSettings),
+		newGuildDataTask("Fetch Support Team Users", model.GuildDataSectionSupportTeamUsers, d.fetchSupportTeamUsers),
+		newGuildDataTask("Fetch Support Team Roles", model.GuildDataSectionSupportTeamRoles, d.fetchSupportTeamRoles),
+		newGuildDataTask("Fetch Support Teams", model.GuildDataSectionSupportTeams, d.fetchSupportTeams),
+		newGuildDataTask("Fetch Tags", model.GuildDataSectionTags, d.fetchTags),
+		newGuildDataTask("Fetch Ticket Claims", model.GuildDataSectionTicketClaims, d.fetchTicketClaims),
+		newGuildDataTask("Fetch Ticket Last Messages", model.GuildDataSectionTicketLastMessages, d.fetchTicketLastMessages),
+		newGuildDataTask("Fetch Ticket Limit", model.GuildDataSectionTicketLimit, d.fetchTicketLimit),
+		newGuildDataTask("Fetch Ticket Additional Members", model.GuildDataSectionTicketAdditionalMembers, d.fetchTicketAdditionalMembers),
+		newGuildDataTask("Fetch Ticket Permissions", model.GuildDataSectionTicketPermissions, d.fetchTicketPermissions),
+		newGuildDataTask("Fetch Tickets", model.GuildDataSectionTickets, d.fetchTickets),
+		newGuildDataTask("Fetch Users Can Close", model.GuildDataSectionUsersCanClose, d.fetchUsersCanClose),
+		newGuildDataTask("Fetch Welcome Message", model.GuildDataSectionWelcomeMessage, d.fetchWelcomeMessage),
 	}
 
-	logger.InfoContext(ctx, "Uploading artifact", slog.Int64("size", artifactSize))
+	tasks = slices.DeleteFunc(tasks, func(task guildDataTask) bool {
+		return !slices.Contains(sections, task.Section)
+	})
 
-	key := utils.RandomString(32)
-	expiresAt := time.Now().Add(transcriptExpiry)
-	if err := d.artifacts.Store(ctx, request.Id, key, expiresAt, artifact); err != nil {
-		d.logger.Error("Failed to store artifact", "error", err)
-		return err
+	// Every task reads from the same snapshot, so that the export is consistent even if the guild's data changes while
+	// it is running
+	snapshot, err := d.beginSnapshot(ctx, d.config.Daemon.SnapshotConnections)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to begin snapshot", "error", err)
+		return dto.GuildData{}, err
 	}
 
-	metrics.ArtifactsUploaded.WithLabelValues(request.Type.String()).Inc()
-	metrics.ArtifactsUploadedBytes.WithLabelValues(request.Type.String()).Add(float64(artifactSize))
+	defer snapshot.release()
 
-	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
-		if err := tx.Requests().SetStatus(ctx, request.Id, model.RequestStatusCompleted); err != nil {
-			return err
-		}
+	// Tasks only run while they hold one of the snapshot's transactions, so there is no need to start more goroutines
+	// than there are transactions
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(snapshot.size())
 
-		if err := tx.Artifacts().Create(ctx, request.Id, key, expiresAt, artifactSize); err != nil {
-			return err
-		}
+	for _, task := range tasks {
+		task := task
 
-		return nil
-	}); err != nil {
-		d.logger.Error("Failed to update request status", "error", err)
-		return err
+		group.Go(func() error {
+			now := time.Now()
+
+			return snapshot.run(groupCtx, func(tx pgx.Tx) error {
+				logger.DebugContext(groupCtx, "Running task", "name", task.Name, "waited", time.Since(now))
+
+				taskCtx, cancel := context.WithTimeout(groupCtx, d.config.Daemon.FetchTimeout)
+				defer cancel()
+
+				started := time.Now()
+				err := task.F(taskCtx, tx, guildId, &data)
+
+				status := model.RequestStatusCompleted
+				if err != nil {
+					status = model.RequestStatusFailed
+				}
+
+				metrics.GuildDataTaskDuration.
+					WithLabelValues(task.Section.String(), status.String()).
+					Observe(time.Since(started).Seconds())
+
+				if err != nil {
+					logger.ErrorContext(ctx, "Failed to run task", "name", task.Name, "error", err, "elapsed", time.Since(now))
+					return fmt.Errorf("%s: %w", task.Name, err)
+				}
+
+				logger.DebugContext(groupCtx, "Task completed", "name", task.Name, "elapsed", time.Since(now))
+				return nil
+			})
+		})
 	}
 
-	return nil
+	if err := group.Wait(); err != nil {
+		d.logger.Error("Failed to run tasks", "error", err)
+		return dto.GuildData{}, err
+	}
+
+	if err := snapshot.release(); err != nil {
+		logger.WarnContext(ctx, "Failed to release snapshot", "error", err)
+	}
+
+	logger.InfoContext(ctx, "All tasks completed")
+	return data, nil
 }
 
-func (d *Daemon) fetchActiveLanguage(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.ActiveLanguage, d.database.ActiveLanguage.Get)
+func (d *Daemon) fetchActiveLanguage(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "language" FROM active_language WHERE "guild_id" = $1;`
+	return fetchVal(ctx, guildId, &guildData.ActiveLanguage, queryValue(db, query, ""))
 }
 
-func (d *Daemon) fetchArchiveChannel(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchPtr(ctx, guildId, &guildData.ArchiveChannel, d.database.ArchiveChannel.Get)
+func (d *Daemon) fetchArchiveChannel(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "channel_id" FROM archive_channel WHERE "guild_id" = $1;`
+	return fetchPtr(ctx, guildId, &guildData.ArchiveChannel, queryValue[*uint64](db, query, nil))
 }
 
-func (d *Daemon) fetchArchiveMessages(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchArchiveMessages(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, channel_id, message_id
 FROM archive_messages
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.ArchiveMessages, query,
-		func(rows pgx.Rows) (dto.TicketUnion[database.ArchiveMessage], error) {
-			var res dto.TicketUnion[database.ArchiveMessage]
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.ArchiveMessages, query, ticketUnionKey,
+		func(rows pgx.Rows) (dto.TicketUnion[dto.ArchiveMessage], error) {
+			var res dto.TicketUnion[dto.ArchiveMessage]
 			if err := rows.Scan(&res.TicketId, &res.Data.ChannelId, &res.Data.MessageId); err != nil {
-				return dto.TicketUnion[database.ArchiveMessage]{}, err
+				return dto.TicketUnion[dto.ArchiveMessage]{}, err
 			}
 
 			return res, nil
 		})
 }
 
-func (d *Daemon) fetchAutocloseSettings(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.AutocloseSettings, d.database.AutoClose.Get)
+func (d *Daemon) fetchAutocloseSettings(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT "enabled", "since_open_with_no_response", "since_last_message", "on_user_leave"
+FROM auto_close
+WHERE "guild_id" = $1;`
+
+	return fetchVal(ctx, guildId, &guildData.AutocloseSettings, convert(func(ctx context.Context, guildId uint64) (database.AutoCloseSettings, error) {
+		var settings database.AutoCloseSettings
+		err := scanRow(ctx, db, guildId, query, &settings.Enabled, &settings.SinceOpenWithNoResponse, &settings.SinceLastMessage, &settings.OnUserLeave)
+		return settings, err
+	}, dtoconv.AutoCloseSettingsToDto))
 }
 
-func (d *Daemon) fetchAutocloseExcluded(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchAutocloseExcluded(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id
 FROM auto_close_exclude
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.AutocloseExcluded, query,
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.AutocloseExcluded, query, idKey,
 		func(rows pgx.Rows) (int, error) {
 			var ticketId int
 			if err := rows.Scan(&ticketId); err != nil {
@@ -229,14 +327,14 @@ ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
 		})
 }
 
-func (d *Daemon) fetchGuildBlacklistedUsers(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchGuildBlacklistedUsers(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT user_id
 FROM blacklist
-WHERE guild_id = $1
-ORDER BY user_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND user_id > $3
+ORDER BY user_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.GuildBlacklistedUsers, query, func(rows pgx.Rows) (uint64, error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.GuildBlacklistedUsers, query, idKey, func(rows pgx.Rows) (uint64, error) {
 		var userId uint64
 		if err := rows.Scan(&userId); err != nil {
 			return 0, err
@@ -246,92 +344,104 @@ ORDER BY user_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchChannelCategory(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.ChannelCategory, d.database.ChannelCategory.Get)
+func (d *Daemon) fetchChannelCategory(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "category_id" FROM channel_category WHERE "guild_id" = $1;`
+	return fetchVal(ctx, guildId, &guildData.ChannelCategory, queryValue[uint64](db, query, 0))
 }
 
-func (d *Daemon) fetchClaimSettings(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.ClaimSettings, d.database.ClaimSettings.Get)
+func (d *Daemon) fetchClaimSettings(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "support_can_view", "support_can_type" FROM claim_settings WHERE "guild_id" = $1;`
+
+	return fetchVal(ctx, guildId, &guildData.ClaimSettings, convert(func(ctx context.Context, guildId uint64) (database.ClaimSettings, error) {
+		settings := database.ClaimSettings{
+			SupportCanView: true,
+			SupportCanType: false,
+		}
+
+		err := scanRow(ctx, db, guildId, query, &settings.SupportCanView, &settings.SupportCanType)
+		return settings, err
+	}, dtoconv.ClaimSettingsToDto))
 }
 
-func (d *Daemon) fetchCloseConfirmationEnabled(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.CloseConfirmationEnabled, d.database.CloseConfirmation.Get)
+func (d *Daemon) fetchCloseConfirmationEnabled(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "confirm" FROM close_confirmation WHERE "guild_id" = $1;`
+	return fetchValNoPtr(ctx, guildId, &guildData.CloseConfirmationEnabled, queryValue(db, query, true))
 }
 
-func (d *Daemon) fetchCloseReasons(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchCloseReasons(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, close_reason, closed_by
 FROM close_reason
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.CloseReasons, query,
-		func(rows pgx.Rows) (dto.TicketUnion[database.CloseMetadata], error) {
-			var data dto.TicketUnion[database.CloseMetadata]
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.CloseReasons, query, ticketUnionKey,
+		func(rows pgx.Rows) (dto.TicketUnion[dto.CloseMetadata], error) {
+			var data dto.TicketUnion[dto.CloseMetadata]
 			if err := rows.Scan(&data.TicketId, &data.Data.Reason, &data.Data.ClosedBy); err != nil {
-				return dto.TicketUnion[database.CloseMetadata]{}, err
+				return dto.TicketUnion[dto.CloseMetadata]{}, err
 			}
 
 			return data, nil
 		})
 }
 
-func (d *Daemon) fetchCustomColours(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchCustomColours(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT colour_id, colour_code
 FROM custom_colours
-WHERE guild_id = $1
-ORDER BY colour_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND colour_id > $3
+ORDER BY colour_id LIMIT $2;`
 
-	return fetchMap(ctx, d.database, guildId, &guildData.CustomColors, query)
+	return fetchMap(ctx, db, guildId, &guildData.CustomColors, query)
 }
 
-func (d *Daemon) fetchEmbedFields(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchEmbedFields(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT f.id, f.embed_id, f.name, f.value, f.inline
 FROM embed_fields f
 INNER JOIN embeds e
 ON e.id = f.embed_id
-WHERE e.guild_id = $1
-ORDER BY f.embed_id, f.id ASC
-LIMIT $2 OFFSET $3;`
+WHERE e.guild_id = $1 AND (f.embed_id, f.id) > ($3, $4)
+ORDER BY f.embed_id, f.id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.EmbedFields, query, func(rows pgx.Rows) (database.EmbedField, error) {
-		var field database.EmbedField
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.EmbedFields, query, func(f dto.EmbedField) []interface{} { return []interface{}{f.EmbedId, f.FieldId} }, func(rows pgx.Rows) (dto.EmbedField, error) {
+		var field dto.EmbedField
 		if err := rows.Scan(&field.FieldId, &field.EmbedId, &field.Name, &field.Value, &field.Inline); err != nil {
-			return database.EmbedField{}, err
+			return dto.EmbedField{}, err
 		}
 
 		return field, nil
 	})
 }
 
-func (d *Daemon) fetchEmbeds(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchEmbeds(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT id, guild_id, title, description, colour, author_name, author_icon_url, author_url, image_url, thumbnail_url, footer_text, footer_icon_url, timestamp
 FROM embeds
-WHERE guild_id = $1
-ORDER BY id ASC
-LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND id > $3
+ORDER BY id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.Embeds, query, func(rows pgx.Rows) (database.CustomEmbed, error) {
-		var embed database.CustomEmbed
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.Embeds, query, func(e dto.CustomEmbed) []interface{} { return []interface{}{e.Id} }, func(rows pgx.Rows) (dto.CustomEmbed, error) {
+		var embed dto.CustomEmbed
 		if err := rows.Scan(&embed.Id, &embed.GuildId, &embed.Title, &embed.Description, &embed.Colour, &embed.AuthorName, &embed.AuthorIconUrl, &embed.AuthorUrl, &embed.ImageUrl, &embed.ThumbnailUrl, &embed.FooterText, &embed.FooterIconUrl, &embed.Timestamp); err != nil {
-			return database.CustomEmbed{}, err
+			return dto.CustomEmbed{}, err
 		}
 
 		return embed, nil
 	})
 }
 
-func (d *Daemon) fetchExitSurveyResponses(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchExitSurveyResponses(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, form_id, question_id, response
 FROM exit_survey_responses
-WHERE guild_id = $1
-ORDER BY ticket_id, question_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND (ticket_id, question_id) > ($3, $4)
+ORDER BY ticket_id, question_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.ExitSurveyResponses, query, func(rows pgx.Rows) (dto.TicketUnion[dto.ExitSurveyResponse], error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.ExitSurveyResponses, query, func(r dto.TicketUnion[dto.ExitSurveyResponse]) []interface{} {
+		return []interface{}{r.TicketId, valueOrZero(r.Data.QuestionId)}
+	}, func(rows pgx.Rows) (dto.TicketUnion[dto.ExitSurveyResponse], error) {
 		var res dto.TicketUnion[dto.ExitSurveyResponse]
 		if err := rows.Scan(&res.TicketId, &res.Data.FormId, &res.Data.QuestionId, &res.Data.Response); err != nil {
 			return dto.TicketUnion[dto.ExitSurveyResponse]{}, err
@@ -341,18 +451,19 @@ ORDER BY ticket_id, question_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchFeedbackEnabled(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.FeedbackEnabled, d.database.FeedbackEnabled.Get)
+func (d *Daemon) fetchFeedbackEnabled(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "feedback_enabled" FROM feedback_enabled WHERE "guild_id" = $1;`
+	return fetchValNoPtr(ctx, guildId, &guildData.FeedbackEnabled, queryValue(db, query, false))
 }
 
-func (d *Daemon) fetchFirstResponseTimes(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchFirstResponseTimes(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, user_id, response_time
 FROM first_response_time
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.FirstResponseTimes, query, func(rows pgx.Rows) (dto.FirstResponseTime, error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.FirstResponseTimes, query, func(t dto.FirstResponseTime) []interface{} { return []interface{}{t.TicketId} }, func(rows pgx.Rows) (dto.FirstResponseTime, error) {
 		var res dto.FirstResponseTime
 		if err := rows.Scan(&res.TicketId, &res.UserId, &res.ResponseTime); err != nil {
 			return dto.FirstResponseTime{}, err
@@ -362,67 +473,97 @@ ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchFormInputs(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchFormInputs(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT i.id, i.form_id, i.position, i.custom_id, i.style, i.label, i.placeholder, i.required, i.min_length, i.max_length
 FROM form_input i
 INNER JOIN forms f
 ON i.form_id = f.form_id
-WHERE f.guild_id = $1
-ORDER BY i.id ASC
-LIMIT $2 OFFSET $3;`
+WHERE f.guild_id = $1 AND i.id > $3
+ORDER BY i.id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.FormInputs, query, func(rows pgx.Rows) (database.FormInput, error) {
-		var input database.FormInput
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.FormInputs, query, func(i dto.FormInput) []interface{} { return []interface{}{i.Id} }, func(rows pgx.Rows) (dto.FormInput, error) {
+		var input dto.FormInput
 		if err := rows.Scan(&input.Id, &input.FormId, &input.Position, &input.CustomId, &input.Style, &input.Label, &input.Placeholder, &input.Required, &input.MinLength, &input.MaxLength); err != nil {
-			return database.FormInput{}, err
+			return dto.FormInput{}, err
 		}
 
 		return input, nil
 	})
 }
 
-func (d *Daemon) fetchForms(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchForms(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT form_id, guild_id, title, custom_id
 FROM forms
-WHERE guild_id = $1
-ORDER BY form_id ASC
-LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND form_id > $3
+ORDER BY form_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.Forms, query, func(rows pgx.Rows) (database.Form, error) {
-		var form database.Form
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.Forms, query, func(f dto.Form) []interface{} { return []interface{}{f.Id} }, func(rows pgx.Rows) (dto.Form, error) {
+		var form dto.Form
 		if err := rows.Scan(&form.Id, &form.GuildId, &form.Title, &form.CustomId); err != nil {
-			return database.Form{}, err
+			return dto.Form{}, err
 		}
 
 		return form, nil
 	})
 }
 
-func (d *Daemon) fetchGuildIsGloballyBlacklisted(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.GuildIsGloballyBlacklisted, func(ctx context.Context, guildId uint64) (bool, error) {
-		isBlacklisted, _, err := d.database.ServerBlacklist.IsBlacklisted(ctx, guildId)
-		return isBlacklisted, err
-	})
+func (d *Daemon) fetchGuildIsGloballyBlacklisted(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT EXISTS(SELECT 1 FROM server_blacklist WHERE "guild_id" = $1);`
+	return fetchValNoPtr(ctx, guildId, &guildData.GuildIsGloballyBlacklisted, queryValue(db, query, false))
 }
 
-func (d *Daemon) fetchGuildMetadata(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.GuildMetadata, d.database.GuildMetadata.Get)
+func (d *Daemon) fetchGuildMetadata(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "on_call_role" FROM guild_metadata WHERE "guild_id" = $1;`
+
+	return fetchValNoPtr(ctx, guildId, &guildData.GuildMetadata, convert(func(ctx context.Context, guildId uint64) (database.GuildMetadata, error) {
+		var metadata database.GuildMetadata
+		err := scanRow(ctx, db, guildId, query, &metadata.OnCallRole)
+		return metadata, err
+	}, dtoconv.GuildMetadataToDto))
 }
 
-func (d *Daemon) fetchMultiPanels(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.MultiPanels, d.database.MultiPanels.GetByGuild)
+func (d *Daemon) fetchMultiPanels(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT "id", "message_id", "channel_id", "guild_id", "select_menu", "select_menu_placeholder", "embed"
+FROM multi_panels
+WHERE "guild_id" = $1 AND "id" > $3
+ORDER BY "id" LIMIT $2;`
+
+	var multiPanels []database.MultiPanel
+	if err := fetchCustomPaginated(ctx, db, guildId, &multiPanels, query, func(p database.MultiPanel) []interface{} { return []interface{}{p.Id} }, func(rows pgx.Rows) (database.MultiPanel, error) {
+		var panel database.MultiPanel
+		var embedRaw *string
+		if err := rows.Scan(
+			&panel.Id, &panel.MessageId, &panel.ChannelId, &panel.GuildId, &panel.SelectMenu, &panel.SelectMenuPlaceholder, &embedRaw,
+		); err != nil {
+			return database.MultiPanel{}, err
+		}
+
+		if embedRaw != nil {
+			if err := json.Unmarshal([]byte(*embedRaw), &panel.Embed); err != nil {
+				return database.MultiPanel{}, err
+			}
+		}
+
+		return panel, nil
+	}); err != nil {
+		return err
+	}
+
+	guildData.MultiPanels = dtoconv.MultiPanelsToDto(multiPanels)
+	return nil
 }
 
-func (d *Daemon) fetchMultiPanelTargets(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchMultiPanelTargets(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT multi_panel_id, panel_id
 FROM multi_panel_targets t
 INNER JOIN multi_panels p
 ON t.multi_panel_id = p.id
-WHERE p.guild_id = $1
-ORDER BY multi_panel_id, panel_id ASC LIMIT $2 OFFSET $3;
+WHERE p.guild_id = $1 AND (multi_panel_id, panel_id) > ($3, $4)
+ORDER BY multi_panel_id, panel_id LIMIT $2;
 `
 
 	type response struct {
@@ -431,7 +572,7 @@ ORDER BY multi_panel_id, panel_id ASC LIMIT $2 OFFSET $3;
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.MultiPanelId, r.PanelId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.MultiPanelId, &data.PanelId); err != nil {
 			return response{}, err
@@ -451,38 +592,85 @@ ORDER BY multi_panel_id, panel_id ASC LIMIT $2 OFFSET $3;
 	return nil
 }
 
-func (d *Daemon) fetchNamingScheme(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.NamingScheme, d.database.NamingScheme.Get)
+func (d *Daemon) fetchNamingScheme(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "naming_scheme" FROM naming_scheme WHERE "guild_id" = $1;`
+	return fetchVal(ctx, guildId, &guildData.NamingScheme, convert(queryValue(db, query, database.Id), dtoconv.NamingSchemeToDto))
 }
 
-func (d *Daemon) fetchOnCallUsers(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.OnCallUsers, d.database.OnCall.GetUsersOnCall)
+func (d *Daemon) fetchOnCallUsers(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT "user_id"
+FROM on_call
+WHERE "guild_id" = $1 AND "is_on_call" = true AND "user_id" > $3
+ORDER BY "user_id" LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.OnCallUsers, query, idKey, func(rows pgx.Rows) (uint64, error) {
+		var userId uint64
+		if err := rows.Scan(&userId); err != nil {
+			return 0, err
+		}
+
+		return userId, nil
+	})
 }
 
-func (d *Daemon) fetchPanelAccessControlRules(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.PanelAccessControlRules, d.database.PanelAccessControlRules.GetAllForGuild)
+func (d *Daemon) fetchPanelAccessControlRules(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	// Rules are evaluated in order of their position, so the order must be kept
+	query := `
+SELECT r.panel_id, r.position, r.role_id, r.action
+FROM panel_access_control_rules r
+INNER JOIN panels p
+ON p.panel_id = r.panel_id
+WHERE p.guild_id = $1 AND (r.panel_id, r.position) > ($3, $4)
+ORDER BY r.panel_id, r.position LIMIT $2;`
+
+	type response struct {
+		PanelId  int
+		Position int
+		Rule     database.PanelAccessControlRule
+	}
+
+	var res []response
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.PanelId, r.Position} }, func(rows pgx.Rows) (response, error) {
+		var data response
+		if err := rows.Scan(&data.PanelId, &data.Position, &data.Rule.RoleId, &data.Rule.Action); err != nil {
+			return response{}, err
+		}
+
+		return data, nil
+	}); err != nil {
+		return err
+	}
+
+	m := make(map[int][]database.PanelAccessControlRule)
+	for _, r := range res {
+		m[r.PanelId] = append(m[r.PanelId], r.Rule)
+	}
+
+	guildData.PanelAccessControlRules = dtoconv.PanelAccessControlRulesToDto(m)
+	return nil
 }
 
-func (d *Daemon) fetchPanelMentionUser(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchPanelMentionUser(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT m.panel_id, m.should_mention_user
 FROM panel_user_mentions m
 INNER JOIN panels p
 ON m.panel_id = p.panel_id
-WHERE p.guild_id = $1
-ORDER BY m.panel_id ASC LIMIT $2 OFFSET $3;`
+WHERE p.guild_id = $1 AND m.panel_id > $3
+ORDER BY m.panel_id LIMIT $2;`
 
-	return fetchMap(ctx, d.database, guildId, &guildData.PanelMentionUser, query)
+	return fetchMap(ctx, db, guildId, &guildData.PanelMentionUser, query)
 }
 
-func (d *Daemon) fetchPanelRoleMentions(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchPanelRoleMentions(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT m.panel_id, m.role_id
 FROM panel_role_mentions m
 INNER JOIN panels p
 ON m.panel_id = p.panel_id
-WHERE p.guild_id = $1
-ORDER BY m.panel_id ASC LIMIT $2 OFFSET $3;`
+WHERE p.guild_id = $1 AND (m.panel_id, m.role_id) > ($3, $4)
+ORDER BY m.panel_id, m.role_id LIMIT $2;`
 
 	type response struct {
 		PanelId int
@@ -490,7 +678,7 @@ ORDER BY m.panel_id ASC LIMIT $2 OFFSET $3;`
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.PanelId, r.RoleId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.PanelId, &data.RoleId); err != nil {
 			return response{}, err
@@ -510,18 +698,84 @@ ORDER BY m.panel_id ASC LIMIT $2 OFFSET $3;`
 	return nil
 }
 
-func (d *Daemon) fetchPanels(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.Panels, d.database.Panel.GetByGuild)
+func (d *Daemon) fetchPanels(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT
+	panel_id,
+	message_id,
+	channel_id,
+	guild_id,
+	title,
+	content,
+	colour,
+	target_category,
+	emoji_name,
+	emoji_id,
+	welcome_message,
+	default_team,
+	custom_id,
+	image_url,
+	thumbnail_url,
+	button_style,
+	button_label,
+	form_id,
+	naming_scheme,
+	force_disabled,
+	disabled,
+	exit_survey_form_id,
+	pending_category
+FROM panels
+WHERE guild_id = $1 AND panel_id > $3
+ORDER BY panel_id LIMIT $2;`
+
+	var panels []database.Panel
+	if err := fetchCustomPaginated(ctx, db, guildId, &panels, query, func(p database.Panel) []interface{} { return []interface{}{p.PanelId} }, func(rows pgx.Rows) (database.Panel, error) {
+		var p database.Panel
+		if err := rows.Scan(
+			&p.PanelId,
+			&p.MessageId,
+			&p.ChannelId,
+			&p.GuildId,
+			&p.Title,
+			&p.Content,
+			&p.Colour,
+			&p.TargetCategory,
+			&p.EmojiName,
+			&p.EmojiId,
+			&p.WelcomeMessageEmbed,
+			&p.WithDefaultTeam,
+			&p.CustomId,
+			&p.ImageUrl,
+			&p.ThumbnailUrl,
+			&p.ButtonStyle,
+			&p.ButtonLabel,
+			&p.FormId,
+			&p.NamingScheme,
+			&p.ForceDisabled,
+			&p.Disabled,
+			&p.ExitSurveyFormId,
+			&p.PendingCategory,
+		); err != nil {
+			return database.Panel{}, err
+		}
+
+		return p, nil
+	}); err != nil {
+		return err
+	}
+
+	guildData.Panels = dtoconv.PanelsToDto(panels)
+	return nil
 }
 
-func (d *Daemon) fetchPanelTeams(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchPanelTeams(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT t.panel_id, t.team_id
 FROM panel_teams t
 INNER JOIN panels p
 ON t.panel_id = p.panel_id
-WHERE p.guild_id = $1
-ORDER BY t.panel_id ASC LIMIT $2 OFFSET $3;`
+WHERE p.guild_id = $1 AND (t.panel_id, t.team_id) > ($3, $4)
+ORDER BY t.panel_id, t.team_id LIMIT $2;`
 
 	type response struct {
 		PanelId int
@@ -529,7 +783,7 @@ ORDER BY t.panel_id ASC LIMIT $2 OFFSET $3;`
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.PanelId, r.TeamId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.PanelId, &data.TeamId); err != nil {
 			return response{}, err
@@ -549,12 +803,12 @@ ORDER BY t.panel_id ASC LIMIT $2 OFFSET $3;`
 	return nil
 }
 
-func (d *Daemon) fetchParticipants(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchParticipants(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, user_id
 FROM participant
-WHERE guild_id = $1
-ORDER BY ticket_id, user_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND (ticket_id, user_id) > ($3, $4)
+ORDER BY ticket_id, user_id LIMIT $2;`
 
 	type response struct {
 		TicketId int
@@ -562,7 +816,7 @@ ORDER BY ticket_id, user_id ASC LIMIT $2 OFFSET $3;`
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.TicketId, r.UserId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.TicketId, &data.UserId); err != nil {
 			return response{}, err
@@ -582,14 +836,14 @@ ORDER BY ticket_id, user_id ASC LIMIT $2 OFFSET $3;`
 	return nil
 }
 
-func (d *Daemon) fetchUserPermissions(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchUserPermissions(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT user_id, support, admin
 FROM permissions
-WHERE guild_id = $1
-ORDER BY user_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND user_id > $3
+ORDER BY user_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.UserPermissions, query, func(rows pgx.Rows) (dto.Permission, error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.UserPermissions, query, permissionKey, func(rows pgx.Rows) (dto.Permission, error) {
 		var permission dto.Permission
 		if err := rows.Scan(&permission.Snowflake, &permission.IsSupport, &permission.IsAdmin); err != nil {
 			return dto.Permission{}, err
@@ -599,14 +853,14 @@ ORDER BY user_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchGuildBlacklistedRoles(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchGuildBlacklistedRoles(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT role_id
 FROM role_blacklist
-WHERE guild_id = $1
-ORDER BY role_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND role_id > $3
+ORDER BY role_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.GuildBlacklistedRoles, query, func(rows pgx.Rows) (uint64, error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.GuildBlacklistedRoles, query, idKey, func(rows pgx.Rows) (uint64, error) {
 		var roleId uint64
 		if err := rows.Scan(&roleId); err != nil {
 			return 0, err
@@ -616,14 +870,14 @@ ORDER BY role_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchRolePermissions(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchRolePermissions(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT role_id, support, admin
 FROM role_permissions
-WHERE guild_id = $1
-ORDER BY role_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND role_id > $3
+ORDER BY role_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.RolePermissions, query, func(rows pgx.Rows) (dto.Permission, error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.RolePermissions, query, permissionKey, func(rows pgx.Rows) (dto.Permission, error) {
 		var permission dto.Permission
 		if err := rows.Scan(&permission.Snowflake, &permission.IsSupport, &permission.IsAdmin); err != nil {
 			return dto.Permission{}, err
@@ -633,14 +887,14 @@ ORDER BY role_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchServiceRatings(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchServiceRatings(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, rating
 FROM service_ratings
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.ServiceRatings, query, func(rows pgx.Rows) (dto.TicketUnion[int16], error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.ServiceRatings, query, ticketUnionKey, func(rows pgx.Rows) (dto.TicketUnion[int16], error) {
 		var res dto.TicketUnion[int16]
 		if err := rows.Scan(&res.TicketId, &res.Data); err != nil {
 			return dto.TicketUnion[int16]{}, err
@@ -650,18 +904,58 @@ ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchSettings(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.Settings, d.database.Settings.Get)
+func (d *Daemon) fetchSettings(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT
+	"hide_claim_button",
+	"disable_open_command",
+	"context_menu_permission_level",
+	"context_menu_add_sender",
+	"context_menu_panel",
+	"store_transcripts",
+	"use_threads",
+	"ticket_notification_channel",
+	"thread_archive_duration",
+	"overflow_enabled",
+	"overflow_category_id",
+	"anonymise_dashboard_responses"
+FROM settings
+WHERE "guild_id" = $1;`
+
+	return fetchValNoPtr(ctx, guildId, &guildData.Settings, convert(func(ctx context.Context, guildId uint64) (database.Settings, error) {
+		// Defaults for guilds that have never changed their settings, as in the database package
+		settings := database.Settings{
+			ContextMenuAddSender:  true,
+			StoreTranscripts:      true,
+			ThreadArchiveDuration: 10080,
+		}
+
+		err := scanRow(ctx, db, guildId, query,
+			&settings.HideClaimButton,
+			&settings.DisableOpenCommand,
+			&settings.ContextMenuPermissionLevel,
+			&settings.ContextMenuAddSender,
+			&settings.ContextMenuPanel,
+			&settings.StoreTranscripts,
+			&settings.UseThreads,
+			&settings.TicketNotificationChannel,
+			&settings.ThreadArchiveDuration,
+			&settings.OverflowEnabled,
+			&settings.OverflowCategoryId,
+			&settings.AnonymiseDashboardResponses,
+		)
+		return settings, err
+	}, dtoconv.SettingsToDto))
 }
 
-func (d *Daemon) fetchSupportTeamUsers(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchSupportTeamUsers(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT team_id, user_id
 FROM support_team_members
 INNER JOIN support_team
 ON support_team_members.team_id = support_team.id
-WHERE support_team.guild_id = $1
-ORDER BY team_id, user_id ASC LIMIT $2 OFFSET $3;`
+WHERE support_team.guild_id = $1 AND (team_id, user_id) > ($3, $4)
+ORDER BY team_id, user_id LIMIT $2;`
 
 	type response struct {
 		TeamId int
@@ -669,7 +963,7 @@ ORDER BY team_id, user_id ASC LIMIT $2 OFFSET $3;`
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.TeamId, r.UserId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.TeamId, &data.UserId); err != nil {
 			return response{}, err
@@ -689,14 +983,14 @@ ORDER BY team_id, user_id ASC LIMIT $2 OFFSET $3;`
 	return nil
 }
 
-func (d *Daemon) fetchSupportTeamRoles(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchSupportTeamRoles(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT team_id, role_id
 FROM support_team_roles
 INNER JOIN support_team
 ON support_team_roles.team_id = support_team.id
-WHERE support_team.guild_id = $1
-ORDER BY team_id, role_id ASC LIMIT $2 OFFSET $3;`
+WHERE support_team.guild_id = $1 AND (team_id, role_id) > ($3, $4)
+ORDER BY team_id, role_id LIMIT $2;`
 
 	type response struct {
 		TeamId int
@@ -704,7 +998,7 @@ ORDER BY team_id, role_id ASC LIMIT $2 OFFSET $3;`
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.TeamId, r.RoleId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.TeamId, &data.RoleId); err != nil {
 			return response{}, err
@@ -724,34 +1018,79 @@ ORDER BY team_id, role_id ASC LIMIT $2 OFFSET $3;`
 	return nil
 }
 
-func (d *Daemon) fetchSupportTeams(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.SupportTeams, d.database.SupportTeam.Get)
+func (d *Daemon) fetchSupportTeams(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT id, guild_id, name, on_call_role_id
+FROM support_team
+WHERE guild_id = $1 AND id > $3
+ORDER BY id LIMIT $2;`
+
+	var teams []database.SupportTeam
+	if err := fetchCustomPaginated(ctx, db, guildId, &teams, query, func(t database.SupportTeam) []interface{} { return []interface{}{t.Id} }, func(rows pgx.Rows) (database.SupportTeam, error) {
+		var team database.SupportTeam
+		if err := rows.Scan(&team.Id, &team.GuildId, &team.Name, &team.OnCallRole); err != nil {
+			return database.SupportTeam{}, err
+		}
+
+		return team, nil
+	}); err != nil {
+		return err
+	}
+
+	guildData.SupportTeams = dtoconv.SupportTeamsToDto(teams)
+	return nil
 }
 
-func (d *Daemon) fetchTags(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.Tags, func(ctx context.Context, guildId uint64) ([]database.Tag, error) {
-		tags, err := d.database.Tag.GetByGuild(ctx, guildId)
-		if err != nil {
-			return nil, err
+func (d *Daemon) fetchTags(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `
+SELECT tag_id, LOWER(tag_id), guild_id, content, embed, application_command_id
+FROM tags
+WHERE guild_id = $1 AND tag_id > $3
+ORDER BY tag_id LIMIT $2;`
+
+	// Tags are exported with lowercase IDs, but must be paged through by the ID as stored
+	type response struct {
+		TagId string
+		Tag   dto.Tag
+	}
+
+	var res []response
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.TagId} }, func(rows pgx.Rows) (response, error) {
+		var data response
+		var tag database.Tag
+		var embedRaw *string
+		if err := rows.Scan(&data.TagId, &tag.Id, &tag.GuildId, &tag.Content, &embedRaw, &tag.ApplicationCommandId); err != nil {
+			return response{}, err
 		}
 
-		values := make([]database.Tag, 0, len(tags))
-		for _, tag := range tags {
-			values = append(values, tag)
+		if embedRaw != nil {
+			if err := json.Unmarshal([]byte(*embedRaw), &tag.Embed); err != nil {
+				return response{}, err
+			}
 		}
 
-		return values, nil
-	})
+		data.Tag = dtoconv.TagToDto(tag)
+		return data, nil
+	}); err != nil {
+		return err
+	}
+
+	guildData.Tags = make([]dto.Tag, len(res))
+	for i, r := range res {
+		guildData.Tags[i] = r.Tag
+	}
+
+	return nil
 }
 
-func (d *Daemon) fetchTicketClaims(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchTicketClaims(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, user_id
 FROM ticket_claims
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.TicketClaims, query, func(rows pgx.Rows) (dto.TicketUnion[uint64], error) {
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.TicketClaims, query, ticketUnionKey, func(rows pgx.Rows) (dto.TicketUnion[uint64], error) {
 		var res dto.TicketUnion[uint64]
 		if err := rows.Scan(&res.TicketId, &res.Data); err != nil {
 			return dto.TicketUnion[uint64]{}, err
@@ -761,36 +1100,34 @@ ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
 	})
 }
 
-func (d *Daemon) fetchTicketLastMessages(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchTicketLastMessages(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, last_message_id, last_message_time, user_id, user_is_staff
 FROM ticket_last_message
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND ticket_id > $3
+ORDER BY ticket_id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.TicketLastMessages, query, func(rows pgx.Rows) (dto.TicketUnion[database.TicketLastMessage], error) {
-		var res dto.TicketUnion[database.TicketLastMessage]
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.TicketLastMessages, query, ticketUnionKey, func(rows pgx.Rows) (dto.TicketUnion[dto.TicketLastMessage], error) {
+		var res dto.TicketUnion[dto.TicketLastMessage]
 		if err := rows.Scan(&res.TicketId, &res.Data.LastMessageId, &res.Data.LastMessageTime, &res.Data.UserId, &res.Data.UserIsStaff); err != nil {
-			return dto.TicketUnion[database.TicketLastMessage]{}, err
+			return dto.TicketUnion[dto.TicketLastMessage]{}, err
 		}
 
 		return res, nil
 	})
 }
 
-func (d *Daemon) fetchTicketLimit(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.TicketLimit, func(ctx context.Context, guildId uint64) (int, error) {
-		limit, err := d.database.TicketLimit.Get(ctx, guildId)
-		return int(limit), err
-	})
+func (d *Daemon) fetchTicketLimit(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "limit" FROM ticket_limit WHERE "guild_id" = $1;`
+	return fetchVal(ctx, guildId, &guildData.TicketLimit, queryValue(db, query, 5))
 }
 
-func (d *Daemon) fetchTicketAdditionalMembers(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchTicketAdditionalMembers(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT ticket_id, user_id
 FROM ticket_members
-WHERE guild_id = $1
-ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND (ticket_id, user_id) > ($3, $4)
+ORDER BY ticket_id, user_id LIMIT $2;`
 
 	type response struct {
 		TicketId int
@@ -798,7 +1135,7 @@ ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
 	}
 
 	var res []response
-	if err := fetchCustomPaginated(ctx, d.database, guildId, &res, query, func(rows pgx.Rows) (response, error) {
+	if err := fetchCustomPaginated(ctx, db, guildId, &res, query, func(r response) []interface{} { return []interface{}{r.TicketId, r.UserId} }, func(rows pgx.Rows) (response, error) {
 		var data response
 		if err := rows.Scan(&data.TicketId, &data.UserId); err != nil {
 			return response{}, err
@@ -818,19 +1155,30 @@ ORDER BY ticket_id ASC LIMIT $2 OFFSET $3;`
 	return nil
 }
 
-func (d *Daemon) fetchTicketPermissions(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.TicketPermissions, d.database.TicketPermissions.Get)
+func (d *Daemon) fetchTicketPermissions(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "attach_files", "embed_links", "add_reactions" FROM ticket_permissions WHERE "guild_id" = $1;`
+
+	return fetchValNoPtr(ctx, guildId, &guildData.TicketPermissions, convert(func(ctx context.Context, guildId uint64) (database.TicketPermissions, error) {
+		permissions := database.TicketPermissions{
+			AttachFiles:  true,
+			EmbedLinks:   true,
+			AddReactions: true,
+		}
+
+		err := scanRow(ctx, db, guildId, query, &permissions.AttachFiles, &permissions.EmbedLinks, &permissions.AddReactions)
+		return permissions, err
+	}, dtoconv.TicketPermissionsToDto))
 }
 
-func (d *Daemon) fetchTickets(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
+func (d *Daemon) fetchTickets(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
 	query := `
 SELECT id, guild_id, channel_id, user_id, open, open_time, welcome_message_id, panel_id, has_transcript, close_time, is_thread, join_message_id, notes_thread_id, status
 FROM tickets
-WHERE guild_id = $1
-ORDER BY id ASC LIMIT $2 OFFSET $3;`
+WHERE guild_id = $1 AND id > $3
+ORDER BY id LIMIT $2;`
 
-	return fetchCustomPaginated(ctx, d.database, guildId, &guildData.Tickets, query, func(rows pgx.Rows) (database.Ticket, error) {
-		var ticket database.Ticket
+	return fetchCustomPaginated(ctx, db, guildId, &guildData.Tickets, query, func(t dto.Ticket) []interface{} { return []interface{}{t.Id} }, func(rows pgx.Rows) (dto.Ticket, error) {
+		var ticket dto.Ticket
 		if err := rows.Scan(
 			&ticket.Id,
 			&ticket.GuildId,
@@ -847,19 +1195,21 @@ ORDER BY id ASC LIMIT $2 OFFSET $3;`
 			&ticket.NotesThreadId,
 			&ticket.Status,
 		); err != nil {
-			return database.Ticket{}, err
+			return dto.Ticket{}, err
 		}
 
 		return ticket, nil
 	})
 }
 
-func (d *Daemon) fetchUsersCanClose(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchValNoPtr(ctx, guildId, &guildData.UsersCanClose, d.database.UsersCanClose.Get)
+func (d *Daemon) fetchUsersCanClose(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "users_can_close" FROM users_can_close WHERE "guild_id" = $1;`
+	return fetchValNoPtr(ctx, guildId, &guildData.UsersCanClose, queryValue(db, query, true))
 }
 
-func (d *Daemon) fetchWelcomeMessage(ctx context.Context, guildId uint64, guildData *dto.GuildData) error {
-	return fetchVal(ctx, guildId, &guildData.WelcomeMessage, d.database.WelcomeMessages.Get)
+func (d *Daemon) fetchWelcomeMessage(ctx context.Context, db querier, guildId uint64, guildData *dto.GuildData) error {
+	query := `SELECT "welcome_message" FROM welcome_messages WHERE "guild_id" = $1;`
+	return fetchVal(ctx, guildId, &guildData.WelcomeMessage, queryValue(db, query, ""))
 }
 
 func fetchVal[T comparable](
@@ -911,21 +1261,65 @@ func fetchPtr[T any](
 	return nil
 }
 
+// queryValue returns a getter for a single column of the guild's row in a per-guild settings table, returning def if
+// the guild has no row, as the equivalent getters in the database package do.
+func queryValue[T any](db querier, query string, def T) func(context.Context, uint64) (T, error) {
+	return func(ctx context.Context, guildId uint64) (T, error) {
+		value := def
+		if err := scanRow(ctx, db, guildId, query, &value); err != nil {
+			return def, err
+		}
+
+		return value, nil
+	}
+}
+
+// scanRow scans the guild's row in a per-guild settings table into dest. If the guild has no row, dest is left
+// unchanged, so should already hold the defaults.
+func scanRow(ctx context.Context, db querier, guildId uint64, query string, dest ...interface{}) error {
+	if err := db.QueryRow(ctx, query, guildId).Scan(dest...); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// convert wraps a database getter, converting its result to the equivalent DTO.
+func convert[T, U any](
+	f func(context.Context, uint64) (T, error),
+	c func(T) U,
+) func(context.Context, uint64) (U, error) {
+	return func(ctx context.Context, id uint64) (U, error) {
+		data, err := f(ctx, id)
+		if err != nil {
+			return *new(U), err
+		}
+
+		return c(data), nil
+	}
+}
+
 const paginationLimit = 2_500
 
+// fetchCustomPaginated reads every row returned by query, a page at a time. Pages are fetched by keyset rather than by
+// offset, so that each page starts from an index lookup instead of re-reading every row before it. The query is called
+// with the ID, the page size, and then the values returned by key for the last row of the previous page. It must
+// order rows by those values, which must be unique, and only return rows that sort after them.
 func fetchCustomPaginated[T any](
 	ctx context.Context,
-	db *database.Database,
-	guildId uint64,
+	db querier,
+	id uint64,
 	ptr *[]T,
 	query string,
+	key func(T) []interface{},
 	de func(rows pgx.Rows) (T, error),
 ) error {
 	res := make([]T, 0)
-	count := 0
+	var zero T
+	after := firstKey(key(zero))
 	hasMore := true
 	for hasMore {
-		rows, err := db.ArchiveMessages.Query(ctx, query, guildId, paginationLimit, count)
+		rows, err := db.Query(ctx, query, append([]interface{}{id, paginationLimit}, after...)...)
 		if err != nil {
 			return err
 		}
@@ -934,6 +1328,7 @@ func fetchCustomPaginated[T any](
 		for rows.Next() {
 			row, err := de(rows)
 			if err != nil {
+				rows.Close()
 				return err
 			}
 
@@ -941,7 +1336,16 @@ func fetchCustomPaginated[T any](
 			thisCount++
 		}
 
-		count += thisCount
+		// Rows must be closed before the connection is used again, which matters when reading from a snapshot
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if thisCount > 0 {
+			after = key(res[len(res)-1])
+		}
+
 		hasMore = thisCount == paginationLimit
 	}
 
@@ -949,18 +1353,21 @@ func fetchCustomPaginated[T any](
 	return nil
 }
 
+// fetchMap reads every key-value pair returned by query into a map, paging through them by key in the same way as
+// fetchCustomPaginated.
 func fetchMap[T comparable, U any](
 	ctx context.Context,
-	db *database.Database,
+	db querier,
 	guildId uint64,
 	ptr *map[T]U,
 	query string,
 ) error {
 	res := make(map[T]U)
-	var count int
+	var zero T
+	after := firstKey([]interface{}{zero})
 	hasMore := true
 	for hasMore {
-		rows, err := db.ArchiveMessages.Query(ctx, query, guildId, paginationLimit, count)
+		rows, err := db.Query(ctx, query, guildId, paginationLimit, after[0])
 		if err != nil {
 			return err
 		}
@@ -970,17 +1377,59 @@ func fetchMap[T comparable, U any](
 			var key T
 			var value U
 			if err := rows.Scan(&key, &value); err != nil {
+				rows.Close()
 				return err
 			}
 
 			res[key] = value
+			after[0] = key
 			thisCount++
 		}
 
-		count += thisCount
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
 		hasMore = thisCount == paginationLimit
 	}
 
 	*ptr = res
 	return nil
 }
+
+// firstKey returns a key that sorts before that of every row, for fetching the first page. Every key column is either
+// a non-negative integer, such as an ID or a snowflake, or text, so the types of the values in key are enough.
+func firstKey(key []interface{}) []interface{} {
+	first := make([]interface{}, len(key))
+	for i, value := range key {
+		if _, ok := value.(string); ok {
+			first[i] = ""
+		} else {
+			first[i] = -1
+		}
+	}
+
+	return first
+}
+
+func idKey[T int | uint64](id T) []interface{} {
+	return []interface{}{id}
+}
+
+func ticketUnionKey[T any](union dto.TicketUnion[T]) []interface{} {
+	return []interface{}{union.TicketId}
+}
+
+func permissionKey(permission dto.Permission) []interface{} {
+	return []interface{}{permission.Snowflake}
+}
+
+func valueOrZero[T any](ptr *T) T {
+	if ptr == nil {
+		var zero T
+		return zero
+	}
+
+	return *ptr
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/internal/worker/redact"
+	"github.com/TicketsBot/export/pkg/dto"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"sync"
+)
+
+// handleGuildFullTask exports a guild's data and transcripts into a single archive. Rather than signing each file, as
+// the guild data and transcripts exports do, every file is hashed into index.json, and only the index is signed.
+func (d *Daemon) handleGuildFullTask(ctx context.Context, task model.Task, request model.Request) error {
+	if request.GuildId == nil || *request.GuildId == 0 {
+		d.logger.Error("Guild ID is nil", slog.String("task_id", task.Id.String()))
+		return fmt.Errorf("guild ID is nil")
+	}
+
+	guildId := *request.GuildId
+
+	logger := d.logger.With(slog.Uint64("guild_id", guildId), "request_id", request.Id)
+
+	redactor, err := redact.New(request.Options.Redaction)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to create redactor", "error", err)
+		return err
+	}
+
+	sections := request.Options.GuildDataSections()
+
+	data, err := d.fetchGuildData(ctx, logger, guildId, sections)
+	if err != nil {
+		return err
+	}
+
+	redactor.GuildData(&data)
+
+	transcripts, err := d.transcripts.GetTranscriptsForGuild(ctx, guildId)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get transcripts for guild", "error", err)
+		return err
+	}
+
+	logger.InfoContext(ctx, "Got transcripts for guild")
+
+	files := make(map[string][]byte)
+	mu := sync.Mutex{}
+
+	failed := transcripts.Failed
+	group, _ := errgroup.WithContext(ctx)
+	group.SetLimit(d.config.Daemon.SigningWorkers)
+
+	for ticketId, transcript := range transcripts.Transcripts {
+		ticketId, transcript := ticketId, transcript
+
+		group.Go(func() error {
+			// A transcript that can't be redacted is left out, rather than exported unredacted
+			redacted, err := redactor.Transcript(transcript)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				logger.WarnContext(ctx, "Failed to redact transcript", "ticket_id", ticketId, "error", err)
+				failed = append(failed, ticketId)
+				return nil
+			}
+
+			files[transcriptFileName(ticketId)] = redacted
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		logger.ErrorContext(ctx, "Failed to redact transcripts", "error", err)
+		return err
+	}
+
+	// Link each ticket to its transcript, so that consumers don't need to know how transcript files are named
+	for i, ticket := range data.Tickets {
+		name := transcriptFileName(ticket.Id)
+		if _, ok := files[name]; ok {
+			data.Tickets[i].TranscriptFile = &name
+		}
+	}
+
+	marshalled, err := json.Marshal(data)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to marshal data", "error", err)
+		return err
+	}
+
+	files["data.json"] = marshalled
+
+	metadata, err := buildMetadata(request, guildId, redactor, sections)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to build metadata", "error", err)
+		return err
+	}
+
+	files["metadata.json"] = metadata
+
+	// Covered by the signed index, like every other file
+	d.addFailed(files, failed, false)
+
+	if err := d.addIndex(files, guildId); err != nil {
+		logger.ErrorContext(ctx, "Failed to build index", "error", err)
+		return err
+	}
+
+	return d.uploadArtifact(ctx, logger, request, files)
+}
+
+// addIndex adds a signed index.json to the export's files, listing the SHA-256 hash of every other file.
+func (d *Daemon) addIndex(files map[string][]byte, guildId uint64) error {
+	index := dto.ExportIndex{
+		GuildId: guildId,
+		Files:   make(map[string]string, len(files)),
+	}
+
+	for name, content := range files {
+		hash := sha256.Sum256(content)
+		index.Files[name] = hex.EncodeToString(hash[:])
+	}
+
+	marshalled, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	files["index.json"] = marshalled
+	files["index.json.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled)))
+	return nil
+}
+
+func transcriptFileName(ticketId int) string {
+	return fmt.Sprintf("transcripts/%d.json", ticketId)
+}
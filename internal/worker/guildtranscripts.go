@@ -3,13 +3,17 @@ package worker
 import (
 	"context"
 	"crypto/ed25519"
+	"encoding/json"
 	"fmt"
-	"github.com/TicketsBot/export/internal/metrics"
 	"github.com/TicketsBot/export/internal/model"
-	"github.com/TicketsBot/export/internal/repository"
 	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/internal/worker/redact"
+	"github.com/TicketsBot/export/internal/worker/render"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/jackc/pgx/v4"
 	"golang.org/x/sync/errgroup"
 	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,15 +48,29 @@ func (d *Daemon) handleGuildTranscriptsTask(ctx context.Context, task model.Task
 	files["guild_id.txt"] = []byte(guildIdStr)
 	files["guild_id.txt.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, []byte(guildIdStr))))
 
-	if len(transcripts.Failed) > 0 {
-		marshalled := make([]string, 0, len(transcripts.Failed))
-		for _, ticketId := range transcripts.Failed {
-			marshalled = append(marshalled, strconv.Itoa(ticketId))
+	redactor, err := redact.New(request.Options.Redaction)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to create redactor", "error", err)
+		return err
+	}
+
+	if err := d.addMetadata(files, request, guildId, redactor, nil); err != nil {
+		logger.ErrorContext(ctx, "Failed to build metadata", "error", err)
+		return err
+	}
+
+	var tickets map[int]render.Ticket
+	if request.Options.RenderHtml {
+		tickets, err = d.fetchRenderTickets(ctx, guildId)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to fetch tickets for rendering", "error", err)
+			return err
 		}
 
-		content := []byte("The following tickets failed to export:\n" + strings.Join(marshalled, ", "))
-		files["failed.txt"] = content
-		files["failed.txt.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, content)))
+		for id, ticket := range tickets {
+			ticket.UserId = redactor.UserId(ticket.UserId)
+			tickets[id] = ticket
+		}
 	}
 
 	type transcriptData struct {
@@ -60,12 +78,27 @@ func (d *Daemon) handleGuildTranscriptsTask(ctx context.Context, task model.Task
 		transcript []byte
 	}
 
+	failed := transcripts.Failed
+	var exported []int
 	ch := make(chan transcriptData, len(transcripts.Transcripts))
 	group, _ := errgroup.WithContext(ctx)
 
 	for i := 0; i < d.config.Daemon.SigningWorkers; i++ {
 		group.Go(func() error {
 			for data := range ch {
+				// A transcript that can't be redacted is left out, rather than exported unredacted
+				transcript, err := redactor.Transcript(data.transcript)
+				if err != nil {
+					logger.WarnContext(ctx, "Failed to redact transcript", "ticket_id", data.ticketId, "error", err)
+
+					mu.Lock()
+					failed = append(failed, data.ticketId)
+					mu.Unlock()
+					continue
+				}
+
+				data.transcript = transcript
+
 				sigData := make([]byte, 0, len(data.transcript)+len(guildIdStr)+2+6)
 				sigData = append(sigData, []byte(guildIdStr)...)
 				sigData = append(sigData, byte('|'))
@@ -75,9 +108,29 @@ func (d *Daemon) handleGuildTranscriptsTask(ctx context.Context, task model.Task
 
 				signed := []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, sigData)))
 
+				// A transcript that can't be rendered is still exported, as the JSON is the source of truth
+				var rendered []byte
+				if request.Options.RenderHtml {
+					ticket, ok := tickets[data.ticketId]
+					if !ok {
+						ticket = render.Ticket{Id: data.ticketId}
+					}
+
+					var err error
+					rendered, err = render.Transcript(guildId, ticket, data.transcript)
+					if err != nil {
+						logger.WarnContext(ctx, "Failed to render transcript", "ticket_id", data.ticketId, "error", err)
+					}
+				}
+
 				mu.Lock()
 				files[fmt.Sprintf("transcripts/%d.json", data.ticketId)] = data.transcript
 				files[fmt.Sprintf("transcripts/%d.json.sig", data.ticketId)] = signed
+				if rendered != nil {
+					files[fmt.Sprintf("transcripts/%d.html", data.ticketId)] = rendered
+					files[fmt.Sprintf("transcripts/%d.html.sig", data.ticketId)] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, rendered)))
+				}
+				exported = append(exported, data.ticketId)
 				mu.Unlock()
 			}
 
@@ -98,54 +151,93 @@ func (d *Daemon) handleGuildTranscriptsTask(ctx context.Context, task model.Task
 		return err
 	}
 
-	artifact, err := utils.BuildZip(d.config, files)
+	// The manifest lets the validator detect transcripts that were removed from the archive
+	sort.Ints(exported)
+	manifest, err := json.Marshal(dto.TranscriptManifest{
+		GuildId:     guildId,
+		Transcripts: exported,
+	})
 	if err != nil {
-		d.logger.Error("Failed to build zip", err)
+		logger.ErrorContext(ctx, "Failed to marshal manifest", "error", err)
 		return err
 	}
 
-	artifactSize := int64(len(artifact))
+	files["manifest.json"] = manifest
+	files["manifest.json.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, manifest)))
 
-	var globalArtifactSize int64
-	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) (err error) {
-		globalArtifactSize, err = tx.Artifacts().GetGlobalSize(ctx)
-		return err
+	d.addFailed(files, failed, true)
+
+	if request.Options.RenderHtml {
+		index := make([]render.Ticket, 0, len(tickets))
+		for _, ticket := range tickets {
+			_, ticket.HasTranscript = files[fmt.Sprintf("transcripts/%d.html", ticket.Id)]
+			index = append(index, ticket)
+		}
+
+		sort.Slice(index, func(i, j int) bool {
+			return index[i].Id < index[j].Id
+		})
+
+		rendered, err := render.Index(guildId, index)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to render index", "error", err)
+			return err
+		}
+
+		files["index.html"] = rendered
+		files["index.html.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, rendered)))
+	}
+
+	return d.uploadArtifact(ctx, logger, request, files)
+}
+
+func (d *Daemon) fetchRenderTickets(ctx context.Context, guildId uint64) (map[int]render.Ticket, error) {
+	query := `
+SELECT t.id, t.user_id, t.open_time, t.close_time, p.title
+FROM tickets t
+LEFT OUTER JOIN panels p
+ON t.panel_id = p.panel_id
+WHERE t.guild_id = $1 AND t.id > $3
+ORDER BY t.id LIMIT $2;`
+
+	var tickets []render.Ticket
+	if err := fetchCustomPaginated(ctx, d.pool, guildId, &tickets, query, func(t render.Ticket) []interface{} { return []interface{}{t.Id} }, func(rows pgx.Rows) (render.Ticket, error) {
+		var ticket render.Ticket
+		if err := rows.Scan(&ticket.Id, &ticket.UserId, &ticket.OpenTime, &ticket.CloseTime, &ticket.PanelTitle); err != nil {
+			return render.Ticket{}, err
+		}
+
+		return ticket, nil
 	}); err != nil {
-		d.logger.Error("Failed to get global artifact size", "error", err)
-		return err
+		return nil, err
 	}
 
-	if globalArtifactSize+artifactSize > maxActiveSize {
-		d.logger.Error("Artifact size exceeds maximum", slog.Int64("size", globalArtifactSize+artifactSize))
-		return fmt.Errorf("artifact size exceeds maximum")
+	res := make(map[int]render.Ticket, len(tickets))
+	for _, ticket := range tickets {
+		res[ticket.Id] = ticket
 	}
 
-	logger.InfoContext(ctx, "Uploading artifact", slog.Int64("size", artifactSize))
+	return res, nil
+}
 
-	key := utils.RandomString(32)
-	expiresAt := time.Now().Add(transcriptExpiry)
-	if err := d.artifacts.Store(ctx, request.Id, key, expiresAt, artifact); err != nil {
-		d.logger.Error("Failed to store artifact", "error", err)
-		return err
+// addFailed adds failed.txt to the export's files, listing the tickets that failed to export, if there were any. The
+// list is signed if sign is set.
+func (d *Daemon) addFailed(files map[string][]byte, failed []int, sign bool) {
+	if len(failed) == 0 {
+		return
 	}
 
-	metrics.ArtifactsUploaded.WithLabelValues(request.Type.String()).Inc()
-	metrics.ArtifactsUploadedBytes.WithLabelValues(request.Type.String()).Add(float64(artifactSize))
+	sort.Ints(failed)
 
-	if err := d.repository.Tx(ctx, func(ctx context.Context, tx repository.TransactionContext) error {
-		if err := tx.Requests().SetStatus(ctx, request.Id, model.RequestStatusCompleted); err != nil {
-			return err
-		}
+	ids := make([]string, 0, len(failed))
+	for _, ticketId := range failed {
+		ids = append(ids, strconv.Itoa(ticketId))
+	}
 
-		if err := tx.Artifacts().Create(ctx, request.Id, key, expiresAt, artifactSize); err != nil {
-			return err
-		}
+	content := []byte("The following tickets failed to export:\n" + strings.Join(ids, ", "))
+	files["failed.txt"] = content
 
-		return nil
-	}); err != nil {
-		d.logger.Error("Failed to update request status", "error", err)
-		return err
+	if sign {
+		files["failed.txt.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, content)))
 	}
-
-	return nil
 }
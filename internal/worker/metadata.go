@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/internal/worker/redact"
+	"github.com/TicketsBot/export/pkg/dto"
+	"time"
+)
+
+// addMetadata adds a signed metadata.json to the export's files, recording how the export was produced. sections is
+// only set for guild data exports.
+func (d *Daemon) addMetadata(
+	files map[string][]byte,
+	request model.Request,
+	guildId uint64,
+	redactor *redact.Redactor,
+	sections []model.GuildDataSection,
+) error {
+	marshalled, err := buildMetadata(request, guildId, redactor, sections)
+	if err != nil {
+		return err
+	}
+
+	files["metadata.json"] = marshalled
+	files["metadata.json.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled)))
+	return nil
+}
+
+func buildMetadata(
+	request model.Request,
+	guildId uint64,
+	redactor *redact.Redactor,
+	sections []model.GuildDataSection,
+) ([]byte, error) {
+	metadata := dto.ExportMetadata{
+		RequestId:   request.Id.String(),
+		RequestType: request.Type.String(),
+		GuildId:     guildId,
+		CreatedAt:   time.Now(),
+		Redaction:   redactor.Metadata(),
+	}
+
+	if sections != nil {
+		metadata.Sections = make([]string, len(sections))
+		for i, section := range sections {
+			metadata.Sections[i] = section.String()
+		}
+	}
+
+	return json.Marshal(metadata)
+}
@@ -0,0 +1,261 @@
+package worker
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/dto"
+	"os"
+	"sort"
+	"strings"
+)
+
+const partsFileName = "parts.json"
+
+// partPlan is the split of an export's files into parts of at most the configured maximum size, in the request's
+// archive format. Each part is written to a temporary file as the files are split, so that every file is only
+// compressed once, and the parts can then be stored one at a time, rather than holding every part in memory.
+//
+// If the export is split into several parts, every part also holds a signed parts.json, listing the files in each
+// part of the export. An export that fits in a single part does not, so that it is identical to an export made before
+// exports could be split.
+type partPlan struct {
+	format   model.ArchiveFormat
+	capacity int64 // The space for files in each part, after reserving space for parts.json
+	parts    []plannedPart
+}
+
+type plannedPart struct {
+	file   *os.File
+	writer utils.ArchiveWriter
+	names  []string
+	size   int64 // The size of the finished part, set by finish
+}
+
+// writeParts works out which files go in each part, and writes them. Files are kept together with their signatures. A
+// file larger than the maximum part size can't be split, so is put in a part of its own, which will exceed the
+// maximum. The parts are left open for parts.json to be added by finish, and remove must be called once they have
+// been stored.
+func (d *Daemon) writeParts(request model.Request, files map[string][]byte) (*partPlan, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	// Keep each file in the same part as its signature
+	groups := make([][]utils.ArchiveFile, 0, len(names))
+	groupIndex := make(map[string]int, len(names))
+	for _, name := range names {
+		file := utils.ArchiveFile{Name: name, Content: files[name]}
+		if i, ok := groupIndex[strings.TrimSuffix(name, ".sig")]; ok {
+			groups[i] = append(groups[i], file)
+		} else {
+			groupIndex[name] = len(groups)
+			groups = append(groups, []utils.ArchiveFile{file})
+		}
+	}
+
+	// Space for parts.json is reserved in every part, but its size depends on the number of parts, which isn't known
+	// until the files have been split. Every part holds at least one group, so reserve enough for a part per group.
+	maxPartSize := d.config.Daemon.MaxPartMegabytes * 1024 * 1024
+	reserved, err := partsIndexSize(request, names, len(groups))
+	if err != nil {
+		return nil, err
+	}
+
+	if reserved >= maxPartSize {
+		return nil, fmt.Errorf("maximum part size is too small to hold the index of %d files", len(names))
+	}
+
+	plan := &partPlan{
+		format:   request.Options.ArchiveFormat(),
+		capacity: maxPartSize - reserved,
+	}
+
+	if err := d.writeGroups(plan, groups); err != nil {
+		plan.remove()
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (d *Daemon) writeGroups(plan *partPlan, groups [][]utils.ArchiveFile) error {
+	for _, group := range groups {
+		if len(plan.parts) > 0 {
+			part := &plan.parts[len(plan.parts)-1]
+
+			added, err := part.writer.AddIfFits(plan.capacity, group...)
+			if err != nil {
+				return err
+			}
+
+			if added {
+				part.names = appendNames(part.names, group)
+				continue
+			}
+
+			// The part is full, but stays open until parts.json is added, so only its compressor is released
+			if err := part.writer.Suspend(); err != nil {
+				return err
+			}
+		}
+
+		file, err := os.CreateTemp("", "export-part-*")
+		if err != nil {
+			return err
+		}
+
+		plan.parts = append(plan.parts, plannedPart{file: file})
+		part := &plan.parts[len(plan.parts)-1]
+
+		if part.writer, err = utils.NewArchiveWriter(plan.format, d.config.Daemon.CompressionLevel, file); err != nil {
+			return err
+		}
+
+		if err := part.writer.Add(group...); err != nil {
+			return err
+		}
+
+		part.names = appendNames(nil, group)
+	}
+
+	return nil
+}
+
+// size returns the total size of the parts, once they have been finished.
+func (p *partPlan) size() int64 {
+	var size int64
+	for _, part := range p.parts {
+		size += part.size
+	}
+
+	return size
+}
+
+func appendNames(names []string, files []utils.ArchiveFile) []string {
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+
+	return names
+}
+
+// partsIndexSize returns an upper bound on the space taken up in each part by parts.json and its signature, if the
+// files were split into the given number of parts.
+func partsIndexSize(request model.Request, names []string, partCount int) (int64, error) {
+	// Hashes are a fixed length, so listing every file under a single part is as large as any other split
+	index := dto.ArchiveParts{
+		RequestId: request.Id.String(),
+		Part:      partCount,
+		Parts:     make([]dto.ArchivePart, partCount),
+	}
+
+	for i := range index.Parts {
+		index.Parts[i] = dto.ArchivePart{
+			Part:  partCount,
+			Files: make(map[string]string),
+		}
+	}
+
+	for _, name := range names {
+		index.Parts[0].Files[name] = strings.Repeat("0", sha256.Size*2)
+	}
+
+	marshalled, err := json.Marshal(index)
+	if err != nil {
+		return 0, err
+	}
+
+	// Compression adds at most a few bytes per block to data that doesn't compress, so allow for 1% more than the
+	// uncompressed size, plus the entries for parts.json and its signature.
+	size := int64(len(marshalled)) + int64(len(marshalled))/100 + 1024
+	size += 2 * (int64(2*len(partsFileName+".sig")) + 4096)
+
+	return size, nil
+}
+
+// partsIndex returns the contents of parts.json, listing the SHA-256 hash of every file in every part, or nil if the
+// export fits in a single part. Part is set by finish.
+func (p *partPlan) partsIndex(request model.Request, files map[string][]byte) *dto.ArchiveParts {
+	if len(p.parts) <= 1 {
+		return nil
+	}
+
+	index := &dto.ArchiveParts{
+		RequestId: request.Id.String(),
+		Parts:     make([]dto.ArchivePart, len(p.parts)),
+	}
+
+	for i, part := range p.parts {
+		index.Parts[i] = dto.ArchivePart{
+			Part:  i + 1,
+			Files: make(map[string]string, len(part.names)),
+		}
+
+		for _, name := range part.names {
+			hash := sha256.Sum256(files[name])
+			index.Parts[i].Files[name] = hex.EncodeToString(hash[:])
+		}
+	}
+
+	return index
+}
+
+// finish adds parts.json to each part if index is not nil, and closes the parts.
+func (d *Daemon) finish(plan *partPlan, index *dto.ArchiveParts) error {
+	for i := range plan.parts {
+		part := &plan.parts[i]
+
+		if index != nil {
+			index.Part = i + 1
+
+			marshalled, err := json.Marshal(index)
+			if err != nil {
+				return err
+			}
+
+			if err := part.writer.Add(
+				utils.ArchiveFile{Name: partsFileName, Content: marshalled},
+				utils.ArchiveFile{
+					Name:    partsFileName + ".sig",
+					Content: []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled))),
+				},
+			); err != nil {
+				return err
+			}
+		}
+
+		if err := part.writer.Close(); err != nil {
+			return err
+		}
+
+		info, err := part.file.Stat()
+		if err != nil {
+			return err
+		}
+
+		part.size = info.Size()
+	}
+
+	return nil
+}
+
+// read returns the contents of the finished part at index i.
+func (p *partPlan) read(i int) ([]byte, error) {
+	return os.ReadFile(p.parts[i].file.Name())
+}
+
+// remove deletes the parts' temporary files.
+func (p *partPlan) remove() {
+	for _, part := range p.parts {
+		part.file.Close()
+		os.Remove(part.file.Name())
+	}
+}
@@ -0,0 +1,266 @@
+// Package redact removes identifying data from guild exports, so that they can be shared with third parties such as
+// auditors. User IDs are replaced with pseudonyms derived with HMAC-SHA256 and a random per-export salt, so a user has
+// the same pseudonym throughout an export, including across data.json and transcripts, but pseudonyms can't be
+// reversed or linked between exports.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/pkg/dto"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+const Algorithm = "HMAC-SHA256"
+
+const saltLength = 32
+
+var mentionRegex = regexp.MustCompile(`<@!?(\d+)>`)
+
+// discordCdnRegex matches URLs of files hosted by Discord, which contain the IDs of the user or message that the file
+// belongs to, e.g. cdn.discordapp.com/avatars/<user ID>/<hash>.png.
+var discordCdnRegex = regexp.MustCompile(`^https?://(cdn\.discordapp\.com|media\.discordapp\.net)/`)
+
+// Redactor applies a redaction profile. With RedactionProfileNone, every method leaves its input unchanged.
+type Redactor struct {
+	profile model.RedactionProfile
+	salt    []byte
+}
+
+// New returns a Redactor for the profile, generating a fresh salt. The salt is never stored, so every Redactor
+// produces different pseudonyms.
+func New(profile model.RedactionProfile) (*Redactor, error) {
+	if profile == "" {
+		profile = model.RedactionProfileNone
+	}
+
+	r := &Redactor{
+		profile: profile,
+	}
+
+	if profile.Pseudonymise() {
+		r.salt = make([]byte, saltLength)
+		if _, err := rand.Read(r.salt); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Metadata describes the redaction applied, for the export's metadata.json.
+func (r *Redactor) Metadata() dto.RedactionMetadata {
+	metadata := dto.RedactionMetadata{
+		Profile:         string(r.profile),
+		Pseudonymised:   r.profile.Pseudonymise(),
+		ContentStripped: r.profile.StripContent(),
+	}
+
+	if metadata.Pseudonymised {
+		metadata.Algorithm = Algorithm
+	}
+
+	return metadata
+}
+
+// UserId returns the pseudonym for a user ID, or the ID itself if user IDs are not pseudonymised. 0 is used by the
+// database to mean no user, so is left as it is.
+func (r *Redactor) UserId(userId uint64) uint64 {
+	if !r.profile.Pseudonymise() || userId == 0 {
+		return userId
+	}
+
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(strconv.FormatUint(userId, 10)))
+	return binary.BigEndian.Uint64(mac.Sum(nil)[:8])
+}
+
+func (r *Redactor) userIdPtr(userId *uint64) {
+	if userId != nil {
+		*userId = r.UserId(*userId)
+	}
+}
+
+// userIds pseudonymises a list of user IDs in place. Lists are sorted by user ID when exported, so they are re-sorted,
+// as the original order would reveal the relative order of the real IDs.
+func (r *Redactor) userIds(userIds []uint64) {
+	for i, userId := range userIds {
+		userIds[i] = r.UserId(userId)
+	}
+
+	slices.Sort(userIds)
+}
+
+// GuildData redacts the guild data in place.
+func (r *Redactor) GuildData(data *dto.GuildData) {
+	if r.profile.Pseudonymise() {
+		r.userIds(data.GuildBlacklistedUsers)
+		r.userIds(data.OnCallUsers)
+
+		for _, userIds := range data.Participants {
+			r.userIds(userIds)
+		}
+
+		for _, userIds := range data.SupportTeamUsers {
+			r.userIds(userIds)
+		}
+
+		for _, userIds := range data.TicketAdditionalMembers {
+			r.userIds(userIds)
+		}
+
+		for i := range data.CloseReasons {
+			r.userIdPtr(data.CloseReasons[i].Data.ClosedBy)
+		}
+
+		for i := range data.FirstResponseTimes {
+			data.FirstResponseTimes[i].UserId = r.UserId(data.FirstResponseTimes[i].UserId)
+		}
+
+		for i := range data.UserPermissions {
+			data.UserPermissions[i].Snowflake = r.UserId(data.UserPermissions[i].Snowflake)
+		}
+
+		for i := range data.TicketClaims {
+			data.TicketClaims[i].Data = r.UserId(data.TicketClaims[i].Data)
+		}
+
+		for i := range data.TicketLastMessages {
+			r.userIdPtr(data.TicketLastMessages[i].Data.UserId)
+		}
+
+		for i := range data.Tickets {
+			data.Tickets[i].UserId = r.UserId(data.Tickets[i].UserId)
+		}
+	}
+
+	if r.profile.StripContent() {
+		for i := range data.CloseReasons {
+			data.CloseReasons[i].Data.Reason = nil
+		}
+
+		for i := range data.ExitSurveyResponses {
+			data.ExitSurveyResponses[i].Data.Response = nil
+		}
+	}
+}
+
+// Transcript redacts an archived transcript. Redacted transcripts are re-encoded in the latest transcript version;
+// if nothing is redacted, the transcript is returned as it was archived.
+func (r *Redactor) Transcript(raw []byte) ([]byte, error) {
+	if !r.profile.Pseudonymise() && !r.profile.StripContent() {
+		return raw, nil
+	}
+
+	transcript, err := dto.DecodeTranscript(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.profile.Pseudonymise() {
+		users := make(map[string]dto.TranscriptUser, len(transcript.Entities.Users))
+		for _, user := range transcript.Entities.Users {
+			user.Id = r.UserId(user.Id)
+			user.Username = fmt.Sprintf("user-%016x", user.Id)
+			user.Avatar = ""
+			users[strconv.FormatUint(user.Id, 10)] = user
+		}
+
+		transcript.Entities.Users = users
+
+		// Ticket channels are often named after the user that opened the ticket
+		for id, channel := range transcript.Entities.Channels {
+			channel.Name = strconv.FormatUint(channel.Id, 10)
+			transcript.Entities.Channels[id] = channel
+		}
+	}
+
+	for i := range transcript.Messages {
+		msg := &transcript.Messages[i]
+		msg.AuthorId = r.UserId(msg.AuthorId)
+		msg.Author = nil
+
+		if r.profile.StripContent() {
+			msg.Content = ""
+			msg.Embeds = nil
+			msg.Attachments = nil
+			continue
+		}
+
+		msg.Content = r.mentions(msg.Content)
+		for j := range msg.Embeds {
+			embed := &msg.Embeds[j]
+			embed.Title = r.mentions(embed.Title)
+			embed.Description = r.mentions(embed.Description)
+
+			for k := range embed.Fields {
+				embed.Fields[k].Name = r.mentions(embed.Fields[k].Name)
+				embed.Fields[k].Value = r.mentions(embed.Fields[k].Value)
+			}
+
+			// Embed authors are usually the user the embed is about, with their avatar as the icon
+			embed.Author = nil
+
+			// Welcome and close embeds commonly show the user's avatar, whose URL contains their real ID
+			embed.Url = r.cdnUrl(embed.Url)
+			embed.Image = r.cdnMedia(embed.Image)
+			embed.Thumbnail = r.cdnMedia(embed.Thumbnail)
+
+			if embed.Footer != nil {
+				embed.Footer.Text = r.mentions(embed.Footer.Text)
+				embed.Footer.IconUrl = r.cdnUrl(embed.Footer.IconUrl)
+			}
+		}
+
+		// Attachments are hosted on Discord's CDN, so their URLs are removed, keeping the file name and size
+		if r.profile.Pseudonymise() {
+			for j := range msg.Attachments {
+				msg.Attachments[j].Url = ""
+			}
+		}
+	}
+
+	return json.Marshal(transcript)
+}
+
+// cdnUrl removes URLs of files hosted by Discord when pseudonymising, as they may contain real user IDs. Other URLs are
+// left as they are.
+func (r *Redactor) cdnUrl(url string) string {
+	if r.profile.Pseudonymise() && discordCdnRegex.MatchString(url) {
+		return ""
+	}
+
+	return url
+}
+
+// cdnMedia removes an embed's image or thumbnail if it is hosted by Discord, as with cdnUrl.
+func (r *Redactor) cdnMedia(media *dto.TranscriptEmbedMedia) *dto.TranscriptEmbedMedia {
+	if media == nil || r.cdnUrl(media.Url) == "" {
+		return nil
+	}
+
+	return media
+}
+
+// mentions replaces the IDs in user mentions, e.g. <@123>, with their pseudonyms.
+func (r *Redactor) mentions(s string) string {
+	if !r.profile.Pseudonymise() {
+		return s
+	}
+
+	return mentionRegex.ReplaceAllStringFunc(s, func(mention string) string {
+		userId, err := strconv.ParseUint(mentionRegex.FindStringSubmatch(mention)[1], 10, 64)
+		if err != nil {
+			return mention
+		}
+
+		return fmt.Sprintf("<@%d>", r.UserId(userId))
+	})
+}
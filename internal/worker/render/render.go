@@ -0,0 +1,125 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
+	"html/template"
+	"time"
+)
+
+type Ticket struct {
+	Id            int
+	UserId        uint64
+	OpenTime      time.Time
+	CloseTime     *time.Time
+	PanelTitle    *string
+	HasTranscript bool
+}
+
+type transcriptPage struct {
+	GuildId  uint64
+	Ticket   Ticket
+	Messages []renderedMessage
+}
+
+type renderedMessage struct {
+	dto.TranscriptMessage
+	Author dto.TranscriptUser
+}
+
+type indexPage struct {
+	GuildId uint64
+	Tickets []Ticket
+}
+
+var (
+	//go:embed templates/*.html.tmpl
+	templateFs embed.FS
+
+	templates = template.Must(template.New("").Funcs(template.FuncMap{
+		"formatTime": formatTime,
+		"formatSize": formatSize,
+		"hexColour":  hexColour,
+	}).ParseFS(templateFs, "templates/*.html.tmpl"))
+)
+
+// Transcript renders a self-contained HTML page for a single transcript. Avatars and attachments are linked rather
+// than embedded, so they will stop loading once they are removed from Discord's CDN.
+func Transcript(guildId uint64, ticket Ticket, data []byte) ([]byte, error) {
+	parsed, err := dto.DecodeTranscript(data)
+	if err != nil {
+		return nil, err
+	}
+
+	page := transcriptPage{
+		GuildId:  guildId,
+		Ticket:   ticket,
+		Messages: make([]renderedMessage, len(parsed.Messages)),
+	}
+
+	for i, msg := range parsed.Messages {
+		author, ok := parsed.User(msg.AuthorId)
+		if !ok {
+			author = dto.TranscriptUser{Id: msg.AuthorId, Username: fmt.Sprintf("Unknown User (%d)", msg.AuthorId)}
+		}
+
+		page.Messages[i] = renderedMessage{
+			TranscriptMessage: msg,
+			Author:            author,
+		}
+	}
+
+	return execute("transcript.html.tmpl", page)
+}
+
+// Index renders a page listing every ticket in the guild, linking to the rendered transcripts where available.
+func Index(guildId uint64, tickets []Ticket) ([]byte, error) {
+	return execute("index.html.tmpl", indexPage{
+		GuildId: guildId,
+		Tickets: tickets,
+	})
+}
+
+func execute(name string, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatTime(t any) string {
+	switch v := t.(type) {
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05 UTC")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+
+		return v.UTC().Format("2006-01-02 15:04:05 UTC")
+	default:
+		return ""
+	}
+}
+
+func formatSize(size int) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	} else if size < 1024*1024 {
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	} else {
+		return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+	}
+}
+
+func hexColour(colour int) string {
+	if colour == 0 {
+		return "#1e1f22"
+	}
+
+	return fmt.Sprintf("#%06x", colour)
+}
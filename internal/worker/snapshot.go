@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
+)
+
+// querier is implemented by both pgx.Tx and *pgxpool.Pool, so that fetchers can read either from a snapshot or
+// directly from the pool.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+var snapshotTxOptions = pgx.TxOptions{
+	IsoLevel:   pgx.RepeatableRead,
+	AccessMode: pgx.ReadOnly,
+}
+
+// snapshot is a read-only view of the tickets database at a single point in time, shared between several transactions
+// with pg_export_snapshot, so that guild data can be read concurrently while still being internally consistent. For
+// example, every ticket in Tickets has its participants in Participants, even if the ticket was opened part way
+// through the export.
+type snapshot struct {
+	txs chan pgx.Tx
+	all []pgx.Tx
+}
+
+// beginSnapshot opens a snapshot over up to connections transactions, limited by the size of the pool so that opening
+// it can't block forever. The snapshot must be released once it is no longer needed.
+func (d *Daemon) beginSnapshot(ctx context.Context, connections int) (*snapshot, error) {
+	if maxConns := int(d.pool.Config().MaxConns); connections > maxConns {
+		connections = maxConns
+	}
+
+	if connections < 1 {
+		connections = 1
+	}
+
+	s := &snapshot{
+		txs: make(chan pgx.Tx, connections),
+		all: make([]pgx.Tx, 0, connections),
+	}
+
+	// The exporting transaction must stay open until every other transaction has imported the snapshot, so it is kept
+	// for the lifetime of the snapshot, and used for reads like the others
+	exporter, err := d.pool.BeginTx(ctx, snapshotTxOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	s.add(exporter)
+
+	var snapshotId string
+	if err := exporter.QueryRow(ctx, `SELECT pg_export_snapshot();`).Scan(&snapshotId); err != nil {
+		s.release()
+		return nil, err
+	}
+
+	// SET TRANSACTION SNAPSHOT does not accept parameters. The ID is generated by the server, but is quoted anyway.
+	importQuery := fmt.Sprintf(`SET TRANSACTION SNAPSHOT '%s';`, strings.ReplaceAll(snapshotId, "'", "''"))
+
+	for i := 1; i < connections; i++ {
+		tx, err := d.pool.BeginTx(ctx, snapshotTxOptions)
+		if err != nil {
+			s.release()
+			return nil, err
+		}
+
+		s.add(tx)
+
+		if _, err := tx.Exec(ctx, importQuery); err != nil {
+			s.release()
+			return nil, fmt.Errorf("failed to import snapshot %s: %w", snapshotId, err)
+		}
+	}
+
+	return s, nil
+}
+
+// size returns the number of transactions in the snapshot, and therefore how many readers can use it at once.
+func (s *snapshot) size() int {
+	return len(s.all)
+}
+
+func (s *snapshot) add(tx pgx.Tx) {
+	s.all = append(s.all, tx)
+	s.txs <- tx
+}
+
+// run calls f with one of the snapshot's transactions, waiting for one to be free. A transaction is only used by one
+// caller at a time, as a connection can't run concurrent queries.
+func (s *snapshot) run(ctx context.Context, f func(tx pgx.Tx) error) error {
+	select {
+	case tx := <-s.txs:
+		defer func() {
+			s.txs <- tx
+		}()
+
+		return f(tx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release ends every transaction of the snapshot, returning their connections to the pool. It is safe to call more
+// than once.
+func (s *snapshot) release() error {
+	// Use a fresh context, as the transactions must be ended even if the export's context has been cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var errs []error
+	for _, tx := range s.all {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			errs = append(errs, err)
+		}
+	}
+
+	s.all = nil
+	return errors.Join(errs...)
+}
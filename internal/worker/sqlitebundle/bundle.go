@@ -0,0 +1,530 @@
+package sqlitebundle
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
+	"os"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+type bundle struct {
+	tx *sql.Tx
+}
+
+// Build writes every section of the guild data into normalised SQLite tables, and returns the database file. If
+// transcripts is non-nil, the messages of each transcript are written to the transcript_messages table. Transcripts
+// that can't be decoded are left out, and their ticket IDs returned in failed.
+//
+// Foreign keys are declared for the benefit of query tools, but are not enforced: the export may legitimately contain
+// rows referencing deleted entities (e.g. survey responses for a deleted form).
+func Build(ctx context.Context, data dto.GuildData, transcripts map[int][]byte) ([]byte, []int, error) {
+	f, err := os.CreateTemp("", "guild-data-*.sqlite")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	path := f.Name()
+	defer os.Remove(path)
+
+	if err := f.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	failed, err := write(ctx, db, data, transcripts)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	if err := db.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return content, failed, nil
+}
+
+func write(ctx context.Context, db *sql.DB, data dto.GuildData, transcripts map[int][]byte) ([]int, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	b := &bundle{tx: tx}
+
+	steps := []func(ctx context.Context, data dto.GuildData) error{
+		b.writeSettings,
+		b.writeForms,
+		b.writeEmbeds,
+		b.writeSupportTeams,
+		b.writePanels,
+		b.writeMultiPanels,
+		b.writeTags,
+		b.writeTickets,
+		b.writeTicketData,
+		b.writePermissions,
+	}
+
+	for _, step := range steps {
+		if err := step(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var failed []int
+	for ticketId, raw := range transcripts {
+		parsed, err := dto.DecodeTranscript(raw)
+		if err != nil {
+			failed = append(failed, ticketId)
+			continue
+		}
+
+		if err := b.writeTranscript(ctx, ticketId, parsed); err != nil {
+			return nil, fmt.Errorf("failed to write transcript for ticket %d: %w", ticketId, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+func (b *bundle) writeSettings(ctx context.Context, data dto.GuildData) error {
+	settings := data.Settings
+
+	var namingScheme *string
+	if data.NamingScheme != nil {
+		namingScheme = (*string)(data.NamingScheme)
+	}
+
+	if err := b.exec(ctx, `
+INSERT INTO settings (guild_id, hide_claim_button, disable_open_command, context_menu_permission_level,
+                      context_menu_add_sender, context_menu_panel, store_transcripts, use_threads,
+                      ticket_notification_channel, thread_archive_duration, overflow_enabled, overflow_category_id,
+                      exit_survey_form_id, anonymise_dashboard_responses, active_language, archive_channel,
+                      channel_category, close_confirmation_enabled, feedback_enabled, guild_is_globally_blacklisted,
+                      on_call_role_id, naming_scheme, ticket_limit, users_can_close, welcome_message)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		snowflake(data.GuildId), settings.HideClaimButton, settings.DisableOpenCommand,
+		settings.ContextMenuPermissionLevel, settings.ContextMenuAddSender, settings.ContextMenuPanel,
+		settings.StoreTranscripts, settings.UseThreads, snowflakePtr(settings.TicketNotificationChannel),
+		settings.ThreadArchiveDuration, settings.OverflowEnabled, snowflakePtr(settings.OverflowCategoryId),
+		settings.ExitSurveyFormId, settings.AnonymiseDashboardResponses, data.ActiveLanguage,
+		snowflakePtr(data.ArchiveChannel), snowflakePtr(data.ChannelCategory), data.CloseConfirmationEnabled,
+		data.FeedbackEnabled, data.GuildIsGloballyBlacklisted, snowflakePtr(data.GuildMetadata.OnCallRole),
+		namingScheme, data.TicketLimit, data.UsersCanClose, data.WelcomeMessage); err != nil {
+		return err
+	}
+
+	if autoClose := data.AutocloseSettings; autoClose != nil {
+		if err := b.exec(ctx, `
+INSERT INTO autoclose_settings (enabled, since_open_with_no_response_seconds, since_last_message_seconds, on_user_leave)
+VALUES (?, ?, ?, ?);`,
+			autoClose.Enabled, durationSeconds(autoClose.SinceOpenWithNoResponse),
+			durationSeconds(autoClose.SinceLastMessage), autoClose.OnUserLeave); err != nil {
+			return err
+		}
+	}
+
+	if claim := data.ClaimSettings; claim != nil {
+		if err := b.exec(ctx, `INSERT INTO claim_settings (support_can_view, support_can_type) VALUES (?, ?);`,
+			claim.SupportCanView, claim.SupportCanType); err != nil {
+			return err
+		}
+	}
+
+	if err := b.exec(ctx, `INSERT INTO ticket_permissions (attach_files, embed_links, add_reactions) VALUES (?, ?, ?);`,
+		data.TicketPermissions.AttachFiles, data.TicketPermissions.EmbedLinks,
+		data.TicketPermissions.AddReactions); err != nil {
+		return err
+	}
+
+	for colourId, colourCode := range data.CustomColors {
+		if err := b.exec(ctx, `INSERT INTO custom_colours (colour_id, colour_code) VALUES (?, ?);`, colourId, colourCode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeForms(ctx context.Context, data dto.GuildData) error {
+	for _, form := range data.Forms {
+		if err := b.exec(ctx, `INSERT INTO forms (form_id, title, custom_id) VALUES (?, ?, ?);`,
+			form.Id, form.Title, form.CustomId); err != nil {
+			return err
+		}
+	}
+
+	for _, input := range data.FormInputs {
+		if err := b.exec(ctx, `
+INSERT INTO form_inputs (id, form_id, position, custom_id, style, label, placeholder, required, min_length, max_length)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+			input.Id, input.FormId, input.Position, input.CustomId, input.Style, input.Label, input.Placeholder,
+			input.Required, input.MinLength, input.MaxLength); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeEmbeds(ctx context.Context, data dto.GuildData) error {
+	for _, embed := range data.Embeds {
+		if err := b.exec(ctx, `
+INSERT INTO embeds (id, title, description, url, colour, author_name, author_icon_url, author_url, image_url,
+                    thumbnail_url, footer_text, footer_icon_url, timestamp)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+			embed.Id, embed.Title, embed.Description, embed.Url, embed.Colour, embed.AuthorName, embed.AuthorIconUrl,
+			embed.AuthorUrl, embed.ImageUrl, embed.ThumbnailUrl, embed.FooterText, embed.FooterIconUrl,
+			embed.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range data.EmbedFields {
+		if err := b.exec(ctx, `INSERT INTO embed_fields (id, embed_id, name, value, inline) VALUES (?, ?, ?, ?, ?);`,
+			field.FieldId, field.EmbedId, field.Name, field.Value, field.Inline); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeSupportTeams(ctx context.Context, data dto.GuildData) error {
+	for _, team := range data.SupportTeams {
+		if err := b.exec(ctx, `INSERT INTO support_teams (id, name, on_call_role_id) VALUES (?, ?, ?);`,
+			team.Id, team.Name, snowflakePtr(team.OnCallRole)); err != nil {
+			return err
+		}
+	}
+
+	if err := b.writeIdSnowflakes(ctx, `INSERT INTO support_team_users (team_id, user_id) VALUES (?, ?);`, data.SupportTeamUsers); err != nil {
+		return err
+	}
+
+	return b.writeIdSnowflakes(ctx, `INSERT INTO support_team_roles (team_id, role_id) VALUES (?, ?);`, data.SupportTeamRoles)
+}
+
+func (b *bundle) writePanels(ctx context.Context, data dto.GuildData) error {
+	for _, panel := range data.Panels {
+		if err := b.exec(ctx, `
+INSERT INTO panels (panel_id, message_id, channel_id, title, content, colour, category_id, emoji_name, emoji_id,
+                    welcome_message_embed, default_team, custom_id, image_url, thumbnail_url, button_style,
+                    button_label, form_id, naming_scheme, force_disabled, disabled, exit_survey_form_id,
+                    pending_category)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+			panel.PanelId, snowflake(panel.MessageId), snowflake(panel.ChannelId), panel.Title, panel.Content,
+			panel.Colour, snowflake(panel.TargetCategory), panel.EmojiName, snowflakePtr(panel.EmojiId),
+			panel.WelcomeMessageEmbed, panel.WithDefaultTeam, panel.CustomId, panel.ImageUrl, panel.ThumbnailUrl,
+			panel.ButtonStyle, panel.ButtonLabel, panel.FormId, panel.NamingScheme, panel.ForceDisabled,
+			panel.Disabled, panel.ExitSurveyFormId, snowflakePtr(panel.PendingCategory)); err != nil {
+			return err
+		}
+	}
+
+	for panelId, teamIds := range data.PanelTeams {
+		for _, teamId := range teamIds {
+			if err := b.exec(ctx, `INSERT INTO panel_teams (panel_id, team_id) VALUES (?, ?);`, panelId, teamId); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := b.writeIdSnowflakes(ctx, `INSERT INTO panel_role_mentions (panel_id, role_id) VALUES (?, ?);`, data.PanelRoleMentions); err != nil {
+		return err
+	}
+
+	for panelId, mentionUser := range data.PanelMentionUser {
+		if err := b.exec(ctx, `INSERT INTO panel_mention_user (panel_id, mention_user) VALUES (?, ?);`, panelId, mentionUser); err != nil {
+			return err
+		}
+	}
+
+	for panelId, rules := range data.PanelAccessControlRules {
+		for position, rule := range rules {
+			if err := b.exec(ctx, `INSERT INTO panel_access_control_rules (panel_id, position, role_id, action) VALUES (?, ?, ?, ?);`,
+				panelId, position, snowflake(rule.RoleId), string(rule.Action)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeMultiPanels(ctx context.Context, data dto.GuildData) error {
+	for _, multiPanel := range data.MultiPanels {
+		embed, err := jsonPtr(multiPanel.Embed)
+		if err != nil {
+			return err
+		}
+
+		if err := b.exec(ctx, `
+INSERT INTO multi_panels (id, message_id, channel_id, select_menu, select_menu_placeholder, embed)
+VALUES (?, ?, ?, ?, ?, ?);`,
+			multiPanel.Id, snowflake(multiPanel.MessageId), snowflake(multiPanel.ChannelId), multiPanel.SelectMenu,
+			multiPanel.SelectMenuPlaceholder, embed); err != nil {
+			return err
+		}
+	}
+
+	for multiPanelId, panelIds := range data.MultiPanelTargets {
+		for _, panelId := range panelIds {
+			if err := b.exec(ctx, `INSERT INTO multi_panel_targets (multi_panel_id, panel_id) VALUES (?, ?);`,
+				multiPanelId, panelId); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeTags(ctx context.Context, data dto.GuildData) error {
+	for _, tag := range data.Tags {
+		embed, err := jsonPtr(tag.Embed)
+		if err != nil {
+			return err
+		}
+
+		if err := b.exec(ctx, `INSERT INTO tags (tag_id, content, embed, application_command_id) VALUES (?, ?, ?, ?);`,
+			tag.Id, tag.Content, embed, snowflakePtr(tag.ApplicationCommandId)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeTickets(ctx context.Context, data dto.GuildData) error {
+	for _, ticket := range data.Tickets {
+		if err := b.exec(ctx, `
+INSERT INTO tickets (id, user_id, channel_id, open, open_time, welcome_message_id, panel_id, has_transcript,
+                     close_time, is_thread, join_message_id, notes_thread_id, status)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+			ticket.Id, snowflake(ticket.UserId), snowflakePtr(ticket.ChannelId), ticket.Open, ticket.OpenTime,
+			snowflakePtr(ticket.WelcomeMessageId), ticket.PanelId, ticket.HasTranscript, ticket.CloseTime,
+			ticket.IsThread, snowflakePtr(ticket.JoinMessageId), snowflakePtr(ticket.NotesThreadId),
+			string(ticket.Status)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeTicketData(ctx context.Context, data dto.GuildData) error {
+	for _, msg := range data.ArchiveMessages {
+		if err := b.exec(ctx, `INSERT INTO archive_messages (ticket_id, channel_id, message_id) VALUES (?, ?, ?);`,
+			msg.TicketId, snowflake(msg.Data.ChannelId), snowflake(msg.Data.MessageId)); err != nil {
+			return err
+		}
+	}
+
+	for _, ticketId := range data.AutocloseExcluded {
+		if err := b.exec(ctx, `INSERT INTO autoclose_excluded (ticket_id) VALUES (?);`, ticketId); err != nil {
+			return err
+		}
+	}
+
+	for _, reason := range data.CloseReasons {
+		if err := b.exec(ctx, `INSERT INTO close_reasons (ticket_id, reason, closed_by) VALUES (?, ?, ?);`,
+			reason.TicketId, reason.Data.Reason, snowflakePtr(reason.Data.ClosedBy)); err != nil {
+			return err
+		}
+	}
+
+	for _, res := range data.ExitSurveyResponses {
+		if err := b.exec(ctx, `INSERT INTO exit_survey_responses (ticket_id, form_id, question_id, response) VALUES (?, ?, ?, ?);`,
+			res.TicketId, res.Data.FormId, res.Data.QuestionId, res.Data.Response); err != nil {
+			return err
+		}
+	}
+
+	for _, frt := range data.FirstResponseTimes {
+		if err := b.exec(ctx, `INSERT INTO first_response_times (ticket_id, user_id, response_time_seconds) VALUES (?, ?, ?);`,
+			frt.TicketId, snowflake(frt.UserId), frt.ResponseTime.Seconds()); err != nil {
+			return err
+		}
+	}
+
+	if err := b.writeIdSnowflakes(ctx, `INSERT INTO participants (ticket_id, user_id) VALUES (?, ?);`, data.Participants); err != nil {
+		return err
+	}
+
+	for _, rating := range data.ServiceRatings {
+		if err := b.exec(ctx, `INSERT INTO service_ratings (ticket_id, rating) VALUES (?, ?);`, rating.TicketId, rating.Data); err != nil {
+			return err
+		}
+	}
+
+	for _, claim := range data.TicketClaims {
+		if err := b.exec(ctx, `INSERT INTO ticket_claims (ticket_id, user_id) VALUES (?, ?);`, claim.TicketId, snowflake(claim.Data)); err != nil {
+			return err
+		}
+	}
+
+	for _, msg := range data.TicketLastMessages {
+		if err := b.exec(ctx, `
+INSERT INTO ticket_last_messages (ticket_id, last_message_id, last_message_time, user_id, user_is_staff)
+VALUES (?, ?, ?, ?, ?);`,
+			msg.TicketId, snowflakePtr(msg.Data.LastMessageId), msg.Data.LastMessageTime, snowflakePtr(msg.Data.UserId),
+			msg.Data.UserIsStaff); err != nil {
+			return err
+		}
+	}
+
+	return b.writeIdSnowflakes(ctx, `INSERT INTO ticket_additional_members (ticket_id, user_id) VALUES (?, ?);`, data.TicketAdditionalMembers)
+}
+
+func (b *bundle) writePermissions(ctx context.Context, data dto.GuildData) error {
+	for _, permission := range data.UserPermissions {
+		if err := b.exec(ctx, `INSERT INTO user_permissions (user_id, is_admin, is_support) VALUES (?, ?, ?);`,
+			snowflake(permission.Snowflake), permission.IsAdmin, permission.IsSupport); err != nil {
+			return err
+		}
+	}
+
+	for _, permission := range data.RolePermissions {
+		if err := b.exec(ctx, `INSERT INTO role_permissions (role_id, is_admin, is_support) VALUES (?, ?, ?);`,
+			snowflake(permission.Snowflake), permission.IsAdmin, permission.IsSupport); err != nil {
+			return err
+		}
+	}
+
+	for _, userId := range data.GuildBlacklistedUsers {
+		if err := b.exec(ctx, `INSERT INTO guild_blacklisted_users (user_id) VALUES (?);`, snowflake(userId)); err != nil {
+			return err
+		}
+	}
+
+	for _, roleId := range data.GuildBlacklistedRoles {
+		if err := b.exec(ctx, `INSERT INTO guild_blacklisted_roles (role_id) VALUES (?);`, snowflake(roleId)); err != nil {
+			return err
+		}
+	}
+
+	for _, userId := range data.OnCallUsers {
+		if err := b.exec(ctx, `INSERT INTO on_call_users (user_id) VALUES (?);`, snowflake(userId)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeTranscript(ctx context.Context, ticketId int, parsed *dto.Transcript) error {
+	for _, user := range parsed.Entities.Users {
+		if err := b.exec(ctx, `
+INSERT OR IGNORE INTO transcript_users (ticket_id, user_id, username, avatar, bot)
+VALUES (?, ?, ?, ?, ?);`,
+			ticketId, snowflake(user.Id), user.Username, user.Avatar, user.Bot); err != nil {
+			return err
+		}
+	}
+
+	for _, msg := range parsed.Messages {
+		embeds, err := json.Marshal(msg.Embeds)
+		if err != nil {
+			return err
+		}
+
+		attachments, err := json.Marshal(msg.Attachments)
+		if err != nil {
+			return err
+		}
+
+		if err := b.exec(ctx, `
+INSERT OR IGNORE INTO transcript_messages (ticket_id, message_id, author_id, content, timestamp, embeds, attachments)
+VALUES (?, ?, ?, ?, ?, ?, ?);`,
+			ticketId, snowflake(msg.Id), snowflake(msg.AuthorId), msg.Content, msg.Timestamp.UTC().Format(time.RFC3339Nano),
+			string(embeds), string(attachments)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) writeIdSnowflakes(ctx context.Context, query string, m map[int][]uint64) error {
+	for id, snowflakes := range m {
+		for _, s := range snowflakes {
+			if err := b.exec(ctx, query, id, snowflake(s)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *bundle) exec(ctx context.Context, query string, args ...any) error {
+	_, err := b.tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Snowflakes are stored as text, matching the string encoding used in data.json
+func snowflake(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+func snowflakePtr(v *uint64) *string {
+	if v == nil {
+		return nil
+	}
+
+	s := snowflake(*v)
+	return &s
+}
+
+func jsonPtr(v any) (*string, error) {
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(marshalled) == "null" {
+		return nil, nil
+	}
+
+	s := string(marshalled)
+	return &s, nil
+}
+
+func durationSeconds(v *time.Duration) *int64 {
+	if v == nil {
+		return nil
+	}
+
+	seconds := int64(v.Seconds())
+	return &seconds
+}
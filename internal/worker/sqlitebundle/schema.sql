@@ -0,0 +1,347 @@
+-- Guild-wide settings, with a single row
+CREATE TABLE settings
+(
+    guild_id                      TEXT    NOT NULL,
+    hide_claim_button             BOOLEAN NOT NULL,
+    disable_open_command          BOOLEAN NOT NULL,
+    context_menu_permission_level INTEGER NOT NULL,
+    context_menu_add_sender       BOOLEAN NOT NULL,
+    context_menu_panel            INTEGER REFERENCES panels (panel_id),
+    store_transcripts             BOOLEAN NOT NULL,
+    use_threads                   BOOLEAN NOT NULL,
+    ticket_notification_channel   TEXT,
+    thread_archive_duration       INTEGER NOT NULL,
+    overflow_enabled              BOOLEAN NOT NULL,
+    overflow_category_id          TEXT,
+    exit_survey_form_id           INTEGER REFERENCES forms (form_id),
+    anonymise_dashboard_responses BOOLEAN NOT NULL,
+    active_language               TEXT,
+    archive_channel               TEXT,
+    channel_category              TEXT,
+    close_confirmation_enabled    BOOLEAN NOT NULL,
+    feedback_enabled              BOOLEAN NOT NULL,
+    guild_is_globally_blacklisted BOOLEAN NOT NULL,
+    on_call_role_id               TEXT,
+    naming_scheme                 TEXT,
+    ticket_limit                  INTEGER,
+    users_can_close               BOOLEAN NOT NULL,
+    welcome_message               TEXT
+);
+
+-- Has a single row, or none if the guild has not configured auto close
+CREATE TABLE autoclose_settings
+(
+    enabled                             BOOLEAN NOT NULL,
+    since_open_with_no_response_seconds INTEGER,
+    since_last_message_seconds          INTEGER,
+    on_user_leave                       BOOLEAN
+);
+
+-- Has a single row, or none if the guild has not configured claiming
+CREATE TABLE claim_settings
+(
+    support_can_view BOOLEAN NOT NULL,
+    support_can_type BOOLEAN NOT NULL
+);
+
+-- Has a single row
+CREATE TABLE ticket_permissions
+(
+    attach_files  BOOLEAN NOT NULL,
+    embed_links   BOOLEAN NOT NULL,
+    add_reactions BOOLEAN NOT NULL
+);
+
+CREATE TABLE forms
+(
+    form_id   INTEGER PRIMARY KEY,
+    title     TEXT NOT NULL,
+    custom_id TEXT NOT NULL
+);
+
+CREATE TABLE form_inputs
+(
+    id          INTEGER PRIMARY KEY,
+    form_id     INTEGER NOT NULL REFERENCES forms (form_id),
+    position    INTEGER NOT NULL,
+    custom_id   TEXT    NOT NULL,
+    style       INTEGER NOT NULL,
+    label       TEXT    NOT NULL,
+    placeholder TEXT,
+    required    BOOLEAN NOT NULL,
+    min_length  INTEGER,
+    max_length  INTEGER
+);
+
+CREATE TABLE embeds
+(
+    id              INTEGER PRIMARY KEY,
+    title           TEXT,
+    description     TEXT,
+    url             TEXT,
+    colour          INTEGER NOT NULL,
+    author_name     TEXT,
+    author_icon_url TEXT,
+    author_url      TEXT,
+    image_url       TEXT,
+    thumbnail_url   TEXT,
+    footer_text     TEXT,
+    footer_icon_url TEXT,
+    timestamp       TIMESTAMP
+);
+
+CREATE TABLE embed_fields
+(
+    id       INTEGER PRIMARY KEY,
+    embed_id INTEGER NOT NULL REFERENCES embeds (id),
+    name     TEXT    NOT NULL,
+    value    TEXT    NOT NULL,
+    inline   BOOLEAN NOT NULL
+);
+
+CREATE TABLE support_teams
+(
+    id              INTEGER PRIMARY KEY,
+    name            TEXT NOT NULL,
+    on_call_role_id TEXT
+);
+
+CREATE TABLE support_team_users
+(
+    team_id INTEGER NOT NULL REFERENCES support_teams (id),
+    user_id TEXT    NOT NULL,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE TABLE support_team_roles
+(
+    team_id INTEGER NOT NULL REFERENCES support_teams (id),
+    role_id TEXT    NOT NULL,
+    PRIMARY KEY (team_id, role_id)
+);
+
+CREATE TABLE panels
+(
+    panel_id              INTEGER PRIMARY KEY,
+    message_id            TEXT    NOT NULL,
+    channel_id            TEXT    NOT NULL,
+    title                 TEXT    NOT NULL,
+    content               TEXT    NOT NULL,
+    colour                INTEGER NOT NULL,
+    category_id           TEXT    NOT NULL,
+    emoji_name            TEXT,
+    emoji_id              TEXT,
+    welcome_message_embed INTEGER REFERENCES embeds (id),
+    default_team          BOOLEAN NOT NULL,
+    custom_id             TEXT    NOT NULL,
+    image_url             TEXT,
+    thumbnail_url         TEXT,
+    button_style          INTEGER NOT NULL,
+    button_label          TEXT    NOT NULL,
+    form_id               INTEGER REFERENCES forms (form_id),
+    naming_scheme         TEXT,
+    force_disabled        BOOLEAN NOT NULL,
+    disabled              BOOLEAN NOT NULL,
+    exit_survey_form_id   INTEGER REFERENCES forms (form_id),
+    pending_category      TEXT
+);
+
+CREATE TABLE panel_teams
+(
+    panel_id INTEGER NOT NULL REFERENCES panels (panel_id),
+    team_id  INTEGER NOT NULL REFERENCES support_teams (id),
+    PRIMARY KEY (panel_id, team_id)
+);
+
+CREATE TABLE panel_role_mentions
+(
+    panel_id INTEGER NOT NULL REFERENCES panels (panel_id),
+    role_id  TEXT    NOT NULL,
+    PRIMARY KEY (panel_id, role_id)
+);
+
+CREATE TABLE panel_mention_user
+(
+    panel_id     INTEGER PRIMARY KEY REFERENCES panels (panel_id),
+    mention_user BOOLEAN NOT NULL
+);
+
+CREATE TABLE panel_access_control_rules
+(
+    panel_id INTEGER NOT NULL REFERENCES panels (panel_id),
+    position INTEGER NOT NULL,
+    role_id  TEXT    NOT NULL,
+    action   TEXT    NOT NULL,
+    PRIMARY KEY (panel_id, position)
+);
+
+CREATE TABLE multi_panels
+(
+    id                      INTEGER PRIMARY KEY,
+    message_id              TEXT    NOT NULL,
+    channel_id              TEXT    NOT NULL,
+    select_menu             BOOLEAN NOT NULL,
+    select_menu_placeholder TEXT,
+    embed                   TEXT -- JSON encoded
+);
+
+CREATE TABLE multi_panel_targets
+(
+    multi_panel_id INTEGER NOT NULL REFERENCES multi_panels (id),
+    panel_id       INTEGER NOT NULL REFERENCES panels (panel_id),
+    PRIMARY KEY (multi_panel_id, panel_id)
+);
+
+CREATE TABLE tags
+(
+    tag_id                 TEXT PRIMARY KEY,
+    content                TEXT,
+    embed                  TEXT, -- JSON encoded
+    application_command_id TEXT
+);
+
+CREATE TABLE tickets
+(
+    id                 INTEGER PRIMARY KEY,
+    user_id            TEXT      NOT NULL,
+    channel_id         TEXT,
+    open               BOOLEAN   NOT NULL,
+    open_time          TIMESTAMP NOT NULL,
+    welcome_message_id TEXT,
+    panel_id           INTEGER REFERENCES panels (panel_id),
+    has_transcript     BOOLEAN   NOT NULL,
+    close_time         TIMESTAMP,
+    is_thread          BOOLEAN   NOT NULL,
+    join_message_id    TEXT,
+    notes_thread_id    TEXT,
+    status             TEXT      NOT NULL
+);
+
+CREATE TABLE archive_messages
+(
+    ticket_id  INTEGER PRIMARY KEY REFERENCES tickets (id),
+    channel_id TEXT NOT NULL,
+    message_id TEXT NOT NULL
+);
+
+CREATE TABLE autoclose_excluded
+(
+    ticket_id INTEGER PRIMARY KEY REFERENCES tickets (id)
+);
+
+CREATE TABLE close_reasons
+(
+    ticket_id INTEGER PRIMARY KEY REFERENCES tickets (id),
+    reason    TEXT,
+    closed_by TEXT
+);
+
+CREATE TABLE exit_survey_responses
+(
+    ticket_id   INTEGER NOT NULL REFERENCES tickets (id),
+    form_id     INTEGER REFERENCES forms (form_id),
+    question_id INTEGER REFERENCES form_inputs (id),
+    response    TEXT
+);
+
+CREATE TABLE first_response_times
+(
+    ticket_id             INTEGER PRIMARY KEY REFERENCES tickets (id),
+    user_id               TEXT NOT NULL,
+    response_time_seconds REAL NOT NULL
+);
+
+CREATE TABLE participants
+(
+    ticket_id INTEGER NOT NULL REFERENCES tickets (id),
+    user_id   TEXT    NOT NULL,
+    PRIMARY KEY (ticket_id, user_id)
+);
+
+CREATE TABLE service_ratings
+(
+    ticket_id INTEGER PRIMARY KEY REFERENCES tickets (id),
+    rating    INTEGER NOT NULL
+);
+
+CREATE TABLE ticket_claims
+(
+    ticket_id INTEGER PRIMARY KEY REFERENCES tickets (id),
+    user_id   TEXT NOT NULL
+);
+
+CREATE TABLE ticket_last_messages
+(
+    ticket_id          INTEGER PRIMARY KEY REFERENCES tickets (id),
+    last_message_id    TEXT,
+    last_message_time  TIMESTAMP,
+    user_id            TEXT,
+    user_is_staff      BOOLEAN
+);
+
+CREATE TABLE ticket_additional_members
+(
+    ticket_id INTEGER NOT NULL REFERENCES tickets (id),
+    user_id   TEXT    NOT NULL,
+    PRIMARY KEY (ticket_id, user_id)
+);
+
+CREATE TABLE user_permissions
+(
+    user_id    TEXT PRIMARY KEY,
+    is_admin   BOOLEAN NOT NULL,
+    is_support BOOLEAN NOT NULL
+);
+
+CREATE TABLE role_permissions
+(
+    role_id    TEXT PRIMARY KEY,
+    is_admin   BOOLEAN NOT NULL,
+    is_support BOOLEAN NOT NULL
+);
+
+CREATE TABLE guild_blacklisted_users
+(
+    user_id TEXT PRIMARY KEY
+);
+
+CREATE TABLE guild_blacklisted_roles
+(
+    role_id TEXT PRIMARY KEY
+);
+
+CREATE TABLE on_call_users
+(
+    user_id TEXT PRIMARY KEY
+);
+
+CREATE TABLE custom_colours
+(
+    colour_id   INTEGER PRIMARY KEY,
+    colour_code INTEGER NOT NULL
+);
+
+CREATE TABLE transcript_users
+(
+    ticket_id INTEGER NOT NULL REFERENCES tickets (id),
+    user_id   TEXT    NOT NULL,
+    username  TEXT    NOT NULL,
+    avatar    TEXT,
+    bot       BOOLEAN NOT NULL,
+    PRIMARY KEY (ticket_id, user_id)
+);
+
+CREATE TABLE transcript_messages
+(
+    ticket_id   INTEGER   NOT NULL REFERENCES tickets (id),
+    message_id  TEXT      NOT NULL,
+    author_id   TEXT      NOT NULL,
+    content     TEXT      NOT NULL,
+    timestamp   TIMESTAMP NOT NULL,
+    embeds      TEXT, -- JSON encoded
+    attachments TEXT, -- JSON encoded
+    PRIMARY KEY (ticket_id, message_id)
+);
+
+CREATE INDEX tickets_user_id_idx ON tickets (user_id);
+CREATE INDEX transcript_messages_author_id_idx ON transcript_messages (author_id);
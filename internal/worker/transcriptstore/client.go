@@ -4,6 +4,7 @@ import "context"
 
 type Client interface {
 	GetTranscriptsForGuild(ctx context.Context, guildId uint64) (*GetTranscriptsResponse, error)
+	DeleteTranscriptsForGuild(ctx context.Context, guildId uint64) (int, error)
 }
 
 type GetTranscriptsResponse struct {
@@ -8,6 +8,7 @@ import (
 	"github.com/TicketsBot/export/internal/config"
 	"github.com/TicketsBot/export/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
 	"golang.org/x/sync/errgroup"
 	"io"
@@ -131,6 +132,51 @@ func (c *S3Client) GetTranscriptsForGuild(ctx context.Context, guildId uint64) (
 	return &response, nil
 }
 
+// deleteBatchSize is the maximum number of keys accepted by a single DeleteObjects call
+const deleteBatchSize = 1000
+
+func (c *S3Client) DeleteTranscriptsForGuild(ctx context.Context, guildId uint64) (int, error) {
+	logger := c.logger.With(slog.Uint64("guild_id", guildId))
+
+	keys, err := c.listForGuild(ctx, guildId)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for bucket, objKeys := range keys {
+		for i := 0; i < len(objKeys); i += deleteBatchSize {
+			batch := objKeys[i:min(i+deleteBatchSize, len(objKeys))]
+
+			objects := make([]types.ObjectIdentifier, len(batch))
+			for j, key := range batch {
+				objects[j] = types.ObjectIdentifier{Key: utils.Ptr(key)}
+			}
+
+			output, err := c.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: utils.Ptr(bucket),
+				Delete: &types.Delete{
+					Objects: objects,
+					Quiet:   utils.Ptr(true),
+				},
+			})
+			if err != nil {
+				return deleted, err
+			}
+
+			if len(output.Errors) > 0 {
+				return deleted, fmt.Errorf("failed to delete %d transcripts from bucket %s", len(output.Errors), bucket)
+			}
+
+			deleted += len(batch)
+		}
+	}
+
+	logger.Info("Deleted transcripts for guild", slog.Int("transcript_count", deleted))
+
+	return deleted, nil
+}
+
 type object struct {
 	bucket string
 	key    string
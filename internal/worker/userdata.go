@@ -0,0 +1,217 @@
+package worker
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/internal/model"
+	"github.com/TicketsBot/export/internal/utils"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/jackc/pgx/v4"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"time"
+)
+
+type userDataTask struct {
+	Name string
+	F    func(ctx context.Context, userId uint64, userData *dto.UserData) error
+}
+
+func newUserDataTask(name string, f func(ctx context.Context, userId uint64, userData *dto.UserData) error) userDataTask {
+	return userDataTask{
+		Name: name,
+		F:    f,
+	}
+}
+
+func (d *Daemon) handleUserDataTask(ctx context.Context, task model.Task, request model.Request) error {
+	if request.UserId == 0 {
+		d.logger.Error("User ID is zero", slog.String("task_id", task.Id.String()))
+		return fmt.Errorf("user ID is zero")
+	}
+
+	userId := request.UserId
+
+	logger := d.logger.With(slog.Uint64("user_id", userId), "request_id", request.Id)
+
+	data := dto.UserData{
+		FormatVersion: dto.FormatVersionLatest,
+		UserId:        userId,
+	}
+
+	tasks := []userDataTask{
+		newUserDataTask("Fetch Tickets", d.fetchUserTickets),
+		newUserDataTask("Fetch Participation", d.fetchUserParticipation),
+		newUserDataTask("Fetch Ticket Claims", d.fetchUserTicketClaims),
+		newUserDataTask("Fetch Service Ratings", d.fetchUserServiceRatings),
+		newUserDataTask("Fetch Exit Survey Responses", d.fetchUserExitSurveyResponses),
+		newUserDataTask("Fetch Blacklisted Guilds", d.fetchUserBlacklistedGuilds),
+		newUserDataTask("Fetch Globally Blacklisted", d.fetchUserGloballyBlacklisted),
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, task := range tasks {
+		task := task
+
+		group.Go(func() error {
+			now := time.Now()
+
+			logger.DebugContext(groupCtx, "Running task", "name", task.Name)
+			if err := task.F(groupCtx, userId, &data); err != nil {
+				logger.ErrorContext(ctx, "Failed to run task", "name", task.Name, "error", err, "elapsed", time.Since(now))
+				return err
+			}
+
+			logger.DebugContext(groupCtx, "Task completed", "name", task.Name, "elapsed", time.Since(now))
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		logger.ErrorContext(ctx, "Failed to run tasks", "error", err)
+		return err
+	}
+
+	logger.InfoContext(ctx, "All tasks completed")
+
+	files := make(map[string][]byte)
+
+	marshalled, err := json.Marshal(data)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to marshal data", "error", err)
+		return err
+	}
+
+	files["data.json"] = marshalled
+	files["data.json.sig"] = []byte(utils.Base64Encode(ed25519.Sign(d.privateKey, marshalled)))
+
+	return d.uploadArtifact(ctx, logger, request, files)
+}
+
+func (d *Daemon) fetchUserTickets(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	query := `
+SELECT id, guild_id, channel_id, user_id, open, open_time, welcome_message_id, panel_id, has_transcript, close_time, is_thread, join_message_id, notes_thread_id, status
+FROM tickets
+WHERE user_id = $1 AND (guild_id, id) > ($3, $4)
+ORDER BY guild_id, id LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, d.pool, userId, &userData.Tickets, query, func(t dto.Ticket) []interface{} { return []interface{}{t.GuildId, t.Id} }, func(rows pgx.Rows) (dto.Ticket, error) {
+		var ticket dto.Ticket
+		if err := rows.Scan(
+			&ticket.Id,
+			&ticket.GuildId,
+			&ticket.ChannelId,
+			&ticket.UserId,
+			&ticket.Open,
+			&ticket.OpenTime,
+			&ticket.WelcomeMessageId,
+			&ticket.PanelId,
+			&ticket.HasTranscript,
+			&ticket.CloseTime,
+			&ticket.IsThread,
+			&ticket.JoinMessageId,
+			&ticket.NotesThreadId,
+			&ticket.Status,
+		); err != nil {
+			return dto.Ticket{}, err
+		}
+
+		return ticket, nil
+	})
+}
+
+func (d *Daemon) fetchUserParticipation(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	query := `
+SELECT guild_id, ticket_id
+FROM participant
+WHERE user_id = $1 AND (guild_id, ticket_id) > ($3, $4)
+ORDER BY guild_id, ticket_id LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, d.pool, userId, &userData.Participation, query, guildTicketKey, scanGuildTicket)
+}
+
+func (d *Daemon) fetchUserTicketClaims(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	query := `
+SELECT guild_id, ticket_id
+FROM ticket_claims
+WHERE user_id = $1 AND (guild_id, ticket_id) > ($3, $4)
+ORDER BY guild_id, ticket_id LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, d.pool, userId, &userData.TicketClaims, query, guildTicketKey, scanGuildTicket)
+}
+
+func (d *Daemon) fetchUserServiceRatings(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	query := `
+SELECT r.guild_id, r.ticket_id, r.rating
+FROM service_ratings r
+INNER JOIN tickets t
+ON r.guild_id = t.guild_id AND r.ticket_id = t.id
+WHERE t.user_id = $1 AND (r.guild_id, r.ticket_id) > ($3, $4)
+ORDER BY r.guild_id, r.ticket_id LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, d.pool, userId, &userData.ServiceRatings, query, func(r dto.GuildTicketUnion[int16]) []interface{} { return []interface{}{r.GuildId, r.TicketId} }, func(rows pgx.Rows) (dto.GuildTicketUnion[int16], error) {
+		var res dto.GuildTicketUnion[int16]
+		if err := rows.Scan(&res.GuildId, &res.TicketId, &res.Data); err != nil {
+			return dto.GuildTicketUnion[int16]{}, err
+		}
+
+		return res, nil
+	})
+}
+
+func (d *Daemon) fetchUserExitSurveyResponses(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	query := `
+SELECT r.guild_id, r.ticket_id, r.form_id, r.question_id, r.response
+FROM exit_survey_responses r
+INNER JOIN tickets t
+ON r.guild_id = t.guild_id AND r.ticket_id = t.id
+WHERE t.user_id = $1 AND (r.guild_id, r.ticket_id, r.question_id) > ($3, $4, $5)
+ORDER BY r.guild_id, r.ticket_id, r.question_id LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, d.pool, userId, &userData.ExitSurveyResponses, query, func(r dto.GuildTicketUnion[dto.ExitSurveyResponse]) []interface{} {
+		return []interface{}{r.GuildId, r.TicketId, valueOrZero(r.Data.QuestionId)}
+	}, func(rows pgx.Rows) (dto.GuildTicketUnion[dto.ExitSurveyResponse], error) {
+		var res dto.GuildTicketUnion[dto.ExitSurveyResponse]
+		if err := rows.Scan(&res.GuildId, &res.TicketId, &res.Data.FormId, &res.Data.QuestionId, &res.Data.Response); err != nil {
+			return dto.GuildTicketUnion[dto.ExitSurveyResponse]{}, err
+		}
+
+		return res, nil
+	})
+}
+
+func (d *Daemon) fetchUserBlacklistedGuilds(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	query := `
+SELECT guild_id
+FROM blacklist
+WHERE user_id = $1 AND guild_id > $3
+ORDER BY guild_id LIMIT $2;`
+
+	return fetchCustomPaginated(ctx, d.pool, userId, &userData.BlacklistedGuilds, query, idKey, func(rows pgx.Rows) (uint64, error) {
+		var guildId uint64
+		if err := rows.Scan(&guildId); err != nil {
+			return 0, err
+		}
+
+		return guildId, nil
+	})
+}
+
+func (d *Daemon) fetchUserGloballyBlacklisted(ctx context.Context, userId uint64, userData *dto.UserData) error {
+	return fetchValNoPtr(ctx, userId, &userData.GloballyBlacklisted, d.database.GlobalBlacklist.IsBlacklisted)
+}
+
+func scanGuildTicket(rows pgx.Rows) (dto.GuildTicket, error) {
+	var res dto.GuildTicket
+	if err := rows.Scan(&res.GuildId, &res.TicketId); err != nil {
+		return dto.GuildTicket{}, err
+	}
+
+	return res, nil
+}
+
+func guildTicketKey(ticket dto.GuildTicket) []interface{} {
+	return []interface{}{ticket.GuildId, ticket.TicketId}
+}
@@ -0,0 +1 @@
+ALTER TYPE request_type ADD VALUE 'user_data';
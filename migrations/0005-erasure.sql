@@ -0,0 +1,8 @@
+ALTER TYPE request_type ADD VALUE 'guild_erasure';
+ALTER TYPE request_type ADD VALUE 'user_erasure';
+
+ALTER TYPE request_status ADD VALUE 'awaiting_confirmation' BEFORE 'queued';
+ALTER TYPE request_status ADD VALUE 'cancelled';
+
+ALTER TABLE task_queue ADD COLUMN run_after TIMESTAMPTZ NOT NULL DEFAULT NOW();
+CREATE INDEX task_queue_run_after_idx ON task_queue (run_after);
@@ -0,0 +1 @@
+ALTER TABLE requests ADD COLUMN options JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,7 @@
+CREATE TABLE oauth_tokens (
+    user_id BIGINT PRIMARY KEY,
+    access_token BYTEA NOT NULL,
+    refresh_token BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,13 @@
+CREATE TABLE sessions
+(
+    id             uuid PRIMARY KEY      DEFAULT gen_random_uuid(),
+    user_id        int8         NOT NULL,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at     TIMESTAMPTZ  NOT NULL,
+    revoked_at     TIMESTAMPTZ  NULL     DEFAULT NULL,
+    revoked_by     int8         NULL     DEFAULT NULL,
+    revoked_reason VARCHAR(255) NULL     DEFAULT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
@@ -0,0 +1,15 @@
+CREATE TABLE api_keys
+(
+    id           uuid PRIMARY KEY      DEFAULT gen_random_uuid(),
+    user_id      int8         NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    key_hash     BYTEA        NOT NULL UNIQUE,
+    scopes       TEXT[]       NOT NULL,
+    guild_ids    int8[]       NULL     DEFAULT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ  NULL     DEFAULT NULL,
+    last_used_at TIMESTAMPTZ  NULL     DEFAULT NULL,
+    revoked_at   TIMESTAMPTZ  NULL     DEFAULT NULL
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE requests ADD COLUMN failure_reason TEXT NULL DEFAULT NULL;
+
+CREATE INDEX requests_status_idx ON requests (status);
@@ -0,0 +1 @@
+ALTER TYPE request_type ADD VALUE 'guild_full';
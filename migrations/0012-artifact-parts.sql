@@ -0,0 +1,5 @@
+ALTER TABLE artifacts DROP CONSTRAINT artifacts_request_id_key;
+
+-- Large exports are split into several parts, each stored and downloaded separately. Existing artifacts are part 1.
+ALTER TABLE artifacts ADD COLUMN part int4 NOT NULL DEFAULT 1;
+ALTER TABLE artifacts ADD CONSTRAINT artifacts_request_id_part_key UNIQUE (request_id, part);
@@ -0,0 +1,3 @@
+CREATE TYPE archive_format AS ENUM ('zip', 'tar.gz', 'tar.zst');
+
+ALTER TABLE artifacts ADD COLUMN format archive_format NOT NULL DEFAULT 'zip';
@@ -0,0 +1,2 @@
+-- Records what a guild erasure has deleted so far, so that it can be retried without losing count of what was deleted
+ALTER TABLE requests ADD COLUMN erasure_progress jsonb NULL;
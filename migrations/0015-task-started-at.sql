@@ -0,0 +1,2 @@
+-- Set when a worker picks up the task, so that it isn't requeued or cancelled while it is running
+ALTER TABLE task_queue ADD COLUMN started_at timestamptz NULL;
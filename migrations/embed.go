@@ -0,0 +1,7 @@
+// Package migrations embeds the SQL migrations, so that the API and worker can check and apply them at startup.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
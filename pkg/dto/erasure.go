@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type ErasureReceipt struct {
+	RequestId          string           `json:"request_id"`
+	RequestType        string           `json:"request_type"`
+	UserId             uint64           `json:"user_id,string"`
+	GuildId            *uint64          `json:"guild_id,string,omitempty"`
+	RequestedAt        time.Time        `json:"requested_at"`
+	ErasedAt           time.Time        `json:"erased_at"`
+	DeletedRows        map[string]int64 `json:"deleted_rows"` // table -> number of rows deleted
+	DeletedTranscripts int              `json:"deleted_transcripts"`
+}
@@ -1,7 +1,6 @@
 package dto
 
 import (
-	"github.com/TicketsBot/database"
 	"time"
 )
 
@@ -11,54 +10,55 @@ type TicketUnion[T any] struct {
 }
 
 type GuildData struct {
-	GuildId                    uint64                                    `json:"guild_id,string"`
-	ActiveLanguage             *string                                   `json:"active_language"`
-	ArchiveChannel             *uint64                                   `json:"archive_channel,string"`
-	ArchiveMessages            []TicketUnion[database.ArchiveMessage]    `json:"archive_messages"`
-	AutocloseSettings          *database.AutoCloseSettings               `json:"autoclose_settings"`
-	AutocloseExcluded          []int                                     `json:"autoclose_excluded"` // ticket IDs
-	GuildBlacklistedUsers      []uint64                                  `json:"guild_blacklisted_users"`
-	ChannelCategory            *uint64                                   `json:"channel_category,string"`
-	ClaimSettings              *database.ClaimSettings                   `json:"claim_settings"`
-	CloseConfirmationEnabled   bool                                      `json:"close_confirmation_enabled"`
-	CloseReasons               []TicketUnion[database.CloseMetadata]     `json:"close_reasons"`
-	CustomColors               map[int16]int                             `json:"custom_colors"`
-	EmbedFields                []database.EmbedField                     `json:"embed_fields"`
-	Embeds                     []database.CustomEmbed                    `json:"embeds"`
-	ExitSurveyResponses        []TicketUnion[ExitSurveyResponse]         `json:"exit_survey_responses"`
-	FeedbackEnabled            bool                                      `json:"feedback_enabled"`
-	FirstResponseTimes         []FirstResponseTime                       `json:"first_response_times"`
-	FormInputs                 []database.FormInput                      `json:"form_inputs"`
-	Forms                      []database.Form                           `json:"forms"`
-	GuildIsGloballyBlacklisted bool                                      `json:"guild_is_globally_blacklisted"`
-	GuildMetadata              database.GuildMetadata                    `json:"guild_metadata"`
-	MultiPanels                []database.MultiPanel                     `json:"multi_panels"`
-	MultiPanelTargets          map[int][]int                             `json:"multi_panel_targets"` // multi_panel_id -> [panel_ids]
-	NamingScheme               *database.NamingScheme                    `json:"naming_scheme"`
-	OnCallUsers                []uint64                                  `json:"on_call_users"`
-	PanelAccessControlRules    map[int][]database.PanelAccessControlRule `json:"panel_access_control_rules"` // panel_id -> rules
-	PanelMentionUser           map[int]bool                              `json:"panel_mention_user"`
-	PanelRoleMentions          map[int][]uint64                          `json:"panel_role_mentions"`
-	Panels                     []database.Panel                          `json:"panels"`
-	PanelTeams                 map[int][]int                             `json:"panel_teams"`  // panel_id -> [team_ids]
-	Participants               map[int][]uint64                          `json:"participants"` // ticket_id -> [user_ids]
-	UserPermissions            []Permission                              `json:"user_permissions"`
-	GuildBlacklistedRoles      []uint64                                  `json:"guild_blacklisted_roles"`
-	RolePermissions            []Permission                              `json:"role_permissions"`
-	ServiceRatings             []TicketUnion[int16]                      `json:"service_ratings"`
-	Settings                   database.Settings                         `json:"settings"`
-	SupportTeamUsers           map[int][]uint64                          `json:"support_team_users"` // team_id -> [user_ids]
-	SupportTeamRoles           map[int][]uint64                          `json:"support_team_roles"` // team_id -> [role_ids]
-	SupportTeams               []database.SupportTeam                    `json:"support_teams"`
-	Tags                       []database.Tag                            `json:"tags"`
-	TicketClaims               []TicketUnion[uint64]                     `json:"ticket_claims"`
-	TicketLastMessages         []TicketUnion[database.TicketLastMessage] `json:"ticket_last_messages"`
-	TicketLimit                *int                                      `json:"ticket_limit"`
-	TicketAdditionalMembers    map[int][]uint64                          `json:"ticket_additional_members"` // ticket_id -> [user_ids]
-	TicketPermissions          database.TicketPermissions                `json:"ticket_permissions"`
-	Tickets                    []database.Ticket                         `json:"tickets"`
-	UsersCanClose              bool                                      `json:"users_can_close"`
-	WelcomeMessage             *string                                   `json:"welcome_message"`
+	FormatVersion              FormatVersion                     `json:"format_version"`
+	GuildId                    uint64                            `json:"guild_id,string"`
+	ActiveLanguage             *string                           `json:"active_language"`
+	ArchiveChannel             *uint64                           `json:"archive_channel,string"`
+	ArchiveMessages            []TicketUnion[ArchiveMessage]     `json:"archive_messages"`
+	AutocloseSettings          *AutoCloseSettings                `json:"autoclose_settings"`
+	AutocloseExcluded          []int                             `json:"autoclose_excluded"` // ticket IDs
+	GuildBlacklistedUsers      []uint64                          `json:"guild_blacklisted_users"`
+	ChannelCategory            *uint64                           `json:"channel_category,string"`
+	ClaimSettings              *ClaimSettings                    `json:"claim_settings"`
+	CloseConfirmationEnabled   bool                              `json:"close_confirmation_enabled"`
+	CloseReasons               []TicketUnion[CloseMetadata]      `json:"close_reasons"`
+	CustomColors               map[int16]int                     `json:"custom_colors"`
+	EmbedFields                []EmbedField                      `json:"embed_fields"`
+	Embeds                     []CustomEmbed                     `json:"embeds"`
+	ExitSurveyResponses        []TicketUnion[ExitSurveyResponse] `json:"exit_survey_responses"`
+	FeedbackEnabled            bool                              `json:"feedback_enabled"`
+	FirstResponseTimes         []FirstResponseTime               `json:"first_response_times"`
+	FormInputs                 []FormInput                       `json:"form_inputs"`
+	Forms                      []Form                            `json:"forms"`
+	GuildIsGloballyBlacklisted bool                              `json:"guild_is_globally_blacklisted"`
+	GuildMetadata              GuildMetadata                     `json:"guild_metadata"`
+	MultiPanels                []MultiPanel                      `json:"multi_panels"`
+	MultiPanelTargets          map[int][]int                     `json:"multi_panel_targets"` // multi_panel_id -> [panel_ids]
+	NamingScheme               *NamingScheme                     `json:"naming_scheme"`
+	OnCallUsers                []uint64                          `json:"on_call_users"`
+	PanelAccessControlRules    map[int][]PanelAccessControlRule  `json:"panel_access_control_rules"` // panel_id -> rules
+	PanelMentionUser           map[int]bool                      `json:"panel_mention_user"`
+	PanelRoleMentions          map[int][]uint64                  `json:"panel_role_mentions"`
+	Panels                     []Panel                           `json:"panels"`
+	PanelTeams                 map[int][]int                     `json:"panel_teams"`  // panel_id -> [team_ids]
+	Participants               map[int][]uint64                  `json:"participants"` // ticket_id -> [user_ids]
+	UserPermissions            []Permission                      `json:"user_permissions"`
+	GuildBlacklistedRoles      []uint64                          `json:"guild_blacklisted_roles"`
+	RolePermissions            []Permission                      `json:"role_permissions"`
+	ServiceRatings             []TicketUnion[int16]              `json:"service_ratings"`
+	Settings                   Settings                          `json:"settings"`
+	SupportTeamUsers           map[int][]uint64                  `json:"support_team_users"` // team_id -> [user_ids]
+	SupportTeamRoles           map[int][]uint64                  `json:"support_team_roles"` // team_id -> [role_ids]
+	SupportTeams               []SupportTeam                     `json:"support_teams"`
+	Tags                       []Tag                             `json:"tags"`
+	TicketClaims               []TicketUnion[uint64]             `json:"ticket_claims"`
+	TicketLastMessages         []TicketUnion[TicketLastMessage]  `json:"ticket_last_messages"`
+	TicketLimit                *int                              `json:"ticket_limit"`
+	TicketAdditionalMembers    map[int][]uint64                  `json:"ticket_additional_members"` // ticket_id -> [user_ids]
+	TicketPermissions          TicketPermissions                 `json:"ticket_permissions"`
+	Tickets                    []Ticket                          `json:"tickets"`
+	UsersCanClose              bool                              `json:"users_can_close"`
+	WelcomeMessage             *string                           `json:"welcome_message"`
 }
 
 // Shims
@@ -80,3 +80,191 @@ type ExitSurveyResponse struct {
 	QuestionId *int    `json:"question_id"`
 	Response   *string `json:"response"`
 }
+
+// Types mirroring those in the TicketsBot database package. They are copied rather than referenced, so that changes
+// to the database package do not silently change the export format.
+
+type ArchiveMessage struct {
+	ChannelId uint64 `json:"channel_id,string"`
+	MessageId uint64 `json:"message_id,string"`
+}
+
+type AutoCloseSettings struct {
+	Enabled                 bool           `json:"enabled"`
+	SinceOpenWithNoResponse *time.Duration `json:"since_open_with_no_response"`
+	SinceLastMessage        *time.Duration `json:"since_last_message"`
+	OnUserLeave             *bool          `json:"on_user_leave"`
+}
+
+type ClaimSettings struct {
+	SupportCanView bool `json:"support_can_view"`
+	SupportCanType bool `json:"support_can_type"`
+}
+
+type CloseMetadata struct {
+	Reason   *string `json:"reason"`
+	ClosedBy *uint64 `json:"closed_by,string"` // Null if auto-closed
+}
+
+type EmbedField struct {
+	FieldId int    `json:"field_id"`
+	EmbedId int    `json:"embed_id"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Inline  bool   `json:"inline"`
+}
+
+type CustomEmbed struct {
+	Id            int        `json:"id"`
+	GuildId       uint64     `json:"guild_id"`
+	Title         *string    `json:"title,omitempty"`
+	Description   *string    `json:"description,omitempty"`
+	Url           *string    `json:"url,omitempty"`
+	Colour        uint32     `json:"colour,omitempty"`
+	AuthorName    *string    `json:"author_name,omitempty"`
+	AuthorIconUrl *string    `json:"author_icon_url,omitempty"`
+	AuthorUrl     *string    `json:"author_url,omitempty"`
+	ImageUrl      *string    `json:"image_url,omitempty"`
+	ThumbnailUrl  *string    `json:"thumbnail_url,omitempty"`
+	FooterText    *string    `json:"footer_text,omitempty"`
+	FooterIconUrl *string    `json:"footer_icon_url,omitempty"`
+	Timestamp     *time.Time `json:"timestamp,omitempty"`
+}
+
+type CustomEmbedWithFields struct {
+	*CustomEmbed
+	Fields []EmbedField `json:"fields,omitempty"`
+}
+
+type FormInput struct {
+	Id          int     `json:"id"`
+	FormId      int     `json:"form_id"`
+	Position    int     `json:"position"`
+	CustomId    string  `json:"custom_id"`
+	Style       uint8   `json:"style"`
+	Label       string  `json:"label"`
+	Placeholder *string `json:"placeholder,omitempty"`
+	Required    bool    `json:"required"`
+	MinLength   *uint16 `json:"min_length,omitempty"`
+	MaxLength   *uint16 `json:"max_length,omitempty"`
+}
+
+type Form struct {
+	Id       int    `json:"form_id"`
+	GuildId  uint64 `json:"guild_id,string"`
+	Title    string `json:"title"`
+	CustomId string `json:"custom_id"`
+}
+
+type GuildMetadata struct {
+	OnCallRole *uint64 `json:"on_call_role_id"`
+}
+
+type MultiPanel struct {
+	Id                    int                    `json:"id"`
+	MessageId             uint64                 `json:"message_id,string"`
+	ChannelId             uint64                 `json:"channel_id,string"`
+	GuildId               uint64                 `json:"guild_id,string"`
+	SelectMenu            bool                   `json:"select_menu"`
+	SelectMenuPlaceholder *string                `json:"select_menu_placeholder"`
+	Embed                 *CustomEmbedWithFields `json:"embed"`
+}
+
+// NamingScheme is either "id" or "username".
+type NamingScheme string
+
+type PanelAccessControlRule struct {
+	RoleId uint64 `json:"role_id,string"`
+	Action string `json:"action"` // "allow" or "deny"
+}
+
+type Panel struct {
+	PanelId             int     `json:"panel_id"`
+	MessageId           uint64  `json:"message_id,string"`
+	ChannelId           uint64  `json:"channel_id,string"`
+	GuildId             uint64  `json:"guild_id,string"`
+	Title               string  `json:"title"`
+	Content             string  `json:"content"`
+	Colour              int32   `json:"colour"`
+	TargetCategory      uint64  `json:"category_id,string"`
+	EmojiName           *string `json:"emoji_name"`
+	EmojiId             *uint64 `json:"emoji_id,string"`
+	WelcomeMessageEmbed *int    `json:"welcome_message_embed"`
+	WithDefaultTeam     bool    `json:"default_team"`
+	CustomId            string  `json:"custom_id"`
+	ImageUrl            *string `json:"image_url,omitempty"`
+	ThumbnailUrl        *string `json:"thumbnail_url,omitempty"`
+	ButtonStyle         int     `json:"button_style"`
+	ButtonLabel         string  `json:"button_label"`
+	FormId              *int    `json:"form_id"`
+	NamingScheme        *string `json:"naming_scheme"`
+	ForceDisabled       bool    `json:"force_disabled"`
+	Disabled            bool    `json:"disabled"`
+	ExitSurveyFormId    *int    `json:"exit_survey_form_id"`
+	PendingCategory     *uint64 `json:"pending_category,string"`
+}
+
+type Settings struct {
+	HideClaimButton             bool    `json:"hide_claim_button"`
+	DisableOpenCommand          bool    `json:"disable_open_command"`
+	ContextMenuPermissionLevel  int     `json:"context_menu_permission_level,string"`
+	ContextMenuAddSender        bool    `json:"context_menu_add_sender"`
+	ContextMenuPanel            *int    `json:"context_menu_panel"`
+	StoreTranscripts            bool    `json:"store_transcripts"`
+	UseThreads                  bool    `json:"use_threads"`
+	TicketNotificationChannel   *uint64 `json:"ticket_notification_channel,string"`
+	ThreadArchiveDuration       int     `json:"thread_archive_duration"`
+	OverflowEnabled             bool    `json:"overflow_enabled"`
+	OverflowCategoryId          *uint64 `json:"overflow_category_id,string"` // If overflow_enabled and nil, use root
+	ExitSurveyFormId            *uint64 `json:"exit_survey_form_id,string"`
+	AnonymiseDashboardResponses bool    `json:"anonymise_dashboard_responses"`
+}
+
+type SupportTeam struct {
+	Id         int     `json:"id"`
+	GuildId    uint64  `json:"guild_id"`
+	Name       string  `json:"name"`
+	OnCallRole *uint64 `json:"on_call_role_id"`
+}
+
+// Tag has no JSON tags in the database package, so its fields are encoded with their Go names.
+type Tag struct {
+	Id                   string
+	GuildId              uint64
+	Content              *string
+	Embed                *CustomEmbedWithFields
+	ApplicationCommandId *uint64
+}
+
+type TicketLastMessage struct {
+	LastMessageId   *uint64    `json:"last_message_id"`
+	LastMessageTime *time.Time `json:"last_message_time"`
+	UserId          *uint64    `json:"last_message_user_id"`
+	UserIsStaff     *bool      `json:"last_message_user_is_staff"`
+}
+
+type TicketPermissions struct {
+	AttachFiles  bool `json:"attach_files"`
+	EmbedLinks   bool `json:"embed_links"`
+	AddReactions bool `json:"add_reactions"`
+}
+
+type Ticket struct {
+	Id               int        `json:"id"`
+	GuildId          uint64     `json:"guild_id"`
+	ChannelId        *uint64    `json:"channel_id"`
+	UserId           uint64     `json:"user_id"`
+	Open             bool       `json:"open"`
+	OpenTime         time.Time  `json:"open_time"`
+	WelcomeMessageId *uint64    `json:"welcome_message_id"`
+	PanelId          *int       `json:"panel_id"`
+	HasTranscript    bool       `json:"has_transcript"`
+	CloseTime        *time.Time `json:"close_time"`
+	IsThread         bool       `json:"is_thread"`
+	JoinMessageId    *uint64    `json:"join_message_id"`
+	NotesThreadId    *uint64    `json:"notes_thread_id"`
+	Status           string     `json:"status"` // "OPEN", "PENDING" or "CLOSED"
+	// TranscriptFile is the path of the ticket's transcript within a full guild export. It is only set in full guild
+	// exports, and only for tickets that have a transcript.
+	TranscriptFile *string `json:"transcript_file,omitempty"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// ExportIndex lists every file in a full guild export, with its SHA-256 hash. It is written to index.json, which is
+// the only signed file in the archive: every other file is verified by comparing its hash with the one in the index,
+// so a single signature covers the guild data, the metadata and every transcript.
+type ExportIndex struct {
+	GuildId uint64            `json:"guild_id,string"`
+	Files   map[string]string `json:"files"` // file name -> hex encoded SHA-256 hash
+}
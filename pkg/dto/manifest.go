@@ -0,0 +1,9 @@
+package dto
+
+// TranscriptManifest lists the ticket ID of every transcript in a guild transcripts export. It is written to
+// manifest.json and signed, so that transcripts removed from the archive are detected, rather than the rest of the
+// archive still validating. Transcripts that failed to export are listed in failed.txt instead.
+type TranscriptManifest struct {
+	GuildId     uint64 `json:"guild_id,string"`
+	Transcripts []int  `json:"transcripts"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+// ExportMetadata describes how a guild data or transcripts export was produced. It is written to metadata.json, and
+// signed like every other file in the archive. Exports made before metadata was added do not contain it.
+type ExportMetadata struct {
+	RequestId   string            `json:"request_id"`
+	RequestType string            `json:"request_type"`
+	GuildId     uint64            `json:"guild_id,string"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Redaction   RedactionMetadata `json:"redaction"`
+
+	// Sections lists the keys of data.json that were exported, for guild data exports. Other keys are left as their
+	// zero value. Absent from guild data exports made before sections could be selected, which include every section.
+	Sections []string `json:"sections,omitempty"`
+}
+
+// RedactionMetadata records the redaction profile applied to an export. When user IDs are pseudonymised, each is
+// replaced with the first 8 bytes of HMAC-SHA256(salt, decimal user ID), read as a big endian integer. The salt is
+// random for each export and is not kept, so pseudonyms are consistent within an export, but can't be linked to
+// users or to the pseudonyms in other exports.
+type RedactionMetadata struct {
+	Profile         string `json:"profile"` // "none", "pseudonymise" or "pseudonymise_strip_content"
+	Algorithm       string `json:"algorithm,omitempty"`
+	Pseudonymised   bool   `json:"pseudonymised"`
+	ContentStripped bool   `json:"content_stripped"`
+}
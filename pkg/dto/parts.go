@@ -0,0 +1,16 @@
+package dto
+
+// ArchiveParts is written to parts.json in every part of an export that was too large for a single archive, and is
+// signed. It lists every part of the export along with the SHA-256 hash of each file in it, so that each part can be
+// verified on its own, without the others, and a complete set of parts can be checked for missing or mixed up
+// parts. Exports that fit in a single archive do not contain it.
+type ArchiveParts struct {
+	RequestId string        `json:"request_id"`
+	Part      int           `json:"part"` // The part that this file was found in, numbered from 1
+	Parts     []ArchivePart `json:"parts"`
+}
+
+type ArchivePart struct {
+	Part  int               `json:"part"`
+	Files map[string]string `json:"files"` // file name -> hex encoded SHA-256 hash
+}
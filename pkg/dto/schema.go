@@ -0,0 +1,212 @@
+package dto
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12) document.
+type Schema map[string]any
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaTypes are the documents that schemas are generated for, keyed by schema name. Transcripts are described in
+// their latest version only.
+var schemaTypes = map[string]struct {
+	title string
+	value any
+}{
+	"guild-data":      {"Guild data export (data.json)", GuildData{}},
+	"user-data":       {"User data export (data.json)", UserData{}},
+	"transcript":      {"Transcript", Transcript{}},
+	"erasure-receipt": {"Erasure receipt (receipt.json)", ErasureReceipt{}},
+	"export-metadata": {"Export metadata (metadata.json)", ExportMetadata{}},
+	"archive-parts":   {"Archive parts index (parts.json)", ArchiveParts{}},
+}
+
+// SchemaNames returns the names of the schemas that can be generated with GenerateSchema, sorted alphabetically.
+func SchemaNames() []string {
+	names := make([]string, 0, len(schemaTypes))
+	for name := range schemaTypes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// GenerateSchema generates the JSON Schema for the named document from the DTOs in this package, so that the schema
+// always matches the format produced by encoding/json.
+func GenerateSchema(name string) (Schema, bool) {
+	typ, ok := schemaTypes[name]
+	if !ok {
+		return nil, false
+	}
+
+	g := schemaGenerator{
+		defs: make(map[string]Schema),
+	}
+
+	root := g.structSchema(reflect.TypeOf(typ.value))
+	root["$schema"] = schemaDialect
+	root["title"] = typ.title
+
+	if len(g.defs) > 0 {
+		root["$defs"] = g.defs
+	}
+
+	return root, true
+}
+
+type schemaGenerator struct {
+	defs map[string]Schema
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+
+	// Generic type names include the package path of their type arguments, e.g. TicketUnion[example.com/dto.Form]
+	packagePathRegex = regexp.MustCompile(`[\w.\-/]+\.`)
+)
+
+// schema returns the schema for a value of type t. asString is set for fields tagged with the ",string" option,
+// which encodes numbers as JSON strings.
+func (g *schemaGenerator) schema(t reflect.Type, asString bool) Schema {
+	switch t {
+	case timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case durationType:
+		return Schema{"type": "integer", "description": "Duration in nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem(), asString))
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if asString {
+			return Schema{"type": "string", "pattern": "^-?[0-9]+$"}
+		}
+
+		return Schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if asString {
+			return Schema{"type": "string", "pattern": "^[0-9]+$"}
+		}
+
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+
+		// Nil slices are encoded as null
+		return nullable(Schema{"type": "array", "items": g.schema(t.Elem(), false)})
+	case reflect.Map:
+		schema := Schema{"type": "object", "additionalProperties": g.schema(t.Elem(), false)}
+
+		// Integer keys are encoded as strings
+		switch t.Key().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			schema["propertyNames"] = Schema{"pattern": "^-?[0-9]+$"}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema["propertyNames"] = Schema{"pattern": "^[0-9]+$"}
+		}
+
+		return nullable(schema)
+	case reflect.Struct:
+		return g.ref(t)
+	default:
+		return Schema{}
+	}
+}
+
+// ref adds the struct to $defs, if it is not already present, and returns a reference to it.
+func (g *schemaGenerator) ref(t reflect.Type) Schema {
+	name := definitionName(t)
+	if _, ok := g.defs[name]; !ok {
+		// Reserve the name before generating, in case the struct refers to itself
+		g.defs[name] = nil
+		g.defs[name] = g.structSchema(t)
+	}
+
+	return Schema{"$ref": "#/$defs/" + name}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) Schema {
+	properties := make(map[string]Schema)
+	required := make([]string, 0)
+	g.addFields(t, properties, &required)
+
+	schema := Schema{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// addFields adds the struct's fields to properties, following the rules of encoding/json: fields of embedded structs
+// without a JSON name are promoted, omitempty fields are optional, and fields tagged "-" are skipped.
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, properties, required)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		optionList := strings.Split(options, ",")
+		properties[name] = g.schema(field.Type, slices.Contains(optionList, "string"))
+
+		if !slices.Contains(optionList, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func nullable(schema Schema) Schema {
+	return Schema{"anyOf": []Schema{schema, {"type": "null"}}}
+}
+
+func definitionName(t reflect.Type) string {
+	name := packagePathRegex.ReplaceAllString(t.Name(), "")
+	return strings.NewReplacer("[", "_", "]", "", ",", "_", " ", "").Replace(name)
+}
@@ -0,0 +1,188 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TranscriptVersion identifies the format a transcript was archived in. Archived transcripts do not record their
+// version, so it is detected by DecodeTranscript.
+type TranscriptVersion int
+
+const (
+	// TranscriptVersionLegacy transcripts are a bare array of messages, with the author embedded in each message.
+	TranscriptVersionLegacy TranscriptVersion = 1
+	// TranscriptVersionEntities transcripts are an object, with users, channels and roles deduplicated into an
+	// entities map that messages refer to by ID.
+	TranscriptVersionEntities TranscriptVersion = 2
+
+	TranscriptVersionLatest = TranscriptVersionEntities
+)
+
+var (
+	ErrEmptyTranscript              = errors.New("transcript is empty")
+	ErrUnsupportedTranscriptVersion = errors.New("unsupported transcript version")
+)
+
+// Transcript is the archived record of a ticket's messages. Transcripts of every version are decoded into this
+// structure, with legacy transcripts normalised so that authors are found in Entities.
+type Transcript struct {
+	Version  TranscriptVersion   `json:"-"`
+	Entities TranscriptEntities  `json:"entities"`
+	Messages []TranscriptMessage `json:"messages"`
+}
+
+// TranscriptEntities are keyed by their ID, as a string.
+type TranscriptEntities struct {
+	Users    map[string]TranscriptUser    `json:"users"`
+	Channels map[string]TranscriptChannel `json:"channels"`
+	Roles    map[string]TranscriptRole    `json:"roles"`
+}
+
+type TranscriptUser struct {
+	Id       uint64 `json:"id,string"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Bot      bool   `json:"bot"`
+}
+
+type TranscriptChannel struct {
+	Id   uint64 `json:"id,string"`
+	Name string `json:"name"`
+}
+
+type TranscriptRole struct {
+	Id     uint64 `json:"id,string"`
+	Name   string `json:"name"`
+	Colour int    `json:"color"`
+}
+
+type TranscriptMessage struct {
+	Id          uint64                 `json:"id,string"`
+	AuthorId    uint64                 `json:"author_id,string"`
+	Author      *TranscriptUser        `json:"author,omitempty"` // Legacy format only, use AuthorId
+	Content     string                 `json:"content"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Embeds      []TranscriptEmbed      `json:"embeds"`
+	Attachments []TranscriptAttachment `json:"attachments"`
+}
+
+type TranscriptEmbed struct {
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Url         string                 `json:"url"`
+	Colour      int                    `json:"color"`
+	Author      *TranscriptEmbedAuthor `json:"author"`
+	Fields      []TranscriptEmbedField `json:"fields"`
+	Image       *TranscriptEmbedMedia  `json:"image"`
+	Thumbnail   *TranscriptEmbedMedia  `json:"thumbnail"`
+	Footer      *TranscriptEmbedFooter `json:"footer"`
+}
+
+type TranscriptEmbedAuthor struct {
+	Name    string `json:"name"`
+	Url     string `json:"url"`
+	IconUrl string `json:"icon_url"`
+}
+
+type TranscriptEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type TranscriptEmbedMedia struct {
+	Url string `json:"url"`
+}
+
+type TranscriptEmbedFooter struct {
+	Text    string `json:"text"`
+	IconUrl string `json:"icon_url"`
+}
+
+type TranscriptAttachment struct {
+	Filename string `json:"filename"`
+	Size     int    `json:"size"`
+	Url      string `json:"url"`
+}
+
+// DecodeTranscript decodes a transcript of any supported version. Transcripts may carry a numeric "version" field;
+// those without one are detected from their structure. ErrUnsupportedTranscriptVersion is returned for versions newer
+// than TranscriptVersionLatest, which this package does not know how to read.
+func DecodeTranscript(data []byte) (*Transcript, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, ErrEmptyTranscript
+	}
+
+	if data[0] == '[' {
+		return decodeLegacyTranscript(data)
+	}
+
+	var header struct {
+		Version *TranscriptVersion `json:"version"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	version := TranscriptVersionEntities
+	if header.Version != nil {
+		version = *header.Version
+	}
+
+	if version != TranscriptVersionEntities {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedTranscriptVersion, version)
+	}
+
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, err
+	}
+
+	transcript.Version = version
+	return &transcript, nil
+}
+
+func decodeLegacyTranscript(data []byte) (*Transcript, error) {
+	var messages []TranscriptMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+
+	transcript := Transcript{
+		Version: TranscriptVersionLegacy,
+		Entities: TranscriptEntities{
+			Users: make(map[string]TranscriptUser),
+		},
+		Messages: messages,
+	}
+
+	for i, msg := range messages {
+		if msg.Author != nil {
+			transcript.Messages[i].AuthorId = msg.Author.Id
+			transcript.Entities.Users[strconv.FormatUint(msg.Author.Id, 10)] = *msg.Author
+		}
+	}
+
+	return &transcript, nil
+}
+
+// User returns the user with the given ID from the transcript's entities.
+func (t *Transcript) User(id uint64) (TranscriptUser, bool) {
+	user, ok := t.Entities.Users[strconv.FormatUint(id, 10)]
+	return user, ok
+}
+
+func (u TranscriptUser) AvatarUrl() string {
+	if u.Avatar == "" {
+		return fmt.Sprintf("https://cdn.discordapp.com/embed/avatars/%d.png", (u.Id>>22)%6)
+	}
+
+	return fmt.Sprintf("https://cdn.discordapp.com/avatars/%d/%s.webp?size=64", u.Id, u.Avatar)
+}
@@ -0,0 +1,24 @@
+package dto
+
+type GuildTicket struct {
+	GuildId  uint64 `json:"guild_id,string"`
+	TicketId int    `json:"ticket_id"`
+}
+
+type GuildTicketUnion[T any] struct {
+	GuildId  uint64 `json:"guild_id,string"`
+	TicketId int    `json:"ticket_id"`
+	Data     T      `json:"data"`
+}
+
+type UserData struct {
+	FormatVersion       FormatVersion                          `json:"format_version"`
+	UserId              uint64                                 `json:"user_id,string"`
+	Tickets             []Ticket                               `json:"tickets"`               // tickets opened by the user
+	Participation       []GuildTicket                          `json:"participation"`         // tickets the user has sent messages in
+	TicketClaims        []GuildTicket                          `json:"ticket_claims"`         // tickets claimed by the user
+	ServiceRatings      []GuildTicketUnion[int16]              `json:"service_ratings"`       // ratings left on the user's tickets
+	ExitSurveyResponses []GuildTicketUnion[ExitSurveyResponse] `json:"exit_survey_responses"` // responses left on the user's tickets
+	BlacklistedGuilds   []uint64                               `json:"blacklisted_guilds"`
+	GloballyBlacklisted bool                                   `json:"globally_blacklisted"`
+}
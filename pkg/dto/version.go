@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FormatVersion is the version of the data.json format of guild and user data exports. It is incremented whenever
+// the format changes, so that consumers can tell which fields to expect.
+type FormatVersion int
+
+const (
+	// FormatVersionUnversioned exports were produced before the format was versioned, and have no format_version
+	// field. Their structure is otherwise identical to FormatVersionDecoupled.
+	FormatVersionUnversioned FormatVersion = 1
+	// FormatVersionDecoupled exports record their format_version, and are encoded from the types in this package
+	// rather than those of the TicketsBot database package.
+	FormatVersionDecoupled FormatVersion = 2
+
+	FormatVersionLatest = FormatVersionDecoupled
+)
+
+var ErrUnsupportedFormatVersion = errors.New("unsupported export format version")
+
+// DecodeGuildData decodes a guild data export of any supported format version. FormatVersion is set on the result
+// even for exports that predate it.
+func DecodeGuildData(data []byte) (*GuildData, error) {
+	var guildData GuildData
+	if err := decodeVersioned(data, &guildData, &guildData.FormatVersion); err != nil {
+		return nil, err
+	}
+
+	return &guildData, nil
+}
+
+// DecodeUserData decodes a user data export of any supported format version. FormatVersion is set on the result even
+// for exports that predate it.
+func DecodeUserData(data []byte) (*UserData, error) {
+	var userData UserData
+	if err := decodeVersioned(data, &userData, &userData.FormatVersion); err != nil {
+		return nil, err
+	}
+
+	return &userData, nil
+}
+
+func decodeVersioned(data []byte, out any, version *FormatVersion) error {
+	if err := json.Unmarshal(data, out); err != nil {
+		return err
+	}
+
+	// The field is absent from exports that predate versioning, so is decoded as zero
+	if *version == 0 {
+		*version = FormatVersionUnversioned
+	}
+
+	if *version < FormatVersionUnversioned || *version > FormatVersionLatest {
+		return fmt.Errorf("%w: %d", ErrUnsupportedFormatVersion, *version)
+	}
+
+	return nil
+}
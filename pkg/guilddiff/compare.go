@@ -0,0 +1,311 @@
+package guilddiff
+
+import (
+	"github.com/TicketsBot/export/pkg/dto"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Sections, in the order their changes are listed
+const (
+	SectionSettings              = "settings"
+	SectionPanels                = "panels"
+	SectionMultiPanels           = "multi_panels"
+	SectionSupportTeams          = "support_teams"
+	SectionForms                 = "forms"
+	SectionEmbeds                = "embeds"
+	SectionTags                  = "tags"
+	SectionUserPermissions       = "user_permissions"
+	SectionRolePermissions       = "role_permissions"
+	SectionGuildBlacklistedUsers = "guild_blacklisted_users"
+	SectionGuildBlacklistedRoles = "guild_blacklisted_roles"
+	SectionOnCallUsers           = "on_call_users"
+)
+
+// Compare returns the configuration changes made between two exports of a guild's data. sections lists the keys of
+// data.json included in both exports, as returned by CommonSections, or is nil if both include every section. Sections
+// missing from either export are left as their zero value, so are not compared, rather than listed as removed.
+func Compare(old, new *dto.GuildData, sections []string) (*Diff, error) {
+	if sections != nil {
+		old, new = onlySections(old, sections), onlySections(new, sections)
+	}
+
+	d := &Diff{
+		OldGuildId: old.GuildId,
+		NewGuildId: new.GuildId,
+		Changes:    make([]Change, 0),
+	}
+
+	if err := d.compareSingle(SectionSettings, settingsOf(old), settingsOf(new)); err != nil {
+		return nil, err
+	}
+
+	entities := []struct {
+		section  string
+		old, new []entity
+	}{
+		{SectionPanels, panels(old), panels(new)},
+		{SectionMultiPanels, multiPanels(old), multiPanels(new)},
+		{SectionSupportTeams, supportTeams(old), supportTeams(new)},
+		{SectionForms, forms(old), forms(new)},
+		{SectionEmbeds, embeds(old), embeds(new)},
+		{SectionTags, tags(old), tags(new)},
+		{SectionUserPermissions, permissions(old.UserPermissions), permissions(new.UserPermissions)},
+		{SectionRolePermissions, permissions(old.RolePermissions), permissions(new.RolePermissions)},
+	}
+
+	for _, e := range entities {
+		if err := d.compareEntities(e.section, e.old, e.new); err != nil {
+			return nil, err
+		}
+	}
+
+	sets := []struct {
+		section  string
+		old, new []uint64
+	}{
+		{SectionGuildBlacklistedUsers, old.GuildBlacklistedUsers, new.GuildBlacklistedUsers},
+		{SectionGuildBlacklistedRoles, old.GuildBlacklistedRoles, new.GuildBlacklistedRoles},
+		{SectionOnCallUsers, old.OnCallUsers, new.OnCallUsers},
+	}
+
+	for _, set := range sets {
+		if err := d.compareSets(set.section, set.old, set.new); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// CommonSections returns the sections included in both exports, given the sections listed in the metadata.json of
+// each. Exports without sections listed include every section, so nil is returned if neither export lists any.
+func CommonSections(old, new []string) []string {
+	switch {
+	case len(old) == 0:
+		return new
+	case len(new) == 0:
+		return old
+	}
+
+	common := make([]string, 0, len(old))
+	for _, section := range old {
+		if slices.Contains(new, section) {
+			common = append(common, section)
+		}
+	}
+
+	return common
+}
+
+// onlySections returns a copy of data with every key of data.json not in sections set to its zero value, as it would
+// be in an export that didn't include it.
+func onlySections(data *dto.GuildData, sections []string) *dto.GuildData {
+	filtered := *data
+
+	v := reflect.ValueOf(&filtered).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if key != "format_version" && key != "guild_id" && !slices.Contains(sections, key) {
+			v.Field(i).SetZero()
+		}
+	}
+
+	return &filtered
+}
+
+// settings gathers the guild-wide configuration, which is spread across several fields of GuildData, into a single
+// entity. The fields of dto.Settings are promoted, so are compared as e.g. use_threads rather than
+// settings.use_threads.
+type settings struct {
+	dto.Settings
+	ActiveLanguage           *string                `json:"active_language"`
+	ArchiveChannel           *uint64                `json:"archive_channel,string"`
+	AutocloseSettings        *dto.AutoCloseSettings `json:"autoclose_settings"`
+	ChannelCategory          *uint64                `json:"channel_category,string"`
+	ClaimSettings            *dto.ClaimSettings     `json:"claim_settings"`
+	CloseConfirmationEnabled bool                   `json:"close_confirmation_enabled"`
+	CustomColors             map[int16]int          `json:"custom_colors"`
+	FeedbackEnabled          bool                   `json:"feedback_enabled"`
+	GuildMetadata            dto.GuildMetadata      `json:"guild_metadata"`
+	NamingScheme             *dto.NamingScheme      `json:"naming_scheme"`
+	TicketLimit              *int                   `json:"ticket_limit"`
+	TicketPermissions        dto.TicketPermissions  `json:"ticket_permissions"`
+	UsersCanClose            bool                   `json:"users_can_close"`
+	WelcomeMessage           *string                `json:"welcome_message"`
+}
+
+func settingsOf(data *dto.GuildData) settings {
+	return settings{
+		Settings:                 data.Settings,
+		ActiveLanguage:           data.ActiveLanguage,
+		ArchiveChannel:           data.ArchiveChannel,
+		AutocloseSettings:        data.AutocloseSettings,
+		ChannelCategory:          data.ChannelCategory,
+		ClaimSettings:            data.ClaimSettings,
+		CloseConfirmationEnabled: data.CloseConfirmationEnabled,
+		CustomColors:             data.CustomColors,
+		FeedbackEnabled:          data.FeedbackEnabled,
+		GuildMetadata:            data.GuildMetadata,
+		NamingScheme:             data.NamingScheme,
+		TicketLimit:              data.TicketLimit,
+		TicketPermissions:        data.TicketPermissions,
+		UsersCanClose:            data.UsersCanClose,
+		WelcomeMessage:           data.WelcomeMessage,
+	}
+}
+
+func panels(data *dto.GuildData) []entity {
+	type panel struct {
+		dto.Panel
+		Teams              []int                        `json:"teams"`
+		RoleMentions       []uint64                     `json:"role_mentions"`
+		MentionUser        bool                         `json:"mention_user"`
+		AccessControlRules []dto.PanelAccessControlRule `json:"access_control_rules"`
+	}
+
+	entities := make([]entity, len(data.Panels))
+	for i, p := range data.Panels {
+		// Rules are evaluated in order, so unlike teams and mentions, their order is significant
+		entities[i] = newEntity(strconv.Itoa(p.PanelId), p.Title, panel{
+			Panel:              p,
+			Teams:              sorted(data.PanelTeams[p.PanelId]),
+			RoleMentions:       sorted(data.PanelRoleMentions[p.PanelId]),
+			MentionUser:        data.PanelMentionUser[p.PanelId],
+			AccessControlRules: data.PanelAccessControlRules[p.PanelId],
+		})
+	}
+
+	return entities
+}
+
+func multiPanels(data *dto.GuildData) []entity {
+	type multiPanel struct {
+		dto.MultiPanel
+		Panels []int `json:"panels"`
+	}
+
+	entities := make([]entity, len(data.MultiPanels))
+	for i, p := range data.MultiPanels {
+		var label string
+		if p.Embed != nil && p.Embed.CustomEmbed != nil && p.Embed.Title != nil {
+			label = *p.Embed.Title
+		}
+
+		entities[i] = newEntity(strconv.Itoa(p.Id), label, multiPanel{
+			MultiPanel: p,
+			Panels:     sorted(data.MultiPanelTargets[p.Id]),
+		})
+	}
+
+	return entities
+}
+
+func supportTeams(data *dto.GuildData) []entity {
+	type supportTeam struct {
+		dto.SupportTeam
+		Users []uint64 `json:"users"`
+		Roles []uint64 `json:"roles"`
+	}
+
+	entities := make([]entity, len(data.SupportTeams))
+	for i, team := range data.SupportTeams {
+		entities[i] = newEntity(strconv.Itoa(team.Id), team.Name, supportTeam{
+			SupportTeam: team,
+			Users:       sorted(data.SupportTeamUsers[team.Id]),
+			Roles:       sorted(data.SupportTeamRoles[team.Id]),
+		})
+	}
+
+	return entities
+}
+
+func forms(data *dto.GuildData) []entity {
+	type form struct {
+		dto.Form
+		Inputs []dto.FormInput `json:"inputs"`
+	}
+
+	inputs := make(map[int][]dto.FormInput)
+	for _, input := range data.FormInputs {
+		inputs[input.FormId] = append(inputs[input.FormId], input)
+	}
+
+	entities := make([]entity, len(data.Forms))
+	for i, f := range data.Forms {
+		formInputs := inputs[f.Id]
+		sort.Slice(formInputs, func(a, b int) bool {
+			return formInputs[a].Position < formInputs[b].Position
+		})
+
+		entities[i] = newEntity(strconv.Itoa(f.Id), f.Title, form{
+			Form:   f,
+			Inputs: formInputs,
+		})
+	}
+
+	return entities
+}
+
+func embeds(data *dto.GuildData) []entity {
+	type embed struct {
+		dto.CustomEmbed
+		Fields []dto.EmbedField `json:"fields"`
+	}
+
+	fields := make(map[int][]dto.EmbedField)
+	for _, field := range data.EmbedFields {
+		fields[field.EmbedId] = append(fields[field.EmbedId], field)
+	}
+
+	entities := make([]entity, len(data.Embeds))
+	for i, e := range data.Embeds {
+		var label string
+		if e.Title != nil {
+			label = *e.Title
+		}
+
+		entities[i] = newEntity(strconv.Itoa(e.Id), label, embed{
+			CustomEmbed: e,
+			Fields:      fields[e.Id],
+		})
+	}
+
+	return entities
+}
+
+func tags(data *dto.GuildData) []entity {
+	entities := make([]entity, len(data.Tags))
+	for i, tag := range data.Tags {
+		entities[i] = newEntity(tag.Id, "", tag)
+	}
+
+	return entities
+}
+
+func permissions(permissions []dto.Permission) []entity {
+	entities := make([]entity, len(permissions))
+	for i, permission := range permissions {
+		entities[i] = newEntity(strconv.FormatUint(permission.Snowflake, 10), "", permission)
+	}
+
+	return entities
+}
+
+func newEntity(key, label string, v any) entity {
+	return entity{
+		key:   key,
+		label: label,
+		value: v,
+	}
+}
+
+// sorted returns a sorted copy, as the order of these lists is not significant and may differ between exports.
+func sorted[T int | uint64](values []T) []T {
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return values
+}
@@ -0,0 +1,227 @@
+// Package guilddiff compares two guild data exports, producing the configuration changes between them keyed by the
+// stable IDs of panels, support teams, forms and other entities. Ticket history, such as tickets, claims and ratings,
+// is not compared.
+package guilddiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+type ChangeType string
+
+const (
+	ChangeTypeAdded    ChangeType = "added"
+	ChangeTypeRemoved  ChangeType = "removed"
+	ChangeTypeModified ChangeType = "modified"
+)
+
+type Diff struct {
+	OldGuildId uint64   `json:"old_guild_id,string"`
+	NewGuildId uint64   `json:"new_guild_id,string"`
+	Changes    []Change `json:"changes"`
+}
+
+// Change is a single entity that was added, removed or modified. Key is the entity's ID within its section, and is
+// empty for sections holding a single entity, such as settings.
+type Change struct {
+	Section string        `json:"section"`
+	Key     string        `json:"key,omitempty"`
+	Label   string        `json:"label,omitempty"` // The entity's name or title, if it has one
+	Type    ChangeType    `json:"type"`
+	Fields  []FieldChange `json:"fields,omitempty"` // Only set for modified entities
+}
+
+// FieldChange is a changed field of a modified entity. Fields of nested objects are named by their path, separated
+// by dots, e.g. autoclose_settings.enabled. A nil Old or New means the field was absent.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Empty returns whether there are no changes between the exports.
+func (d *Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// entity is an entity being compared, keyed by its ID. value is converted to its JSON representation when compared.
+type entity struct {
+	key   string
+	label string
+	value any
+}
+
+func (d *Diff) compareSingle(section string, old, new any) error {
+	oldFields, err := toFields(old)
+	if err != nil {
+		return err
+	}
+
+	newFields, err := toFields(new)
+	if err != nil {
+		return err
+	}
+
+	if changes := compareFields(oldFields, newFields); len(changes) > 0 {
+		d.Changes = append(d.Changes, Change{
+			Section: section,
+			Type:    ChangeTypeModified,
+			Fields:  changes,
+		})
+	}
+
+	return nil
+}
+
+func (d *Diff) compareEntities(section string, old, new []entity) error {
+	oldByKey := make(map[string]entity, len(old))
+	for _, e := range old {
+		oldByKey[e.key] = e
+	}
+
+	newByKey := make(map[string]entity, len(new))
+	for _, e := range new {
+		newByKey[e.key] = e
+	}
+
+	changes := make([]Change, 0)
+	for key, o := range oldByKey {
+		n, ok := newByKey[key]
+		if !ok {
+			changes = append(changes, Change{Section: section, Key: key, Label: o.label, Type: ChangeTypeRemoved})
+			continue
+		}
+
+		oldFields, err := o.fields()
+		if err != nil {
+			return err
+		}
+
+		newFields, err := n.fields()
+		if err != nil {
+			return err
+		}
+
+		if fields := compareFields(oldFields, newFields); len(fields) > 0 {
+			changes = append(changes, Change{Section: section, Key: key, Label: n.label, Type: ChangeTypeModified, Fields: fields})
+		}
+	}
+
+	for key, n := range newByKey {
+		if _, ok := oldByKey[key]; !ok {
+			changes = append(changes, Change{Section: section, Key: key, Label: n.label, Type: ChangeTypeAdded})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return lessKey(changes[i].Key, changes[j].Key)
+	})
+
+	d.Changes = append(d.Changes, changes...)
+	return nil
+}
+
+// compareSets records IDs added to or removed from a list, such as the blacklist.
+func (d *Diff) compareSets(section string, old, new []uint64) error {
+	toEntities := func(ids []uint64) []entity {
+		entities := make([]entity, len(ids))
+		for i, id := range ids {
+			entities[i] = entity{key: strconv.FormatUint(id, 10)}
+		}
+
+		return entities
+	}
+
+	return d.compareEntities(section, toEntities(old), toEntities(new))
+}
+
+func (e entity) fields() (map[string]any, error) {
+	fields, err := toFields(e.value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every entity belongs to the exported guild, so comparing exports of different guilds would otherwise report a
+	// change to every entity
+	delete(fields, "guild_id")
+	delete(fields, "GuildId")
+
+	return fields, nil
+}
+
+// toFields converts a value to its JSON representation, so that fields are compared and named as they appear in
+// data.json. Numbers are kept as json.Number, as snowflakes don't fit in the float64 they would otherwise be decoded
+// as, and IDs that differ only in their last digits would compare as equal.
+func toFields(v any) (map[string]any, error) {
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(marshalled))
+	decoder.UseNumber()
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func compareFields(old, new map[string]any) []FieldChange {
+	changes := make([]FieldChange, 0)
+	compareFieldsWithPrefix("", old, new, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+func compareFieldsWithPrefix(prefix string, old, new map[string]any, changes *[]FieldChange) {
+	keys := make(map[string]struct{}, len(old)+len(new))
+	for key := range old {
+		keys[key] = struct{}{}
+	}
+
+	for key := range new {
+		keys[key] = struct{}{}
+	}
+
+	for key := range keys {
+		o, n := old[key], new[key]
+
+		// Descend into nested objects, so that only the fields that changed are reported
+		oldObject, oldIsObject := o.(map[string]any)
+		newObject, newIsObject := n.(map[string]any)
+		if oldIsObject && newIsObject {
+			compareFieldsWithPrefix(prefix+key+".", oldObject, newObject, changes)
+			continue
+		}
+
+		if !reflect.DeepEqual(o, n) {
+			*changes = append(*changes, FieldChange{
+				Field: prefix + key,
+				Old:   o,
+				New:   n,
+			})
+		}
+	}
+}
+
+// lessKey sorts numeric IDs numerically, and anything else, such as tag IDs, alphabetically.
+func lessKey(a, b string) bool {
+	aInt, aErr := strconv.ParseUint(a, 10, 64)
+	bInt, bErr := strconv.ParseUint(b, 10, 64)
+	if aErr == nil && bErr == nil {
+		return aInt < bInt
+	}
+
+	return a < b
+}
@@ -0,0 +1,77 @@
+package guilddiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteText writes a human readable summary of the diff, grouped by section. Added entities are prefixed with +,
+// removed entities with - and modified entities with ~, followed by each changed field.
+func (d *Diff) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if d.OldGuildId != d.NewGuildId {
+		fmt.Fprintf(bw, "Comparing guild %d with guild %d\n\n", d.OldGuildId, d.NewGuildId)
+	}
+
+	if d.Empty() {
+		fmt.Fprintln(bw, "No differences")
+		return bw.Flush()
+	}
+
+	var section string
+	for _, change := range d.Changes {
+		if change.Section != section {
+			if section != "" {
+				fmt.Fprintln(bw)
+			}
+
+			section = change.Section
+			fmt.Fprintf(bw, "%s:\n", section)
+		}
+
+		var prefix string
+		switch change.Type {
+		case ChangeTypeAdded:
+			prefix = "+"
+		case ChangeTypeRemoved:
+			prefix = "-"
+		default:
+			prefix = "~"
+		}
+
+		fmt.Fprintf(bw, "  %s %s\n", prefix, change.name())
+
+		for _, field := range change.Fields {
+			fmt.Fprintf(bw, "      %s: %s -> %s\n", field.Field, formatValue(field.Old), formatValue(field.New))
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (c Change) name() string {
+	switch {
+	case c.Key == "":
+		return c.Section
+	case c.Label == "":
+		return c.Key
+	default:
+		return fmt.Sprintf("%s (%q)", c.Key, c.Label)
+	}
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return "null"
+	}
+
+	marshalled, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(marshalled)
+}
@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"github.com/TicketsBot/export/pkg/dto"
+	"io"
+)
+
+func (v *Validator) ValidateErasureReceipt(input io.ReaderAt, size int64) (*dto.ErasureReceipt, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := reader.Open("receipt.json")
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(v.newLimitReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := v.validateSignature(reader, "receipt.json", data); err != nil {
+		return nil, err
+	}
+
+	if err := checkVerified(reader, map[string]bool{"receipt.json": true}); err != nil {
+		return nil, err
+	}
+
+	var receipt dto.ErasureReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrValidationFailed    = errors.New("validation failed")
 	ErrMaximumSizeExceeded = errors.New("maximum size exceeded")
+	ErrUnknownFormat       = errors.New("archive is not a zip, tar.gz or tar.zst file")
+	ErrSqliteGuildData     = errors.New("guild data export is in the SQLite format")
 )
@@ -0,0 +1,244 @@
+package validator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+)
+
+// ArchiveFormat is the container and compression that an export is packaged in.
+type ArchiveFormat string
+
+const (
+	ArchiveFormatZip    ArchiveFormat = "zip"
+	ArchiveFormatTarGz  ArchiveFormat = "tar.gz"
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+)
+
+var (
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DetectFormat works out the format of an archive from its first bytes.
+func DetectFormat(input io.ReaderAt) (ArchiveFormat, error) {
+	header := make([]byte, 4)
+	if _, err := input.ReadAt(header, 0); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return ArchiveFormatZip, nil
+	case bytes.HasPrefix(header, gzipMagic):
+		return ArchiveFormatTarGz, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		return ArchiveFormatTarZst, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// ConvertTar copies the files of a tar.gz or tar.zst archive into an uncompressed zip, written to w. The other
+// functions of the validator need random access to the archive, which a compressed tar archive can't provide, so tar
+// archives must be converted, for example to a temporary file, before they are validated. The archive is streamed,
+// so is not subject to WithMaxUncompressedSize, but each file in it is.
+func (v *Validator) ConvertTar(format ArchiveFormat, input io.Reader, w io.Writer) error {
+	var decompressed io.Reader
+	switch format {
+	case ArchiveFormatTarGz:
+		reader, err := gzip.NewReader(input)
+		if err != nil {
+			return err
+		}
+
+		defer reader.Close()
+		decompressed = reader
+	case ArchiveFormatTarZst:
+		reader, err := zstd.NewReader(input)
+		if err != nil {
+			return err
+		}
+
+		defer reader.Close()
+		decompressed = reader
+	default:
+		return fmt.Errorf("%s is not a tar format", format)
+	}
+
+	tarReader := tar.NewReader(decompressed)
+	zipWriter := zip.NewWriter(w)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+
+		// Extracting any other type of entry, such as a symlink, would create something that isn't covered by a
+		// signature. Directories hold nothing, so are skipped, as they are in zip archives.
+		switch header.Typeflag {
+		case tar.TypeReg:
+		case tar.TypeDir:
+			continue
+		default:
+			return fmt.Errorf("%w: %s is not a regular file", ErrValidationFailed, header.Name)
+		}
+
+		if header.Size > v.maxUncompressedSize {
+			return fmt.Errorf("%w: %s", ErrMaximumSizeExceeded, header.Name)
+		}
+
+		f, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:   header.Name,
+			Method: zip.Store,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(f, tarReader); err != nil {
+			return err
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+// Archive is an export opened by OpenArchive, which can be passed to the other functions of the validator.
+type Archive struct {
+	io.ReaderAt
+	Format ArchiveFormat
+	Size   int64
+
+	// Parts is the parts.json of an export that was split into several archives, if the archive was joined from
+	// its parts.
+	Parts *dto.ArchiveParts
+
+	temp    *os.File
+	closers []io.Closer // Closed along with the archive, for archives opened by OpenFiles
+}
+
+// OpenArchive detects the format of an export, and prepares it to be validated. A zip archive is read as is, while a
+// tar archive is converted to a zip in a temporary file, as described by ConvertTar. Close must be called to remove
+// the temporary file.
+func (v *Validator) OpenArchive(input io.ReaderAt, size int64) (*Archive, error) {
+	format, err := DetectFormat(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == ArchiveFormatZip {
+		return &Archive{ReaderAt: input, Format: format, Size: size}, nil
+	}
+
+	f, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{ReaderAt: f, Format: format, temp: f}
+	if err := v.ConvertTar(format, io.NewSectionReader(input, 0, size), f); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	archive.Size = info.Size()
+	return archive, nil
+}
+
+// OpenFiles opens an export from disk, as OpenArchive does. If several paths are given, they are the parts of an export
+// that was split into several archives, and are joined as by OpenArchiveParts. A single part of a split export is
+// refused, as it can't be validated as the export's type on its own.
+func (v *Validator) OpenFiles(paths ...string) (*Archive, error) {
+	parts := make([]*Archive, 0, len(paths))
+	closeParts := func() {
+		for _, part := range parts {
+			part.Close()
+		}
+	}
+
+	for _, path := range paths {
+		part, err := v.OpenFile(path)
+		if err != nil {
+			closeParts()
+			return nil, err
+		}
+
+		parts = append(parts, part)
+	}
+
+	if len(parts) == 1 {
+		reader, err := zip.NewReader(parts[0], parts[0].Size)
+		if err != nil {
+			closeParts()
+			return nil, err
+		}
+
+		if hasFile(reader, partsFileName) {
+			closeParts()
+			return nil, fmt.Errorf("%s is one part of an export that was split into several, so every part is needed", paths[0])
+		}
+
+		return parts[0], nil
+	}
+
+	defer closeParts()
+	return v.OpenArchiveParts(parts...)
+}
+
+// OpenFile opens an archive from disk, as OpenArchive does. Unlike OpenFiles, the archive may be one part of a split
+// export, to be validated with ValidateArchivePart. The file is closed along with the archive.
+func (v *Validator) OpenFile(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	archive, err := v.OpenArchive(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	archive.closers = append(archive.closers, f)
+	return archive, nil
+}
+
+// Close removes the temporary file a tar archive was converted to. It does not close the input the archive was
+// opened from, unless the archive was opened by OpenFiles.
+func (a *Archive) Close() error {
+	for _, closer := range a.closers {
+		closer.Close()
+	}
+
+	if a.temp == nil {
+		return nil
+	}
+
+	a.temp.Close()
+	return os.Remove(a.temp.Name())
+}
@@ -2,9 +2,9 @@ package validator
 
 import (
 	"archive/zip"
-	"encoding/json"
 	"github.com/TicketsBot/export/pkg/dto"
 	"io"
+	"strings"
 )
 
 func (v *Validator) ValidateGuildData(input io.ReaderAt, size int64) (*dto.GuildData, error) {
@@ -29,10 +29,114 @@ func (v *Validator) ValidateGuildData(input io.ReaderAt, size int64) (*dto.Guild
 		return nil, err
 	}
 
-	var guildData dto.GuildData
-	if err := json.Unmarshal(data, &guildData); err != nil {
+	if err := v.validateOptionalFiles(reader, "data.json"); err != nil {
 		return nil, err
 	}
 
-	return &guildData, nil
+	// Exports in older format versions are decoded into the current structure
+	return dto.DecodeGuildData(data)
+}
+
+// ReadGuildData validates either a guild data export in the JSON format or a full guild export, returning the guild's
+// data and the export's metadata, which is nil for guild data exports made before metadata was added. The transcripts
+// in a full guild export are verified, but not returned. Guild data exports in the SQLite format are refused with
+// ErrSqliteGuildData.
+func (v *Validator) ReadGuildData(input io.ReaderAt, size int64) (*dto.GuildData, *dto.ExportMetadata, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hasFile(reader, indexFileName) {
+		output, err := v.StreamGuildFull(input, size, func(int, []byte) error { return nil })
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return output.GuildData, output.Metadata, nil
+	}
+
+	if hasFile(reader, "data.sqlite") {
+		return nil, nil, ErrSqliteGuildData
+	}
+
+	data, err := v.ValidateGuildData(input, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata, err := v.ValidateMetadata(input, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, metadata, nil
+}
+
+// ValidateGuildDataSqlite validates a guild data export in the SQLite format, returning the verified database file.
+func (v *Validator) ValidateGuildDataSqlite(input io.ReaderAt, size int64) ([]byte, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := reader.Open("data.sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(v.newLimitReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := v.validateSignature(reader, "data.sqlite", data); err != nil {
+		return nil, err
+	}
+
+	if err := v.validateOptionalFiles(reader, "data.sqlite"); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// validateOptionalFiles validates the files that a guild data export may contain alongside its data file: the
+// metadata, CSV files and the list of tickets that failed to export. Each must be signed if present, and any other file
+// fails validation.
+func (v *Validator) validateOptionalFiles(reader *zip.Reader, dataFileName string) error {
+	verified := map[string]bool{dataFileName: true}
+	for _, file := range reader.File {
+		isCsv := strings.HasPrefix(file.Name, "csv/") && strings.HasSuffix(file.Name, ".csv")
+		if file.Name != metadataFileName && file.Name != failedFileName && !isCsv {
+			continue
+		}
+
+		if err := v.validateFile(reader, file.Name); err != nil {
+			return err
+		}
+
+		verified[file.Name] = true
+	}
+
+	return checkVerified(reader, verified)
+}
+
+func (v *Validator) validateFile(reader *zip.Reader, name string) error {
+	f, err := reader.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(v.newLimitReader(f))
+	if err != nil {
+		return err
+	}
+
+	_, err = v.validateSignature(reader, name, data)
+	return err
 }
@@ -0,0 +1,141 @@
+package validator
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
+	"io"
+	"strconv"
+)
+
+const indexFileName = "index.json"
+
+type GuildFullOutput struct {
+	GuildData *dto.GuildData
+	// Metadata is always present in full guild exports
+	Metadata *dto.ExportMetadata
+}
+
+// StreamGuildFull validates a full guild export, which holds both the guild's data and its transcripts, passing each
+// verified transcript to f one at a time. Only index.json is signed; every other file in the archive must be listed
+// in the index with a matching SHA-256 hash, and every file in the index must be in the archive. As with
+// StreamGuildTranscripts, the total size of the archive is not capped, but each file is capped by
+// WithMaxIndividualFileSize.
+//
+// Transcripts are passed to f as they are verified, so if an error is returned, f may already have been called with
+// some of the archive's transcripts.
+func (v *Validator) StreamGuildFull(input io.ReaderAt, size int64, f TranscriptFunc) (*GuildFullOutput, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := v.readIndex(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &GuildFullOutput{}
+	seen := make(map[string]bool, len(index.Files))
+	for _, file := range reader.File {
+		if file.Name == indexFileName || file.Name == indexFileName+".sig" {
+			continue
+		}
+
+		expected, ok := index.Files[file.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not listed in the index", ErrValidationFailed, file.Name)
+		}
+
+		b, err := v.readZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.Sum256(b)
+		if hex.EncodeToString(hash[:]) != expected {
+			return nil, fmt.Errorf("%w: %s", ErrValidationFailed, file.Name)
+		}
+
+		seen[file.Name] = true
+		if v.onVerified != nil {
+			v.onVerified(file.Name)
+		}
+
+		switch file.Name {
+		case "data.json":
+			if output.GuildData, err = dto.DecodeGuildData(b); err != nil {
+				return nil, err
+			}
+		case metadataFileName:
+			if err := json.Unmarshal(b, &output.Metadata); err != nil {
+				return nil, err
+			}
+		default:
+			matches := transcriptFileRegex.FindStringSubmatch(file.Name)
+			if len(matches) != 2 {
+				continue
+			}
+
+			ticketId, err := strconv.Atoi(matches[1])
+			if err != nil {
+				continue
+			}
+
+			if err := f(ticketId, b); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name := range index.Files {
+		if !seen[name] {
+			return nil, fmt.Errorf("%w: %s is listed in the index, but missing from the archive", ErrValidationFailed, name)
+		}
+	}
+
+	if output.GuildData == nil || output.Metadata == nil {
+		return nil, fmt.Errorf("%w: data.json and metadata.json are required", ErrValidationFailed)
+	}
+
+	if output.GuildData.GuildId != index.GuildId {
+		return nil, fmt.Errorf("%w: data.json is for a different guild to the index", ErrValidationFailed)
+	}
+
+	for _, ticket := range output.GuildData.Tickets {
+		if ticket.TranscriptFile != nil && !seen[*ticket.TranscriptFile] {
+			return nil, fmt.Errorf("%w: transcript of ticket %d is missing", ErrValidationFailed, ticket.Id)
+		}
+	}
+
+	return output, nil
+}
+
+func (v *Validator) readIndex(reader *zip.Reader) (*dto.ExportIndex, error) {
+	f, err := reader.Open(indexFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	// The index lists every transcript, so may be larger than the individual file size limit
+	data, err := io.ReadAll(io.LimitReader(f, v.maxUncompressedSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := v.validateSignature(reader, indexFileName, data); err != nil {
+		return nil, err
+	}
+
+	var index dto.ExportIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	return &index, nil
+}
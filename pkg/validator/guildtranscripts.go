@@ -2,6 +2,9 @@ package validator
 
 import (
 	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
 	"io"
 	"regexp"
 	"strconv"
@@ -11,65 +14,256 @@ type GuildTranscriptsOutput struct {
 	GuildId uint64
 	// Ticket ID -> Transcript
 	Transcripts map[int][]byte
+	// Ticket ID -> Transcript, only populated when WithParseTranscripts is used
+	ParsedTranscripts map[int]*dto.Transcript
 }
 
-var transcriptFileRegex = regexp.MustCompile(`^transcripts/(\d+)\.json$`)
+const (
+	manifestFileName  = "manifest.json"
+	failedFileName    = "failed.txt"
+	indexHtmlFileName = "index.html"
+)
+
+var (
+	transcriptFileRegex     = regexp.MustCompile(`^transcripts/(\d+)\.json$`)
+	transcriptHtmlFileRegex = regexp.MustCompile(`^transcripts/(\d+)\.html$`)
+)
 
+// TranscriptFunc is called with each transcript once its signature has been verified. Returning an error stops
+// validation, and the error is returned to the caller.
+type TranscriptFunc func(ticketId int, transcript []byte) error
+
+// ParsedTranscriptFunc is called with each transcript once its signature has been verified and it has been decoded.
+// Returning an error stops validation, and the error is returned to the caller.
+type ParsedTranscriptFunc func(ticketId int, transcript *dto.Transcript) error
+
+// ValidateGuildTranscripts validates a guild transcripts export, returning every transcript in memory. The total size
+// read is capped by WithMaxUncompressedSize; use StreamGuildTranscripts for archives that may be larger.
 func (v *Validator) ValidateGuildTranscripts(input io.ReaderAt, size int64) (*GuildTranscriptsOutput, error) {
-	reader, err := zip.NewReader(input, size)
+	output := &GuildTranscriptsOutput{
+		Transcripts: make(map[int][]byte),
+	}
+
+	if v.parseTranscripts {
+		output.ParsedTranscripts = make(map[int]*dto.Transcript)
+	}
+
+	var n int64
+	guildId, err := v.streamGuildTranscripts(input, size, &n, func(ticketId int, transcript []byte) error {
+		output.Transcripts[ticketId] = transcript
+
+		if v.parseTranscripts {
+			parsed, err := decodeTranscript(ticketId, transcript)
+			if err != nil {
+				return err
+			}
+
+			output.ParsedTranscripts[ticketId] = parsed
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	guildId, n, err := v.readGuildId(reader)
+	output.GuildId = guildId
+	return output, nil
+}
+
+// StreamGuildTranscripts validates a guild transcripts export, passing each verified transcript to f one at a time, and
+// returns the guild ID. Every transcript listed in the signed manifest.json must be present, and any file that is not
+// signed, such as a transcript that is not in the manifest, fails validation. Only one transcript is held in memory at
+// once, so the total size of the archive is not capped by WithMaxUncompressedSize, although each file is still capped
+// by WithMaxIndividualFileSize. Passing an *os.File as input means the archive itself is not loaded into memory either.
+//
+// Transcripts are passed to f as they are verified, so if an error is returned, f may already have been called with
+// some of the archive's transcripts.
+func (v *Validator) StreamGuildTranscripts(input io.ReaderAt, size int64, f TranscriptFunc) (uint64, error) {
+	return v.streamGuildTranscripts(input, size, nil, f)
+}
+
+// StreamParsedGuildTranscripts is StreamGuildTranscripts, but passes each transcript to f decoded. Validation fails if
+// any transcript cannot be decoded.
+func (v *Validator) StreamParsedGuildTranscripts(input io.ReaderAt, size int64, f ParsedTranscriptFunc) (uint64, error) {
+	return v.streamGuildTranscripts(input, size, nil, func(ticketId int, transcript []byte) error {
+		parsed, err := decodeTranscript(ticketId, transcript)
+		if err != nil {
+			return err
+		}
+
+		return f(ticketId, parsed)
+	})
+}
+
+func decodeTranscript(ticketId int, transcript []byte) (*dto.Transcript, error) {
+	parsed, err := dto.DecodeTranscript(transcript)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decode transcript for ticket %d: %w", ticketId, err)
+	}
+
+	return parsed, nil
+}
+
+// streamGuildTranscripts adds the number of bytes read to n, if it is not nil, and returns ErrMaximumSizeExceeded
+// once it exceeds the maximum uncompressed size.
+func (v *Validator) streamGuildTranscripts(input io.ReaderAt, size int64, n *int64, f TranscriptFunc) (uint64, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return 0, err
+	}
+
+	guildId, read, err := v.readGuildId(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	count := func(size int64) error {
+		if n == nil {
+			return nil
+		}
+
+		*n += size
+		if *n > v.maxUncompressedSize {
+			return ErrMaximumSizeExceeded
+		}
+
+		return nil
+	}
+
+	if err := count(read); err != nil {
+		return 0, err
+	}
+
+	manifest, read, err := v.readManifest(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := count(read); err != nil {
+		return 0, err
+	}
+
+	if manifest.GuildId != guildId {
+		return 0, fmt.Errorf("%w: %s is for a different guild to guild_id.txt", ErrValidationFailed, manifestFileName)
+	}
+
+	listed := make(map[int]bool, len(manifest.Transcripts))
+	for _, ticketId := range manifest.Transcripts {
+		listed[ticketId] = true
+	}
+
+	verified := map[string]bool{
+		"guild_id.txt":   true,
+		manifestFileName: true,
 	}
 
-	transcripts := make(map[int][]byte)
-	for _, f := range reader.File {
-		matches := transcriptFileRegex.FindStringSubmatch(f.Name)
+	// The metadata, the list of failed tickets and the rendered HTML are optional, but must be signed if present
+	for _, file := range reader.File {
+		if file.Name != metadataFileName && file.Name != failedFileName && file.Name != indexHtmlFileName {
+			matches := transcriptHtmlFileRegex.FindStringSubmatch(file.Name)
+			if len(matches) != 2 {
+				continue
+			}
+
+			if ticketId, err := strconv.Atoi(matches[1]); err != nil || !listed[ticketId] {
+				continue
+			}
+		}
+
+		if err := v.validateFile(reader, file.Name); err != nil {
+			return 0, err
+		}
+
+		verified[file.Name] = true
+	}
+
+	for _, file := range reader.File {
+		matches := transcriptFileRegex.FindStringSubmatch(file.Name)
 		if len(matches) != 2 {
 			continue
 		}
 
 		ticketId, err := strconv.Atoi(matches[1])
-		if err != nil {
-			continue
+		if err != nil || !listed[ticketId] {
+			return 0, fmt.Errorf("%w: %s is not listed in %s", ErrValidationFailed, file.Name, manifestFileName)
 		}
 
-		file, err := f.Open()
+		b, err := v.readZipFile(file)
 		if err != nil {
-			return nil, err
+			return 0, err
+		}
+
+		if err := count(int64(len(b))); err != nil {
+			return 0, err
 		}
 
-		b, err := io.ReadAll(v.newLimitReader(file))
+		sigSize, err := v.validateTranscriptSignature(reader, file.Name, guildId, ticketId, b)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
 
-		n += int64(len(b))
-		if n > v.maxUncompressedSize {
-			return nil, ErrMaximumSizeExceeded
+		if err := count(sigSize); err != nil {
+			return 0, err
 		}
 
-		sigSize, err := v.validateTranscriptSignature(reader, f.Name, guildId, ticketId, b)
-		if err != nil {
-			return nil, err
+		verified[file.Name] = true
+
+		if err := f(ticketId, b); err != nil {
+			return 0, err
 		}
+	}
 
-		n += sigSize
-		if n > v.maxUncompressedSize {
-			return nil, ErrMaximumSizeExceeded
+	for _, ticketId := range manifest.Transcripts {
+		if !verified[fmt.Sprintf("transcripts/%d.json", ticketId)] {
+			return 0, fmt.Errorf("%w: transcript of ticket %d is listed in %s, but missing from the archive",
+				ErrValidationFailed, ticketId, manifestFileName)
 		}
+	}
 
-		transcripts[ticketId] = b
+	if err := checkVerified(reader, verified); err != nil {
+		return 0, err
 	}
 
-	return &GuildTranscriptsOutput{
-		GuildId:     guildId,
-		Transcripts: transcripts,
-	}, nil
+	return guildId, nil
+}
+
+func (v *Validator) readManifest(reader *zip.Reader) (*dto.TranscriptManifest, int64, error) {
+	f, err := reader.Open(manifestFileName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer f.Close()
+
+	// The manifest lists every transcript, so may be larger than the individual file size limit
+	data, err := io.ReadAll(io.LimitReader(f, v.maxUncompressedSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sigSize, err := v.validateSignature(reader, manifestFileName, data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var manifest dto.TranscriptManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, 0, err
+	}
+
+	return &manifest, int64(len(data)) + sigSize, nil
+}
+
+func (v *Validator) readZipFile(file *zip.File) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return io.ReadAll(v.newLimitReader(f))
 }
 
 func (v *Validator) readGuildId(reader *zip.Reader) (uint64, int64, error) {
@@ -0,0 +1,57 @@
+package validator
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"github.com/TicketsBot/export/pkg/dto"
+	"io"
+)
+
+const metadataFileName = "metadata.json"
+
+// ValidateMetadata validates and returns the metadata.json of a guild data or transcripts export, which records how
+// the export was produced, including any redaction applied. Exports made before metadata was added do not contain
+// it, in which case nil is returned without an error.
+func (v *Validator) ValidateMetadata(input io.ReaderAt, size int64) (*dto.ExportMetadata, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasFile(reader, metadataFileName) {
+		return nil, nil
+	}
+
+	f, err := reader.Open(metadataFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(v.newLimitReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := v.validateSignature(reader, metadataFileName, data); err != nil {
+		return nil, err
+	}
+
+	var metadata dto.ExportMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+func hasFile(reader *zip.Reader, name string) bool {
+	for _, file := range reader.File {
+		if file.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,216 @@
+package validator
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TicketsBot/export/pkg/dto"
+	"io"
+	"os"
+	"reflect"
+)
+
+const partsFileName = "parts.json"
+
+// ValidateArchivePart validates one part of an export that was too large for a single archive, and so was split into
+// several. Each part holds an arbitrary subset of the export's files, so rather than being validated by export type,
+// every file in the part is checked against the SHA-256 hash listed for the part in the signed parts.json. Files are
+// hashed as they are read, so the part is not loaded into memory, but each file is capped by WithMaxUncompressedSize.
+func (v *Validator) ValidateArchivePart(input io.ReaderAt, size int64) (*dto.ArchiveParts, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := v.readPartsIndex(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if index.Part < 1 || index.Part > len(index.Parts) || index.Parts[index.Part-1].Part != index.Part {
+		return nil, fmt.Errorf("%w: %s does not list part %d", ErrValidationFailed, partsFileName, index.Part)
+	}
+
+	expected := index.Parts[index.Part-1].Files
+
+	seen := make(map[string]bool, len(expected))
+	for _, file := range reader.File {
+		if file.Name == partsFileName || file.Name == partsFileName+".sig" {
+			continue
+		}
+
+		hash, ok := expected[file.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not listed in %s", ErrValidationFailed, file.Name, partsFileName)
+		}
+
+		actual, err := v.hashZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if actual != hash {
+			return nil, fmt.Errorf("%w: %s", ErrValidationFailed, file.Name)
+		}
+
+		seen[file.Name] = true
+		if v.onVerified != nil {
+			v.onVerified(file.Name)
+		}
+	}
+
+	for name := range expected {
+		if !seen[name] {
+			return nil, fmt.Errorf("%w: %s is listed in %s, but missing from the archive", ErrValidationFailed, name, partsFileName)
+		}
+	}
+
+	return index, nil
+}
+
+// JoinArchiveParts validates every part of an export that was split into several archives, checks that they are the
+// complete set of parts of a single export, and joins their files into a single zip, written to w. The joined archive
+// can then be validated as the export's type, as if it had never been split. Files are copied without being
+// decompressed, so the parts are not loaded into memory. The parts can be given in any order, and the index of the
+// first part is returned.
+func (v *Validator) JoinArchiveParts(parts []*Archive, w io.Writer) (*dto.ArchiveParts, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("no parts were given")
+	}
+
+	var first *dto.ArchiveParts
+	byPart := make(map[int]*Archive, len(parts))
+	for _, part := range parts {
+		index, err := v.ValidateArchivePart(part, part.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		if first == nil {
+			first = index
+		} else if index.RequestId != first.RequestId {
+			return nil, fmt.Errorf("%w: part %d is from a different export to part %d", ErrValidationFailed, index.Part, first.Part)
+		} else if !reflect.DeepEqual(index.Parts, first.Parts) {
+			return nil, fmt.Errorf("%w: part %d lists different files to part %d", ErrValidationFailed, index.Part, first.Part)
+		}
+
+		if _, ok := byPart[index.Part]; ok {
+			return nil, fmt.Errorf("%w: part %d was given more than once", ErrValidationFailed, index.Part)
+		}
+
+		byPart[index.Part] = part
+	}
+
+	for i := 1; i <= len(first.Parts); i++ {
+		if _, ok := byPart[i]; !ok {
+			return nil, fmt.Errorf("%w: part %d of %d is missing", ErrValidationFailed, i, len(first.Parts))
+		}
+	}
+
+	writer := zip.NewWriter(w)
+	seen := make(map[string]bool)
+	for i := 1; i <= len(first.Parts); i++ {
+		part := byPart[i]
+
+		reader, err := zip.NewReader(part, part.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range reader.File {
+			if file.Name == partsFileName || file.Name == partsFileName+".sig" {
+				continue
+			}
+
+			if seen[file.Name] {
+				return nil, fmt.Errorf("%w: %s is in more than one part", ErrValidationFailed, file.Name)
+			}
+
+			seen[file.Name] = true
+			if err := writer.Copy(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return first, nil
+}
+
+// OpenArchiveParts joins the parts of a split export as JoinArchiveParts does, into a temporary file, which Close
+// removes. The parts are not closed. The joined archive has the format of the first part, although it is a zip.
+func (v *Validator) OpenArchiveParts(parts ...*Archive) (*Archive, error) {
+	f, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{ReaderAt: f, temp: f}
+	if archive.Parts, err = v.JoinArchiveParts(parts, f); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	archive.Format = parts[0].Format
+	archive.Size = info.Size()
+	return archive, nil
+}
+
+func (v *Validator) readPartsIndex(reader *zip.Reader) (*dto.ArchiveParts, error) {
+	f, err := reader.Open(partsFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	// The index lists every file in the export, so may be larger than the individual file size limit
+	data, err := io.ReadAll(io.LimitReader(f, v.maxUncompressedSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := v.validateSignature(reader, partsFileName, data); err != nil {
+		return nil, err
+	}
+
+	var index dto.ArchiveParts
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	return &index, nil
+}
+
+// hashZipFile returns the hex encoded SHA-256 hash of a file in the archive, without reading it into memory.
+func (v *Validator) hashZipFile(file *zip.File) (string, error) {
+	if file.UncompressedSize64 > uint64(v.maxUncompressedSize) {
+		return "", fmt.Errorf("%w: %s", ErrMaximumSizeExceeded, file.Name)
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.LimitReader(f, v.maxUncompressedSize)); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -3,9 +3,11 @@ package validator
 import (
 	"archive/zip"
 	"crypto/ed25519"
+	"fmt"
 	"github.com/TicketsBot/export/internal/utils"
 	"io"
 	"strconv"
+	"strings"
 )
 
 func (v *Validator) validateSignature(zipReader *zip.Reader, fileName string, data []byte) (int64, error) {
@@ -27,7 +29,11 @@ func (v *Validator) validateSignature(zipReader *zip.Reader, fileName string, da
 	}
 
 	if !ed25519.Verify(v.publicKey, data, decoded) {
-		return 0, ErrValidationFailed
+		return 0, fmt.Errorf("%w: %s", ErrValidationFailed, fileName)
+	}
+
+	if v.onVerified != nil {
+		v.onVerified(fileName)
 	}
 
 	return int64(len(signature)), nil
@@ -52,3 +58,35 @@ func (v *Validator) validateTranscriptSignature(
 
 	return v.validateSignature(zipReader, fileName, sigData)
 }
+
+// checkVerified returns an error if the archive contains any file that is not in verified, other than the signature
+// of a verified file. Every file is signed, so a file that wasn't verified was added to the archive after it was
+// exported, and must not be mistaken for part of the export. Only one of several files with the same name would have
+// been verified, so duplicate names also fail validation.
+func checkVerified(reader *zip.Reader, verified map[string]bool) error {
+	seen := make(map[string]bool, len(reader.File))
+	for _, file := range reader.File {
+		// Directory entries have no content, and are added by some tools when an archive is extracted and recreated
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+
+		if seen[file.Name] {
+			return fmt.Errorf("%w: %s appears more than once", ErrValidationFailed, file.Name)
+		}
+
+		seen[file.Name] = true
+
+		if verified[file.Name] {
+			continue
+		}
+
+		if name, ok := strings.CutSuffix(file.Name, ".sig"); ok && verified[name] {
+			continue
+		}
+
+		return fmt.Errorf("%w: %s is not part of the export", ErrValidationFailed, file.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package validator
+
+import (
+	"archive/zip"
+	"github.com/TicketsBot/export/pkg/dto"
+	"io"
+)
+
+func (v *Validator) ValidateUserData(input io.ReaderAt, size int64) (*dto.UserData, error) {
+	reader, err := zip.NewReader(input, size)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := reader.Open("data.json")
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	data, err := io.ReadAll(v.newLimitReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := v.validateSignature(reader, "data.json", data); err != nil {
+		return nil, err
+	}
+
+	if err := checkVerified(reader, map[string]bool{"data.json": true}); err != nil {
+		return nil, err
+	}
+
+	return dto.DecodeUserData(data)
+}
@@ -10,6 +10,9 @@ type Validator struct {
 
 	maxUncompressedSize   int64
 	maxIndividualFileSize int64
+
+	onVerified       func(fileName string)
+	parseTranscripts bool
 }
 
 type Option func(*Validator)
@@ -40,6 +43,22 @@ func WithMaxIndividualFileSize(size int64) Option {
 	}
 }
 
+// WithOnVerified sets a function that is called with the name of each file whose signature has been verified, for
+// example to report which files of an archive were checked.
+func WithOnVerified(f func(fileName string)) Option {
+	return func(v *Validator) {
+		v.onVerified = f
+	}
+}
+
+// WithParseTranscripts makes ValidateGuildTranscripts also decode each transcript into GuildTranscriptsOutput's
+// ParsedTranscripts. Validation fails if any transcript cannot be decoded.
+func WithParseTranscripts() Option {
+	return func(v *Validator) {
+		v.parseTranscripts = true
+	}
+}
+
 func (v *Validator) newLimitReader(r io.Reader) io.Reader {
 	return io.LimitReader(r, v.maxIndividualFileSize)
 }